@@ -9,8 +9,6 @@ import (
 	. "github.com/scriptmaster/hf_transformers_go/transformers"
 )
 
-var pipeline = Pipeline
-
 func main() {
 	_ = godotenv.Load(".env.local")
 
@@ -23,7 +21,7 @@ func main() {
 		modelID = "onnx-community/SmolLM-135M-ONNX"
 	}
 
-	generator, err := pipeline(
+	generator, err := TypedPipeline[TextGenerationResult](
 		"text-generation",
 		modelID,
 		map[string]any{"dtype": "q4"},
@@ -51,7 +49,7 @@ func main() {
 		return
 	}
 
-	gen := out[0]["generated_text"].([]map[string]any)
 	fmt.Println("\n---")
-	fmt.Println(gen[len(gen)-1]["content"].(string))
+	fmt.Println(out[0].Reply())
+	fmt.Printf("(%s, %d tokens)\n", out[0].FinishReason, out[0].Usage.TotalTokens)
 }
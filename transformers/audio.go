@@ -0,0 +1,250 @@
+package transformers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Audio is mono PCM audio as float32 samples in [-1, 1].
+type Audio struct {
+	Samples      []float32
+	SamplingRate int
+}
+
+// LoadWAVFile reads a RIFF/WAVE file (8/16/24/32-bit PCM or 32/64-bit float)
+// and downmixes it to mono.
+func LoadWAVFile(path string) (*Audio, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeWAV(data)
+}
+
+// DecodeWAV parses WAV bytes; see LoadWAVFile.
+func DecodeWAV(data []byte) (*Audio, error) {
+	r := bytes.NewReader(data)
+	var riff struct {
+		ID   [4]byte
+		Size uint32
+		Wave [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
+		return nil, fmt.Errorf("wav: %w", err)
+	}
+	if string(riff.ID[:]) != "RIFF" || string(riff.Wave[:]) != "WAVE" {
+		return nil, errors.New("wav: not a RIFF/WAVE file")
+	}
+
+	var (
+		format     uint16
+		channels   int
+		sampleRate int
+		bits       int
+		pcm        []byte
+	)
+	for {
+		var hdr struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &hdr); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return nil, fmt.Errorf("wav: %w", err)
+		}
+		size := int64(hdr.Size)
+		// Chunks are sliced out of data, never allocated from the header
+		// size, so a bogus size cannot make us allocate.
+		pos := int64(len(data)) - int64(r.Len())
+		switch string(hdr.ID[:]) {
+		case "fmt ":
+			if size > int64(r.Len()) {
+				return nil, errors.New("wav: truncated fmt chunk")
+			}
+			buf := data[pos : pos+size]
+			if len(buf) < 16 {
+				return nil, errors.New("wav: short fmt chunk")
+			}
+			format = binary.LittleEndian.Uint16(buf[0:])
+			channels = int(binary.LittleEndian.Uint16(buf[2:]))
+			sampleRate = int(binary.LittleEndian.Uint32(buf[4:]))
+			bits = int(binary.LittleEndian.Uint16(buf[14:]))
+			// WAVE_FORMAT_EXTENSIBLE keeps the real format in the sub-format GUID.
+			if format == 0xFFFE && len(buf) >= 26 {
+				format = binary.LittleEndian.Uint16(buf[24:])
+			}
+		case "data":
+			if size > int64(r.Len()) {
+				size = int64(r.Len()) // tolerate truncated files
+			}
+			pcm = data[pos : pos+size]
+		}
+		if _, err := r.Seek(size, io.SeekCurrent); err != nil {
+			return nil, fmt.Errorf("wav: %w", err)
+		}
+		// Chunks are word aligned.
+		if size%2 == 1 {
+			r.Seek(1, io.SeekCurrent)
+		}
+	}
+	if channels == 0 || sampleRate == 0 {
+		return nil, errors.New("wav: missing fmt chunk")
+	}
+	if pcm == nil {
+		return nil, errors.New("wav: missing data chunk")
+	}
+
+	decode, err := wavSampleDecoder(format, bits)
+	if err != nil {
+		return nil, err
+	}
+	width := bits / 8
+	frames := len(pcm) / (width * channels)
+	samples := make([]float32, frames)
+	for i := 0; i < frames; i++ {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			off := (i*channels + c) * width
+			sum += decode(pcm[off : off+width])
+		}
+		samples[i] = sum / float32(channels)
+	}
+	return &Audio{Samples: samples, SamplingRate: sampleRate}, nil
+}
+
+func wavSampleDecoder(format uint16, bits int) (func([]byte) float32, error) {
+	switch {
+	case format == 1 && bits == 8:
+		return func(b []byte) float32 { return (float32(b[0]) - 128) / 128 }, nil
+	case format == 1 && bits == 16:
+		return func(b []byte) float32 {
+			return float32(int16(binary.LittleEndian.Uint16(b))) / 32768
+		}, nil
+	case format == 1 && bits == 24:
+		return func(b []byte) float32 {
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			return float32(v) / 8388608
+		}, nil
+	case format == 1 && bits == 32:
+		return func(b []byte) float32 {
+			return float32(int32(binary.LittleEndian.Uint32(b))) / 2147483648
+		}, nil
+	case format == 3 && bits == 32:
+		return func(b []byte) float32 {
+			return math.Float32frombits(binary.LittleEndian.Uint32(b))
+		}, nil
+	case format == 3 && bits == 64:
+		return func(b []byte) float32 {
+			return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}, nil
+	}
+	return nil, fmt.Errorf("wav: unsupported format %d with %d bits per sample", format, bits)
+}
+
+// Resample converts a to rate using a Hann-windowed sinc low-pass filter
+// with the cutoff at the lower of the two Nyquist frequencies.
+func (a *Audio) Resample(rate int) *Audio {
+	if a.SamplingRate == rate || rate <= 0 || len(a.Samples) == 0 {
+		return a
+	}
+	return &Audio{Samples: resample(a.Samples, a.SamplingRate, rate), SamplingRate: rate}
+}
+
+func resample(x []float32, srcRate, dstRate int) []float32 {
+	const zeroCrossings = 16
+	ratio := float64(dstRate) / float64(srcRate)
+	cutoff := math.Min(1, ratio) // relative to the source Nyquist frequency
+	support := zeroCrossings / cutoff
+
+	out := make([]float32, int(math.Ceil(float64(len(x))*ratio)))
+	for i := range out {
+		t := float64(i) / ratio
+		lo := max(int(math.Ceil(t-support)), 0)
+		hi := min(int(math.Floor(t+support)), len(x)-1)
+		var acc float64
+		for j := lo; j <= hi; j++ {
+			d := t - float64(j)
+			w := cutoff * sinc(cutoff*d) * 0.5 * (1 + math.Cos(math.Pi*d/support))
+			acc += w * float64(x[j])
+		}
+		out[i] = float32(acc)
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// audioFromInput accepts the audio input forms pipelines take: a WAV file
+// path, WAV bytes, raw samples at defaultRate, *Audio/Audio, or the HF dict
+// form {"raw": []float32, "sampling_rate": 16000}. The result is resampled
+// to defaultRate.
+func audioFromInput(input any, defaultRate int) (*Audio, error) {
+	var a *Audio
+	switch t := input.(type) {
+	case string:
+		var err error
+		if a, err = LoadWAVFile(t); err != nil {
+			return nil, err
+		}
+	case []byte:
+		var err error
+		if a, err = DecodeWAV(t); err != nil {
+			return nil, err
+		}
+	case []float32:
+		a = &Audio{Samples: t, SamplingRate: defaultRate}
+	case *Audio:
+		a = t
+	case Audio:
+		a = &t
+	case map[string]any:
+		raw, ok := t["raw"].([]float32)
+		if !ok {
+			return nil, fmt.Errorf("audio input: \"raw\" must be []float32, got %T", t["raw"])
+		}
+		a = &Audio{Samples: raw, SamplingRate: intOption(t, "sampling_rate", defaultRate)}
+	default:
+		return nil, fmt.Errorf("unsupported audio input %T", input)
+	}
+	return a.Resample(defaultRate), nil
+}
+
+// audioInputs splits batched audio inputs ([]string, [][]byte, [][]float32,
+// []any) from single ones.
+func audioInputs(inputs any) []any {
+	switch t := inputs.(type) {
+	case []string:
+		out := make([]any, len(t))
+		for i, v := range t {
+			out[i] = v
+		}
+		return out
+	case [][]byte:
+		out := make([]any, len(t))
+		for i, v := range t {
+			out[i] = v
+		}
+		return out
+	case [][]float32:
+		out := make([]any, len(t))
+		for i, v := range t {
+			out[i] = v
+		}
+		return out
+	case []any:
+		return t
+	}
+	return []any{inputs}
+}
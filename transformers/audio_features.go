@@ -0,0 +1,397 @@
+package transformers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/cmplx"
+	"os"
+)
+
+// loadPreprocessorConfig reads preprocessor_config.json from the model repo.
+func loadPreprocessorConfig(modelID string) (map[string]any, error) {
+	path, err := HFHubDownload(modelID, "preprocessor_config.json")
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("preprocessor_config.json: %w", err)
+	}
+	return raw, nil
+}
+
+// WhisperFeatureExtractor computes Whisper's log-mel spectrogram, matching
+// transformers' WhisperFeatureExtractor.
+type WhisperFeatureExtractor struct {
+	FeatureSize  int // mel bins: 80, or 128 for large-v3
+	SamplingRate int
+	HopLength    int
+	NFFT         int
+	NSamples     int // samples per 30 s window
+	NbMaxFrames  int
+
+	melFilters [][]float64 // [FeatureSize][NFFT/2+1]
+}
+
+// newWhisperFeatureExtractor builds an extractor from preprocessor_config.json.
+// Precomputed "mel_filters" are used when present.
+func newWhisperFeatureExtractor(pp map[string]any) *WhisperFeatureExtractor {
+	fe := &WhisperFeatureExtractor{
+		FeatureSize:  intOption(pp, "feature_size", 80),
+		SamplingRate: intOption(pp, "sampling_rate", 16000),
+		HopLength:    intOption(pp, "hop_length", 160),
+		NFFT:         intOption(pp, "n_fft", 400),
+	}
+	fe.NSamples = intOption(pp, "n_samples", intOption(pp, "chunk_length", 30)*fe.SamplingRate)
+	fe.NbMaxFrames = intOption(pp, "nb_max_frames", fe.NSamples/fe.HopLength)
+
+	if rows, ok := pp["mel_filters"].([]any); ok && len(rows) > 0 {
+		// Stored as [n_freq][n_mels]; transpose to [n_mels][n_freq].
+		nFreq := len(rows)
+		fe.melFilters = make([][]float64, fe.FeatureSize)
+		for m := range fe.melFilters {
+			fe.melFilters[m] = make([]float64, nFreq)
+		}
+		for f, row := range rows {
+			cols, _ := row.([]any)
+			for m, v := range cols {
+				if x, ok := v.(float64); ok && m < fe.FeatureSize {
+					fe.melFilters[m][f] = x
+				}
+			}
+		}
+	} else {
+		fe.melFilters = melFilterBank(fe.NFFT/2+1, fe.FeatureSize, 0, 8000, fe.SamplingRate)
+	}
+	return fe
+}
+
+// Extract pads or truncates samples to NSamples and returns the
+// [FeatureSize x NbMaxFrames] log-mel spectrogram, row-major.
+func (fe *WhisperFeatureExtractor) Extract(samples []float32) []float32 {
+	x := make([]float64, fe.NSamples)
+	for i := 0; i < len(samples) && i < fe.NSamples; i++ {
+		x[i] = float64(samples[i])
+	}
+
+	power := stftPower(x, fe.NFFT, fe.HopLength)
+	frames := min(len(power)-1, fe.NbMaxFrames) // Whisper drops the last frame
+
+	logSpec := make([]float64, fe.FeatureSize*frames)
+	maxVal := math.Inf(-1)
+	for m := 0; m < fe.FeatureSize; m++ {
+		filt := fe.melFilters[m]
+		for t := 0; t < frames; t++ {
+			var acc float64
+			for f, w := range filt {
+				if w != 0 {
+					acc += w * power[t][f]
+				}
+			}
+			v := math.Log10(math.Max(acc, 1e-10))
+			logSpec[m*frames+t] = v
+			maxVal = math.Max(maxVal, v)
+		}
+	}
+
+	out := make([]float32, fe.FeatureSize*fe.NbMaxFrames)
+	for m := 0; m < fe.FeatureSize; m++ {
+		for t := 0; t < frames; t++ {
+			v := math.Max(logSpec[m*frames+t], maxVal-8)
+			out[m*fe.NbMaxFrames+t] = float32((v + 4) / 4)
+		}
+	}
+	return out
+}
+
+// stftPower returns |STFT|^2 frames of x using a periodic Hann window and
+// centered (reflect-padded) frames, as torch.stft(center=True) does.
+func stftPower(x []float64, nFFT, hop int) [][]float64 {
+	pad := nFFT / 2
+	padded := make([]float64, len(x)+2*pad)
+	copy(padded[pad:], x)
+	for i := 0; i < pad; i++ {
+		padded[pad-1-i] = reflectAt(x, i+1)
+		padded[pad+len(x)+i] = reflectAt(x, len(x)-2-i)
+	}
+
+	window := make([]float64, nFFT)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(nFFT))
+	}
+
+	nFrames := 1 + (len(padded)-nFFT)/hop
+	nFreq := nFFT/2 + 1
+	out := make([][]float64, nFrames)
+	buf := make([]complex128, nFFT)
+	for t := 0; t < nFrames; t++ {
+		start := t * hop
+		for i := 0; i < nFFT; i++ {
+			buf[i] = complex(padded[start+i]*window[i], 0)
+		}
+		spec := fft(buf)
+		row := make([]float64, nFreq)
+		for f := 0; f < nFreq; f++ {
+			a := cmplx.Abs(spec[f])
+			row[f] = a * a
+		}
+		out[t] = row
+	}
+	return out
+}
+
+// reflectAt indexes x with numpy "reflect" padding semantics.
+func reflectAt(x []float64, i int) float64 {
+	n := len(x)
+	if n == 1 {
+		return x[0]
+	}
+	period := 2 * (n - 1)
+	i = ((i % period) + period) % period
+	if i >= n {
+		i = period - i
+	}
+	return x[i]
+}
+
+// fft is a mixed-radix Cooley-Tukey FFT for any length; it recurses on the
+// smallest prime factor, so 400-point Whisper frames cost radix-2/5 steps.
+func fft(x []complex128) []complex128 {
+	n := len(x)
+	if n <= 1 {
+		return append([]complex128(nil), x...)
+	}
+	p := smallestFactor(n)
+	m := n / p
+
+	// FFT each decimated subsequence x[r], x[r+p], ...
+	subs := make([][]complex128, p)
+	tmp := make([]complex128, m)
+	for r := 0; r < p; r++ {
+		for j := 0; j < m; j++ {
+			tmp[j] = x[j*p+r]
+		}
+		subs[r] = fft(tmp)
+	}
+
+	out := make([]complex128, n)
+	for k := 0; k < n; k++ {
+		var acc complex128
+		for r := 0; r < p; r++ {
+			angle := -2 * math.Pi * float64(r*k) / float64(n)
+			acc += subs[r][k%m] * complex(math.Cos(angle), math.Sin(angle))
+		}
+		out[k] = acc
+	}
+	return out
+}
+
+func smallestFactor(n int) int {
+	for p := 2; p*p <= n; p++ {
+		if n%p == 0 {
+			return p
+		}
+	}
+	return n
+}
+
+// melFilterBank builds slaney-scale, slaney-normalized triangular filters,
+// as transformers.audio_utils.mel_filter_bank(norm="slaney",
+// mel_scale="slaney"). The result is [nMels][nFreq].
+func melFilterBank(nFreq, nMels int, minHz, maxHz float64, samplingRate int) [][]float64 {
+	melMin, melMax := hzToMelSlaney(minHz), hzToMelSlaney(maxHz)
+	filterFreqs := make([]float64, nMels+2)
+	for i := range filterFreqs {
+		filterFreqs[i] = melToHzSlaney(melMin + (melMax-melMin)*float64(i)/float64(nMels+1))
+	}
+	fftFreqs := make([]float64, nFreq)
+	for i := range fftFreqs {
+		fftFreqs[i] = float64(samplingRate/2) * float64(i) / float64(nFreq-1)
+	}
+
+	filters := make([][]float64, nMels)
+	for m := 0; m < nMels; m++ {
+		lower, center, upper := filterFreqs[m], filterFreqs[m+1], filterFreqs[m+2]
+		enorm := 2 / (upper - lower)
+		row := make([]float64, nFreq)
+		for f, hz := range fftFreqs {
+			down := (hz - lower) / (center - lower)
+			up := (upper - hz) / (upper - center)
+			row[f] = math.Max(0, math.Min(down, up)) * enorm
+		}
+		filters[m] = row
+	}
+	return filters
+}
+
+const (
+	slaneyMinLogHz  = 1000.0
+	slaneyMinLogMel = 15.0
+)
+
+var slaneyLogStep = 27.0 / math.Log(6.4)
+
+func hzToMelSlaney(hz float64) float64 {
+	if hz < slaneyMinLogHz {
+		return 3 * hz / 200
+	}
+	return slaneyMinLogMel + math.Log(hz/slaneyMinLogHz)*slaneyLogStep
+}
+
+func melToHzSlaney(mel float64) float64 {
+	if mel < slaneyMinLogMel {
+		return 200 * mel / 3
+	}
+	return slaneyMinLogHz * math.Exp((mel-slaneyMinLogMel)/slaneyLogStep)
+}
+
+// ASTFeatureExtractor computes the Kaldi-compatible log-mel filterbank of
+// Audio Spectrogram Transformer models, matching transformers'
+// ASTFeatureExtractor (torchaudio.compliance.kaldi.fbank with a Hanning
+// window, no dither and 10 ms frame shift).
+type ASTFeatureExtractor struct {
+	NumMelBins   int
+	SamplingRate int
+	MaxLength    int // frames; shorter inputs are zero-padded
+	DoNormalize  bool
+	Mean, Std    float64
+
+	melFilters [][]float64 // [NumMelBins][astNFFT/2]
+}
+
+const (
+	astFrameLength = 400 // 25 ms at 16 kHz
+	astFrameShift  = 160 // 10 ms
+	astNFFT        = 512 // frame length rounded up to a power of two
+)
+
+// newASTFeatureExtractor builds an extractor from preprocessor_config.json.
+func newASTFeatureExtractor(pp map[string]any) *ASTFeatureExtractor {
+	fe := &ASTFeatureExtractor{
+		NumMelBins:   intOption(pp, "num_mel_bins", 128),
+		SamplingRate: intOption(pp, "sampling_rate", 16000),
+		MaxLength:    intOption(pp, "max_length", 1024),
+		DoNormalize:  true,
+		Mean:         floatOption(pp, nil, "mean", -4.2677393),
+		Std:          floatOption(pp, nil, "std", 4.5689974),
+	}
+	if v, ok := pp["do_normalize"].(bool); ok {
+		fe.DoNormalize = v
+	}
+	fe.melFilters = kaldiMelBank(fe.NumMelBins, astNFFT, fe.SamplingRate, 20, float64(fe.SamplingRate)/2)
+	return fe
+}
+
+// Extract returns the [MaxLength x NumMelBins] filterbank, row-major.
+func (fe *ASTFeatureExtractor) Extract(samples []float32) []float32 {
+	frames := 0
+	if len(samples) >= astFrameLength {
+		frames = 1 + (len(samples)-astFrameLength)/astFrameShift
+	}
+	frames = min(frames, fe.MaxLength)
+
+	window := make([]float64, astFrameLength)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(astFrameLength-1))
+	}
+	const eps = 1.1920928955078125e-07 // float32 epsilon, Kaldi's log floor
+
+	out := make([]float32, fe.MaxLength*fe.NumMelBins)
+	frame := make([]float64, astFrameLength)
+	buf := make([]complex128, astNFFT)
+	power := make([]float64, astNFFT/2)
+	for t := 0; t < frames; t++ {
+		var mean float64
+		for i := range frame {
+			frame[i] = float64(samples[t*astFrameShift+i])
+			mean += frame[i]
+		}
+		mean /= astFrameLength
+		for i := range frame {
+			frame[i] -= mean // remove DC offset
+		}
+		for i := astFrameLength - 1; i > 0; i-- {
+			frame[i] -= 0.97 * frame[i-1] // pre-emphasis
+		}
+		frame[0] -= 0.97 * frame[0]
+
+		for i := range buf {
+			buf[i] = 0
+			if i < astFrameLength {
+				buf[i] = complex(frame[i]*window[i], 0)
+			}
+		}
+		spec := fft(buf)
+		for f := range power {
+			re, im := real(spec[f]), imag(spec[f])
+			power[f] = re*re + im*im
+		}
+		for m, filt := range fe.melFilters {
+			var acc float64
+			for f, w := range filt {
+				if w != 0 {
+					acc += w * power[f]
+				}
+			}
+			out[t*fe.NumMelBins+m] = float32(math.Log(math.Max(acc, eps)))
+		}
+	}
+
+	if fe.DoNormalize {
+		for i, v := range out {
+			out[i] = float32((float64(v) - fe.Mean) / (fe.Std * 2))
+		}
+	}
+	return out
+}
+
+// kaldiMelBank returns Kaldi's triangular mel filters over the first nFFT/2
+// FFT bins, spaced on the 1127*ln(1+f/700) mel scale.
+func kaldiMelBank(numBins, nFFT, samplingRate int, lowHz, highHz float64) [][]float64 {
+	mel := func(hz float64) float64 { return 1127 * math.Log(1+hz/700) }
+	lowMel, highMel := mel(lowHz), mel(highHz)
+	delta := (highMel - lowMel) / float64(numBins+1)
+	binHz := float64(samplingRate) / float64(nFFT)
+
+	bank := make([][]float64, numBins)
+	for b := range bank {
+		left, center, right := lowMel+float64(b)*delta, lowMel+float64(b+1)*delta, lowMel+float64(b+2)*delta
+		bank[b] = make([]float64, nFFT/2)
+		for f := range bank[b] {
+			m := mel(binHz * float64(f))
+			switch {
+			case m > left && m <= center:
+				bank[b][f] = (m - left) / (center - left)
+			case m > center && m < right:
+				bank[b][f] = (right - m) / (right - center)
+			}
+		}
+	}
+	return bank
+}
+
+// normalizeWaveform applies Wav2Vec2FeatureExtractor's zero-mean,
+// unit-variance normalization.
+func normalizeWaveform(x []float32) []float32 {
+	var mean, variance float64
+	for _, v := range x {
+		mean += float64(v)
+	}
+	mean /= float64(max(len(x), 1))
+	for _, v := range x {
+		d := float64(v) - mean
+		variance += d * d
+	}
+	variance /= float64(max(len(x), 1))
+	inv := 1 / math.Sqrt(variance+1e-7)
+	out := make([]float32, len(x))
+	for i, v := range x {
+		out[i] = float32((float64(v) - mean) * inv)
+	}
+	return out
+}
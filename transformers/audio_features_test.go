@@ -0,0 +1,86 @@
+package transformers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKaldiMelBank(t *testing.T) {
+	bank := kaldiMelBank(128, 512, 16000, 20, 8000)
+	if len(bank) != 128 || len(bank[0]) != 256 {
+		t.Fatalf("shape = %dx%d", len(bank), len(bank[0]))
+	}
+	// Adjacent triangles overlap so that the weights of every bin between
+	// the first and last centers sum to 1.
+	for f := 4; f < 250; f++ {
+		var sum float64
+		for _, row := range bank {
+			if row[f] < 0 || row[f] > 1 {
+				t.Fatalf("bin %d has weight %v", f, row[f])
+			}
+			sum += row[f]
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("bin %d: weights sum to %v", f, sum)
+		}
+	}
+}
+
+func TestNormalizeWaveform(t *testing.T) {
+	got := normalizeWaveform([]float32{1, 2, 3, 4})
+	// mean 2.5, variance 1.25
+	want := []float64{-1.341640, -0.447213, 0.447213, 1.341640}
+	for i := range want {
+		if math.Abs(float64(got[i])-want[i]) > 1e-5 {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+	if out := normalizeWaveform(nil); len(out) != 0 {
+		t.Fatalf("empty input: got %v", out)
+	}
+}
+
+func TestASTFeatureExtractor(t *testing.T) {
+	fe := newASTFeatureExtractor(map[string]any{"max_length": 10, "do_normalize": false})
+	// 1600 samples make 1 + (1600-400)/160 = 8 frames; 2 rows of padding.
+	feats := fe.Extract(make([]float32, 1600))
+	if len(feats) != 10*fe.NumMelBins {
+		t.Fatalf("len = %d", len(feats))
+	}
+	// Silence sits at Kaldi's log floor, ln(float32 epsilon).
+	floor := float32(math.Log(1.1920928955078125e-07))
+	if feats[0] != floor || feats[8*fe.NumMelBins-1] != floor {
+		t.Fatalf("silent frames = %v, %v; want %v", feats[0], feats[8*fe.NumMelBins-1], floor)
+	}
+	if feats[8*fe.NumMelBins] != 0 || feats[len(feats)-1] != 0 {
+		t.Fatal("padding rows are not zero")
+	}
+
+	// Normalization uses the AudioSet statistics: (x - mean) / (2 * std).
+	fe = newASTFeatureExtractor(map[string]any{"max_length": 10})
+	feats = fe.Extract(make([]float32, 1600))
+	want := float32((0 + 4.2677393) / (2 * 4.5689974))
+	if d := feats[len(feats)-1] - want; d > 1e-6 || d < -1e-6 {
+		t.Fatalf("normalized padding = %v, want %v", feats[len(feats)-1], want)
+	}
+
+	// A 1 kHz tone peaks in the mel bin whose center is closest to it.
+	tone := make([]float32, 1600)
+	for i := range tone {
+		tone[i] = float32(0.5 * math.Sin(2*math.Pi*1000*float64(i)/16000))
+	}
+	fe = newASTFeatureExtractor(map[string]any{"max_length": 10, "do_normalize": false})
+	feats = fe.Extract(tone)
+	row := feats[4*fe.NumMelBins : 5*fe.NumMelBins]
+	peak := 0
+	for m := range row {
+		if row[m] > row[peak] {
+			peak = m
+		}
+	}
+	mel := func(hz float64) float64 { return 1127 * math.Log(1+hz/700) }
+	delta := (mel(8000) - mel(20)) / float64(fe.NumMelBins+1)
+	if want := int(math.Round((mel(1000)-mel(20))/delta)) - 1; peak < want-1 || peak > want+1 {
+		t.Fatalf("1 kHz tone peaks in bin %d, want ~%d", peak, want)
+	}
+}
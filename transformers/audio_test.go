@@ -0,0 +1,187 @@
+package transformers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+// wavBytes builds a 16-bit PCM WAV. dataSize overrides the data chunk's
+// declared size when non-negative.
+func wavBytes(channels, rate int, samples []int16, dataSize int64) []byte {
+	var b bytes.Buffer
+	w := func(v any) { binary.Write(&b, binary.LittleEndian, v) }
+	pcm := int64(2 * len(samples))
+	if dataSize < 0 {
+		dataSize = pcm
+	}
+	b.WriteString("RIFF")
+	w(uint32(36 + pcm))
+	b.WriteString("WAVEfmt ")
+	w(uint32(16))
+	w(uint16(1))
+	w(uint16(channels))
+	w(uint32(rate))
+	w(uint32(rate * channels * 2))
+	w(uint16(channels * 2))
+	w(uint16(16))
+	b.WriteString("data")
+	w(uint32(dataSize))
+	w(samples)
+	return b.Bytes()
+}
+
+func TestDecodeWAV(t *testing.T) {
+	stereo := wavBytes(2, 8000, []int16{16384, 0, -32768, -32768}, -1)
+	a, err := DecodeWAV(stereo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.SamplingRate != 8000 || len(a.Samples) != 2 || a.Samples[0] != 0.25 || a.Samples[1] != -1 {
+		t.Fatalf("got %+v", a)
+	}
+
+	// A data chunk claiming 4 GiB is clamped to what is there.
+	a, err = DecodeWAV(wavBytes(1, 16000, []int16{0, 16384}, math.MaxUint32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Samples) != 2 {
+		t.Fatalf("truncated data: got %d samples", len(a.Samples))
+	}
+
+	// So is a fmt chunk, which is an error.
+	bad := wavBytes(1, 16000, []int16{0}, -1)
+	binary.LittleEndian.PutUint32(bad[16:], math.MaxUint32)
+	if _, err := DecodeWAV(bad); err == nil {
+		t.Fatal("oversized fmt chunk: expected an error")
+	}
+
+	if _, err := DecodeWAV([]byte("RIFF\x00\x00\x00\x00WAVE")); err == nil {
+		t.Fatal("no chunks: expected an error")
+	}
+}
+
+func TestResample(t *testing.T) {
+	// A 440 Hz tone keeps its frequency and amplitude across 48k -> 16k.
+	const src, dst = 48000, 16000
+	x := make([]float32, src/10)
+	for i := range x {
+		x[i] = float32(math.Sin(2 * math.Pi * 440 * float64(i) / src))
+	}
+	y := resample(x, src, dst)
+	if len(y) != len(x)/3 {
+		t.Fatalf("len = %d, want %d", len(y), len(x)/3)
+	}
+	for i := 100; i < len(y)-100; i++ {
+		want := math.Sin(2 * math.Pi * 440 * float64(i) / dst)
+		if d := math.Abs(float64(y[i]) - want); d > 2e-3 {
+			t.Fatalf("y[%d] = %v, want %v", i, y[i], want)
+		}
+	}
+
+	// A tone above the new Nyquist frequency is filtered out.
+	for i := range x {
+		x[i] = float32(math.Sin(2 * math.Pi * 12000 * float64(i) / src))
+	}
+	y = resample(x, src, dst)
+	for i := 100; i < len(y)-100; i++ {
+		if math.Abs(float64(y[i])) > 0.02 {
+			t.Fatalf("aliased y[%d] = %v", i, y[i])
+		}
+	}
+}
+
+func TestFFT(t *testing.T) {
+	for _, n := range []int{1, 2, 8, 12, 400, 7} {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(math.Sin(float64(i)*0.7), math.Cos(float64(i)*1.3))
+		}
+		got := fft(x)
+		for k := 0; k < n; k++ {
+			var want complex128
+			for j := 0; j < n; j++ {
+				want += x[j] * cmplx.Exp(complex(0, -2*math.Pi*float64(j*k)/float64(n)))
+			}
+			if cmplx.Abs(got[k]-want) > 1e-9 {
+				t.Fatalf("n=%d: X[%d] = %v, want %v", n, k, got[k], want)
+			}
+		}
+	}
+}
+
+func TestSTFTPower(t *testing.T) {
+	// A cosine at bin 10 of a 400-point frame peaks there; frames are
+	// centered, so a 1 s Whisper clip has 1 + 16000/160 of them.
+	const nFFT, hop = 400, 160
+	x := make([]float64, 16000)
+	for i := range x {
+		x[i] = math.Cos(2 * math.Pi * 10 * float64(i) / nFFT)
+	}
+	frames := stftPower(x, nFFT, hop)
+	if len(frames) != 101 || len(frames[0]) != nFFT/2+1 {
+		t.Fatalf("shape = %dx%d", len(frames), len(frames[0]))
+	}
+	mid := frames[50]
+	peak := 0
+	for f := range mid {
+		if mid[f] > mid[peak] {
+			peak = f
+		}
+	}
+	// Hann-windowed unit cosine: |X[k]| = N/4.
+	if peak != 10 || math.Abs(mid[10]-math.Pow(nFFT/4, 2)) > 1e-6 {
+		t.Fatalf("peak at %d with power %v", peak, mid[peak])
+	}
+}
+
+func TestReflectAt(t *testing.T) {
+	x := []float64{0, 1, 2, 3}
+	for i, want := range map[int]float64{-1: 1, -3: 3, 4: 2, 6: 0, 2: 2} {
+		if got := reflectAt(x, i); got != want {
+			t.Errorf("reflectAt(%d) = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSlaneyMel(t *testing.T) {
+	// Golden values from librosa.hz_to_mel(htk=False).
+	for hz, mel := range map[float64]float64{0: 0, 500: 7.5, 1000: 15, 2000: 25.081880, 8000: 45.245640} {
+		if got := hzToMelSlaney(hz); math.Abs(got-mel) > 1e-5 {
+			t.Errorf("hzToMelSlaney(%v) = %v, want %v", hz, got, mel)
+		}
+		if got := melToHzSlaney(mel); math.Abs(got-hz) > 1e-2 {
+			t.Errorf("melToHzSlaney(%v) = %v, want %v", mel, got, hz)
+		}
+	}
+}
+
+func TestMelFilterBank(t *testing.T) {
+	// Whisper's 80-bin bank: slaney-normalized triangles whose area in Hz
+	// is 1, i.e. each row sums to ~1/binHz.
+	const nFreq, sr = 201, 16000
+	bank := melFilterBank(nFreq, 80, 0, 8000, sr)
+	if len(bank) != 80 || len(bank[0]) != nFreq {
+		t.Fatalf("shape = %dx%d", len(bank), len(bank[0]))
+	}
+	binHz := float64(sr/2) / (nFreq - 1)
+	for m := 20; m < 80; m++ { // low filters are narrower than a bin
+		var sum float64
+		for _, v := range bank[m] {
+			if v < 0 {
+				t.Fatalf("filter %d has negative weight", m)
+			}
+			sum += v
+		}
+		if math.Abs(sum*binHz-1) > 0.1 {
+			t.Errorf("filter %d area = %v", m, sum*binHz)
+		}
+	}
+	// Golden value from Whisper's mel_filters.npz (mel_80[0][1]).
+	if math.Abs(bank[0][1]-0.02486259) > 1e-7 {
+		t.Errorf("bank[0][1] = %v, want 0.02486259", bank[0][1])
+	}
+}
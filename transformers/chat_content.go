@@ -0,0 +1,88 @@
+package transformers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// imagePlaceholder marks an image part in rendered chat prompts. Vision
+// models expand it into their image token sequence.
+const imagePlaceholder = "<image>"
+
+// TextPart is a text content part.
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: "text", Text: text}
+}
+
+// ImagePart is an image content part; image is a file path, encoded
+// bytes or an image.Image.
+func ImagePart(image any) ContentPart {
+	return ContentPart{Type: "image", Image: image}
+}
+
+// TextContent returns the message as prompt text: Content, or the text
+// parts in order with an <image> placeholder for every image part.
+func (m ChatMessage) TextContent() string {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	var b strings.Builder
+	for _, p := range m.Parts {
+		switch p.Type {
+		case "text":
+			b.WriteString(p.Text)
+		case "image":
+			b.WriteString(imagePlaceholder)
+		}
+	}
+	return b.String()
+}
+
+// chatImages collects the image parts of messages in prompt order.
+func chatImages(messages []ChatMessage) []any {
+	var images []any
+	for _, m := range messages {
+		for _, p := range m.Parts {
+			if p.Type == "image" {
+				images = append(images, p.Image)
+			}
+		}
+	}
+	return images
+}
+
+// MarshalJSON writes "content" as a list of parts when Parts is set.
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type plain ChatMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []ContentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+// UnmarshalJSON accepts "content" as a string or a list of parts.
+func (m *ChatMessage) UnmarshalJSON(data []byte) error {
+	type plain ChatMessage
+	var aux struct {
+		plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*m = ChatMessage(aux.plain)
+	if len(aux.Content) == 0 || string(aux.Content) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Content, &m.Content); err == nil {
+		return nil
+	}
+	if err := json.Unmarshal(aux.Content, &m.Parts); err != nil {
+		return fmt.Errorf("chat message content: %w", err)
+	}
+	return nil
+}
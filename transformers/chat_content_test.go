@@ -0,0 +1,83 @@
+package transformers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestChatMessageJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  ChatMessage
+		json string
+	}{
+		{
+			"string content",
+			ChatMessage{Role: RoleUser, Content: "hi"},
+			`{"role":"user","content":"hi"}`,
+		},
+		{
+			"parts",
+			ChatMessage{Role: RoleUser, Parts: []ContentPart{ImagePart("cat.png"), TextPart("What is this?")}},
+			`{"role":"user","content":[{"type":"image","image":"cat.png"},{"type":"text","text":"What is this?"}]}`,
+		},
+		{
+			"tool reply",
+			ChatMessage{Role: RoleTool, Content: "42", ToolCallID: "call_1"},
+			`{"role":"tool","content":"42","tool_call_id":"call_1"}`,
+		},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(tt.msg)
+		if err != nil {
+			t.Fatalf("%s: Marshal: %v", tt.name, err)
+		}
+		if string(data) != tt.json {
+			t.Errorf("%s: Marshal = %s, want %s", tt.name, data, tt.json)
+		}
+		var got ChatMessage
+		if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+			t.Fatalf("%s: Unmarshal: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.msg) {
+			t.Errorf("%s: Unmarshal = %+v, want %+v", tt.name, got, tt.msg)
+		}
+	}
+
+	var m ChatMessage
+	if err := json.Unmarshal([]byte(`{"role":"user","content":null}`), &m); err != nil || m.Content != "" || m.Parts != nil {
+		t.Errorf("null content: got %+v, %v", m, err)
+	}
+	if err := json.Unmarshal([]byte(`{"role":"user","content":42}`), &m); err == nil {
+		t.Error("numeric content: expected an error")
+	}
+}
+
+func TestChatMessageTextContent(t *testing.T) {
+	tests := []struct {
+		msg  ChatMessage
+		want string
+	}{
+		{ChatMessage{Content: "plain"}, "plain"},
+		{ChatMessage{Content: "ignored", Parts: []ContentPart{TextPart("a "), ImagePart(nil), TextPart(" b")}}, "a <image> b"},
+		{ChatMessage{Parts: []ContentPart{ImagePart(nil), ImagePart(nil)}}, "<image><image>"},
+		{ChatMessage{Parts: []ContentPart{{Type: "audio"}, TextPart("x")}}, "x"},
+	}
+	for _, tt := range tests {
+		if got := tt.msg.TextContent(); got != tt.want {
+			t.Errorf("TextContent(%+v) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestChatImages(t *testing.T) {
+	msgs := []ChatMessage{
+		{Role: RoleUser, Parts: []ContentPart{ImagePart("a.png"), TextPart("and")}},
+		{Role: RoleAssistant, Content: "ok"},
+		{Role: RoleUser, Parts: []ContentPart{ImagePart("b.png")}},
+	}
+	if got := chatImages(msgs); !reflect.DeepEqual(got, []any{"a.png", "b.png"}) {
+		t.Errorf("chatImages = %v", got)
+	}
+}
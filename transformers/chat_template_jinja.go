@@ -6,9 +6,10 @@ import (
 	pongo "github.com/flosch/pongo2/v6"
 )
 
-// loadChatTemplate attempts to load chat_template.jinja from the HF repo.
-// Returns nil if not present or on error.
-func loadChatTemplate(modelID string) (func([]ChatMessage) (string, error), error) {
+// loadChatTemplate attempts to load chat_template.jinja from the HF repo,
+// falling back to the default template. custom reports whether the repo
+// shipped its own. Returns nil if the template does not parse.
+func loadChatTemplate(modelID string) (render func([]ChatMessage) (string, error), custom bool) {
 	raw := []byte(defaultChatTemplateJinja)
 
 	// Best effort fetch; do not error on absence.
@@ -16,21 +17,32 @@ func loadChatTemplate(modelID string) (func([]ChatMessage) (string, error), erro
 		if path, ok := paths["chat_template.jinja"]; ok {
 			if b, err := os.ReadFile(path); err == nil && len(b) > 0 {
 				raw = b
+				custom = true
 			}
 		}
 	}
 
 	tpl, err := pongo.FromString(string(raw))
 	if err != nil {
-		return nil, nil
+		return nil, false
 	}
 
 	renderer := func(msgs []ChatMessage) (string, error) {
 		jmsgs := make([]map[string]any, 0, len(msgs))
 		for _, m := range msgs {
+			// Repo templates of vision models loop over content parts;
+			// the default template prints content as text.
+			var content any = m.TextContent()
+			if custom && len(m.Parts) > 0 {
+				parts := make([]map[string]any, len(m.Parts))
+				for i, p := range m.Parts {
+					parts[i] = map[string]any{"type": p.Type, "text": p.Text}
+				}
+				content = parts
+			}
 			jmsgs = append(jmsgs, map[string]any{
 				"role":    string(m.Role),
-				"content": m.Content,
+				"content": content,
 			})
 		}
 		out, err := tpl.Execute(pongo.Context{
@@ -39,7 +51,7 @@ func loadChatTemplate(modelID string) (func([]ChatMessage) (string, error), erro
 		})
 		return out, err
 	}
-	return renderer, nil
+	return renderer, custom
 }
 
 // Default Jinja chat template inspired by LFM2-350M-Extract-ONNX.
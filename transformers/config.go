@@ -19,11 +19,18 @@ type Config struct {
 	hiddenSize        int
 	convLCache        int
 	layerTypes        []string
+	headDim           int
+
+	// encoder-decoder models
+	isEncoderDecoder    bool
+	decoderStartTokenID int64
+	forcedBOSTokenID    int64
 
 	raw map[string]any
 
 	// generation config (optional)
 	stopStrings []string
+	generation  map[string]any
 }
 
 // AutoConfig is the HF-style static dispatcher:
@@ -93,7 +100,26 @@ func (autoConfig) FromPretrained(
 		numKeyValueHeads:  getInt("num_key_value_heads", 0),
 		hiddenSize:        getInt("hidden_size", 0),
 		convLCache:        getInt("conv_l_cache", 0),
+		headDim:           getInt("head_dim", getInt("d_kv", 0)),
 		raw:               raw,
+
+		decoderStartTokenID: getInt64("decoder_start_token_id", -1),
+		forcedBOSTokenID:    getInt64("forced_bos_token_id", -1),
+	}
+	cfg.isEncoderDecoder, _ = raw["is_encoder_decoder"].(bool)
+
+	// Seq2seq configs (T5, BART, Marian) use their own names for these.
+	if cfg.numHiddenLayers == 0 {
+		cfg.numHiddenLayers = getInt("num_layers", getInt("decoder_layers", 0))
+	}
+	if cfg.numAttentionHeads == 0 {
+		cfg.numAttentionHeads = getInt("num_heads", getInt("decoder_attention_heads", 0))
+	}
+	if cfg.hiddenSize == 0 {
+		cfg.hiddenSize = getInt("d_model", 0)
+	}
+	if cfg.headDim == 0 && cfg.numAttentionHeads > 0 {
+		cfg.headDim = cfg.hiddenSize / cfg.numAttentionHeads
 	}
 
 	if lt, ok := raw["layer_types"].([]any); ok {
@@ -129,6 +155,21 @@ func (c *Config) ConvLCache() int          { return c.convLCache }
 func (c *Config) LayerTypes() []string     { return c.layerTypes }
 func (c *Config) Raw() map[string]any      { return c.raw }
 func (c *Config) StopStrings() []string    { return c.stopStrings }
+func (c *Config) HeadDim() int             { return c.headDim }
+func (c *Config) IsEncoderDecoder() bool   { return c.isEncoderDecoder }
+
+// DecoderStartTokenID is the first decoder input of encoder-decoder models,
+// or -1 if the config does not set one.
+func (c *Config) DecoderStartTokenID() int64 { return c.decoderStartTokenID }
+
+// ForcedBOSTokenID is the token forced right after the decoder start token
+// (e.g. <s> for BART, the target language for mBART/NLLB), or -1.
+func (c *Config) ForcedBOSTokenID() int64 { return c.forcedBOSTokenID }
+
+// GenerationConfig is the raw generation_config.json, or nil if the model
+// has none. Model-specific settings (Whisper's lang_to_id, suppress_tokens)
+// live here.
+func (c *Config) GenerationConfig() map[string]any { return c.generation }
 
 func (c *Config) applyGenerationConfig(modelID string) {
 	genPath, err := HFHubDownload(modelID, "generation_config.json")
@@ -143,6 +184,7 @@ func (c *Config) applyGenerationConfig(modelID string) {
 	if err := json.Unmarshal(data, &gen); err != nil {
 		return
 	}
+	c.generation = gen
 	// Override token IDs if present
 	if v, ok := gen["eos_token_id"]; ok {
 		if id, ok2 := toInt64(v); ok2 {
@@ -159,6 +201,16 @@ func (c *Config) applyGenerationConfig(modelID string) {
 			c.padTokenID = id
 		}
 	}
+	if v, ok := gen["decoder_start_token_id"]; ok {
+		if id, ok2 := toInt64(v); ok2 {
+			c.decoderStartTokenID = id
+		}
+	}
+	if v, ok := gen["forced_bos_token_id"]; ok {
+		if id, ok2 := toInt64(v); ok2 {
+			c.forcedBOSTokenID = id
+		}
+	}
 	// Collect stop strings if present
 	if v, ok := gen["stop"]; ok {
 		switch t := v.(type) {
@@ -0,0 +1,62 @@
+package transformers
+
+import "strings"
+
+// generationState tracks the tokens produced by a decoding loop and applies
+// the per-token bookkeeping shared by every loop: incremental detokenizing,
+// stop sequences, EOS and the streamer callback.
+type generationState struct {
+	eosID     int64
+	generated []int64
+	fullText  string
+}
+
+// advance records nextID and reports whether generation should stop.
+func (st *generationState) advance(
+	tokenizer *Tokenizer,
+	nextID int64,
+	step int,
+	opts GenerationOptions,
+) bool {
+	st.generated = append(st.generated, nextID)
+
+	deltaText := ""
+	if tokenizer != nil {
+		txt, err := tokenizer.Decode([]int64{nextID})
+		if err == nil {
+			deltaText = txt
+			st.fullText += deltaText
+		}
+	}
+
+	// Stop sequence handling (string-based).
+	stopHit := false
+	for _, stop := range opts.StopSequences {
+		if stop == "" {
+			continue
+		}
+		if idx := strings.Index(st.fullText, stop); idx >= 0 {
+			st.fullText = st.fullText[:idx]
+			deltaText = "" // avoid streaming the stop tail
+			stopHit = true
+			break
+		}
+	}
+
+	done := st.eosID >= 0 && nextID == st.eosID
+
+	if opts.Streamer != nil {
+		ev := PipelineStreamEvent{
+			TokenID:   nextID,
+			DeltaText: deltaText,
+			FullText:  st.fullText,
+			Step:      step,
+			Done:      done || stopHit,
+		}
+		if !opts.Streamer(ev) {
+			return true
+		}
+	}
+
+	return done || stopHit
+}
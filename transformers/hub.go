@@ -14,21 +14,24 @@ import (
 // HFHubDownload downloads a file from a Hugging Face repo into a local cache.
 // Very simple v1: no auth, no revision. Cache dir can be overridden with CACHE_DIR env;
 // default: ./models/huggingface.co/<repoID>/resolve/main/
+//
+// repoID may also be a local model directory (see FromDirectory), in which
+// case files are read from it in place and nothing is downloaded.
 func HFHubDownload(repoID, filename string) (string, error) {
-	cacheDir, err := hfCacheDir(repoID)
+	cacheDir, download, err := hfResolveDir(repoID)
 	if err != nil {
 		return "", err
 	}
 	localPath := filepath.Join(cacheDir, filename)
-	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
-		return "", err
-	}
 
 	if _, err := os.Stat(localPath); err == nil {
 		// already cached
 		trackDownloaded(repoID, cacheDir, localPath)
 		return localPath, nil
 	}
+	if !download {
+		return "", fmt.Errorf("HFHubDownload %s: %w", filename, notLocalError(repoID, cacheDir))
+	}
 
 	url := fmt.Sprintf("https://huggingface.co/%s/resolve/main/%s", repoID, filename)
 
@@ -46,7 +49,7 @@ func HFHubDownload(repoID, filename string) (string, error) {
 // HFHubEnsureFiles checks (via HEAD) and downloads a set of files into the cache.
 // Returns a map of filename -> local path.
 func HFHubEnsureFiles(repoID string, files []string) (map[string]string, error) {
-	cacheDir, err := hfCacheDir(repoID)
+	cacheDir, download, err := hfResolveDir(repoID)
 	if err != nil {
 		return nil, err
 	}
@@ -56,14 +59,14 @@ func HFHubEnsureFiles(repoID string, files []string) (map[string]string, error)
 			continue
 		}
 		localPath := filepath.Join(cacheDir, name)
-		if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
-			return nil, err
-		}
 		if _, err := os.Stat(localPath); err == nil {
 			res[name] = localPath
 			trackDownloaded(repoID, cacheDir, localPath)
 			continue
 		}
+		if !download {
+			return nil, fmt.Errorf("%s: %w", name, notLocalError(repoID, cacheDir))
+		}
 		url := fmt.Sprintf("https://huggingface.co/%s/resolve/main/%s", repoID, name)
 		if err := headURL(url); err != nil {
 			return nil, fmt.Errorf("HEAD %s: %w", name, err)
@@ -80,7 +83,7 @@ func HFHubEnsureFiles(repoID string, files []string) (map[string]string, error)
 // HFHubEnsureOptionalFiles is like HFHubEnsureFiles but skips files that return 404 on HEAD.
 // It returns a map of filename -> local path for the files that were found/downloaded.
 func HFHubEnsureOptionalFiles(repoID string, files []string) (map[string]string, error) {
-	cacheDir, err := hfCacheDir(repoID)
+	cacheDir, download, err := hfResolveDir(repoID)
 	if err != nil {
 		return nil, err
 	}
@@ -90,14 +93,14 @@ func HFHubEnsureOptionalFiles(repoID string, files []string) (map[string]string,
 			continue
 		}
 		localPath := filepath.Join(cacheDir, name)
-		if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
-			return nil, err
-		}
 		if _, err := os.Stat(localPath); err == nil {
 			res[name] = localPath
 			trackDownloaded(repoID, cacheDir, localPath)
 			continue
 		}
+		if !download {
+			continue
+		}
 		url := fmt.Sprintf("https://huggingface.co/%s/resolve/main/%s", repoID, name)
 		status, err := headURLStatus(url)
 		if err != nil {
@@ -118,12 +121,30 @@ func HFHubEnsureOptionalFiles(repoID string, files []string) (map[string]string,
 	return res, nil
 }
 
-func hfCacheDir(repoID string) (string, error) {
+// hfResolveDir returns the directory repoID's files live in and whether
+// missing ones may be downloaded into it: a local model directory is used
+// as is, and the cache is read-only under HF_HUB_OFFLINE.
+func hfResolveDir(repoID string) (dir string, download bool, err error) {
+	if dir, ok, err := localModelDir(repoID); err != nil || ok {
+		return dir, false, err
+	}
+	if isOffline() {
+		return hfCachePath(repoID), false, nil
+	}
+	dir, err = hfCacheDir(repoID)
+	return dir, true, err
+}
+
+func hfCachePath(repoID string) string {
 	base := os.Getenv("CACHE_DIR")
 	if base == "" {
 		base = filepath.Join(".", "models")
 	}
-	cacheDir := filepath.Join(base, "huggingface.co", repoID, "resolve", "main")
+	return filepath.Join(base, "huggingface.co", repoID, "resolve", "main")
+}
+
+func hfCacheDir(repoID string) (string, error) {
+	cacheDir := hfCachePath(repoID)
 	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
 		return "", err
 	}
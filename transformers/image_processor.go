@@ -0,0 +1,557 @@
+package transformers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"strings"
+
+	// Register the stdlib decoders used by DecodeImage.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// ImageProcessor turns images into model pixel tensors following a
+// transformers preprocessor_config.json: convert to RGB (alpha is dropped),
+// resize, center crop, rescale, normalize and pad.
+type ImageProcessor struct {
+	DoResize bool
+	Size     ImageSize
+	Resample ImageResample
+
+	DoCenterCrop bool
+	CropSize     ImageSize
+
+	DoRescale     bool
+	RescaleFactor float32
+
+	DoNormalize bool
+	ImageMean   []float32
+	ImageStd    []float32
+
+	// DoPad pads bottom/right with zeros to PadSize, or up to a multiple of
+	// SizeDivisor when that is set.
+	DoPad       bool
+	PadSize     ImageSize
+	SizeDivisor int
+
+	// ChannelsLast emits [1, H, W, C] instead of [1, C, H, W].
+	ChannelsLast bool
+
+	// Raw is the parsed preprocessor_config.json.
+	Raw map[string]any
+}
+
+// ImageSize is a target size. Height/Width give an exact size;
+// ShortestEdge/LongestEdge resize keeping the aspect ratio.
+type ImageSize struct {
+	Height, Width             int
+	ShortestEdge, LongestEdge int
+}
+
+// ImageResample is a PIL resampling filter code, as stored in "resample".
+type ImageResample int
+
+const (
+	ResampleNearest  ImageResample = 0
+	ResampleLanczos  ImageResample = 1
+	ResampleBilinear ImageResample = 2
+	ResampleBicubic  ImageResample = 3
+	ResampleBox      ImageResample = 4
+	ResampleHamming  ImageResample = 5
+)
+
+// PixelValues is a preprocessed image batch of one.
+type PixelValues struct {
+	Data  []float32
+	Shape []int64 // [1, C, H, W], or [1, H, W, C] with ChannelsLast
+
+	ChannelsLast bool
+
+	// OriginalSize is the input (height, width); ResizedSize is the size
+	// after resize and crop, before padding. Detection and segmentation map
+	// predictions back through them.
+	OriginalSize [2]int
+	ResizedSize  [2]int
+
+	// PixelMask is [H, W] with 1 over real pixels and 0 over padding.
+	PixelMask []int64
+}
+
+// autoImageProcessor is the HF-style static dispatcher:
+//
+//	processor, err := AutoImageProcessor.FromPretrained(modelID)
+type autoImageProcessor struct{}
+
+var AutoImageProcessor autoImageProcessor
+
+// FromPretrained reads preprocessor_config.json from HF Hub.
+func (autoImageProcessor) FromPretrained(modelID string) (*ImageProcessor, error) {
+	raw, err := loadPreprocessorConfig(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("AutoImageProcessor: %w", err)
+	}
+	p := NewImageProcessor(raw)
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("AutoImageProcessor: %s: %w", modelID, err)
+	}
+	return p, nil
+}
+
+// NewImageProcessor builds a processor from a parsed preprocessor config.
+// Missing keys take the transformers BaseImageProcessor defaults.
+func NewImageProcessor(raw map[string]any) *ImageProcessor {
+	getBool := func(key string, def bool) bool {
+		if v, ok := raw[key].(bool); ok {
+			return v
+		}
+		return def
+	}
+	p := &ImageProcessor{
+		DoResize:      getBool("do_resize", true),
+		Resample:      ImageResample(intOption(raw, "resample", int(ResampleBilinear))),
+		DoCenterCrop:  getBool("do_center_crop", false),
+		DoRescale:     getBool("do_rescale", true),
+		RescaleFactor: 1.0 / 255,
+		DoNormalize:   getBool("do_normalize", true),
+		ImageMean:     []float32{0.5, 0.5, 0.5},
+		ImageStd:      []float32{0.5, 0.5, 0.5},
+		DoPad:         getBool("do_pad", false),
+		SizeDivisor:   intOption(raw, "size_divisor", intOption(raw, "size_divisibility", 0)),
+		Raw:           raw,
+	}
+	if v, ok := raw["rescale_factor"].(float64); ok {
+		p.RescaleFactor = float32(v)
+	}
+	if v := floatList(raw["image_mean"]); v != nil {
+		p.ImageMean = v
+	}
+	if v := floatList(raw["image_std"]); v != nil {
+		p.ImageStd = v
+	}
+
+	// An integer "size" means the shortest edge for CLIP-style processors
+	// (which crop afterwards) and a square otherwise.
+	p.Size = parseImageSize(raw["size"], p.DoCenterCrop)
+	if p.Size == (ImageSize{}) {
+		p.Size = ImageSize{Height: 224, Width: 224}
+	}
+	p.CropSize = parseImageSize(raw["crop_size"], false)
+	if p.CropSize == (ImageSize{}) {
+		p.CropSize = ImageSize{Height: 224, Width: 224}
+	}
+	p.PadSize = parseImageSize(raw["pad_size"], false)
+
+	if df, _ := raw["data_format"].(string); df == "channels_last" {
+		p.ChannelsLast = true
+	}
+	return p
+}
+
+// validate rejects normalization settings Preprocess cannot apply.
+func (p *ImageProcessor) validate() error {
+	if !p.DoNormalize {
+		return nil
+	}
+	if len(p.ImageMean) == 0 || len(p.ImageStd) == 0 {
+		return errors.New("image_mean and image_std must not be empty")
+	}
+	for _, s := range p.ImageStd {
+		if s == 0 {
+			return fmt.Errorf("image_std %v has a zero entry", p.ImageStd)
+		}
+	}
+	return nil
+}
+
+func parseImageSize(v any, intIsShortestEdge bool) ImageSize {
+	switch t := v.(type) {
+	case float64:
+		if intIsShortestEdge {
+			return ImageSize{ShortestEdge: int(t)}
+		}
+		return ImageSize{Height: int(t), Width: int(t)}
+	case []any:
+		if len(t) == 2 {
+			h, _ := t[0].(float64)
+			w, _ := t[1].(float64)
+			return ImageSize{Height: int(h), Width: int(w)}
+		}
+	case map[string]any:
+		return ImageSize{
+			Height:       intOption(t, "height", 0),
+			Width:        intOption(t, "width", 0),
+			ShortestEdge: intOption(t, "shortest_edge", 0),
+			LongestEdge:  intOption(t, "longest_edge", 0),
+		}
+	}
+	return ImageSize{}
+}
+
+func floatList(v any) []float32 {
+	switch t := v.(type) {
+	case float64:
+		return []float32{float32(t), float32(t), float32(t)}
+	case []any:
+		out := make([]float32, 0, len(t))
+		for _, x := range t {
+			f, ok := x.(float64)
+			if !ok {
+				return nil
+			}
+			out = append(out, float32(f))
+		}
+		return out
+	}
+	return nil
+}
+
+// rgbImage is an image as float32 RGB values in [0, 255], row-major [H][W][3].
+type rgbImage struct {
+	pix  []float32
+	w, h int
+}
+
+func newRGBImage(img image.Image) *rgbImage {
+	b := img.Bounds()
+	out := &rgbImage{w: b.Dx(), h: b.Dy(), pix: make([]float32, b.Dx()*b.Dy()*3)}
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			i := (y*out.w + x) * 3
+			out.pix[i], out.pix[i+1], out.pix[i+2] = float32(c.R), float32(c.G), float32(c.B)
+		}
+	}
+	return out
+}
+
+// Preprocess converts img into pixel_values.
+func (p *ImageProcessor) Preprocess(img image.Image) (*PixelValues, error) {
+	if img == nil {
+		return nil, fmt.Errorf("ImageProcessor: nil image")
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("ImageProcessor: %w", err)
+	}
+	src := newRGBImage(img)
+	if src.w == 0 || src.h == 0 {
+		return nil, fmt.Errorf("ImageProcessor: empty image")
+	}
+	pv := &PixelValues{OriginalSize: [2]int{src.h, src.w}}
+
+	if p.DoResize {
+		h, w := p.resizeTarget(src.h, src.w)
+		src = resizeRGB(src, w, h, p.Resample)
+	}
+	if p.DoCenterCrop {
+		src = centerCropRGB(src, p.CropSize.Height, p.CropSize.Width)
+	}
+	pv.ResizedSize = [2]int{src.h, src.w}
+
+	// Rescale and normalize per channel.
+	vals := make([]float32, len(src.pix))
+	for i, v := range src.pix {
+		c := i % 3
+		if p.DoRescale {
+			v *= p.RescaleFactor
+		}
+		if p.DoNormalize {
+			v = (v - p.ImageMean[c%len(p.ImageMean)]) / p.ImageStd[c%len(p.ImageStd)]
+		}
+		vals[i] = v
+	}
+
+	outH, outW := src.h, src.w
+	if p.DoPad {
+		switch {
+		case p.PadSize.Height > 0 && p.PadSize.Width > 0:
+			outH, outW = max(outH, p.PadSize.Height), max(outW, p.PadSize.Width)
+		case p.SizeDivisor > 0:
+			outH = (outH + p.SizeDivisor - 1) / p.SizeDivisor * p.SizeDivisor
+			outW = (outW + p.SizeDivisor - 1) / p.SizeDivisor * p.SizeDivisor
+		}
+	}
+
+	pv.Data = make([]float32, 3*outH*outW)
+	pv.PixelMask = make([]int64, outH*outW)
+	for y := 0; y < src.h; y++ {
+		for x := 0; x < src.w; x++ {
+			pv.PixelMask[y*outW+x] = 1
+			for c := 0; c < 3; c++ {
+				v := vals[(y*src.w+x)*3+c]
+				if p.ChannelsLast {
+					pv.Data[(y*outW+x)*3+c] = v
+				} else {
+					pv.Data[(c*outH+y)*outW+x] = v
+				}
+			}
+		}
+	}
+	pv.ChannelsLast = p.ChannelsLast
+	if p.ChannelsLast {
+		pv.Shape = []int64{1, int64(outH), int64(outW), 3}
+	} else {
+		pv.Shape = []int64{1, 3, int64(outH), int64(outW)}
+	}
+	return pv, nil
+}
+
+// resizeTarget computes the output (height, width) for an h x w input, as
+// transformers' get_resize_output_image_size / get_size_with_aspect_ratio.
+func (p *ImageProcessor) resizeTarget(h, w int) (int, int) {
+	s := p.Size
+	switch {
+	case s.Height > 0 && s.Width > 0:
+		return s.Height, s.Width
+	case s.ShortestEdge > 0:
+		short, long := min(h, w), max(h, w)
+		size := s.ShortestEdge
+		if s.LongestEdge > 0 && float64(long)/float64(short)*float64(size) > float64(s.LongestEdge) {
+			size = int(math.Round(float64(s.LongestEdge) * float64(short) / float64(long)))
+		}
+		if w < h {
+			return int(float64(size) * float64(h) / float64(w)), size
+		}
+		return size, int(float64(size) * float64(w) / float64(h))
+	case s.LongestEdge > 0:
+		scale := float64(s.LongestEdge) / float64(max(h, w))
+		return max(1, int(math.Round(float64(h)*scale))), max(1, int(math.Round(float64(w)*scale)))
+	}
+	return h, w
+}
+
+// centerCropRGB crops (or zero-pads) src to h x w around its center.
+func centerCropRGB(src *rgbImage, h, w int) *rgbImage {
+	if h <= 0 || w <= 0 || (h == src.h && w == src.w) {
+		return src
+	}
+	out := &rgbImage{w: w, h: h, pix: make([]float32, w*h*3)}
+	top := (src.h - h) / 2
+	left := (src.w - w) / 2
+	for y := 0; y < h; y++ {
+		sy := y + top
+		if sy < 0 || sy >= src.h {
+			continue
+		}
+		for x := 0; x < w; x++ {
+			sx := x + left
+			if sx < 0 || sx >= src.w {
+				continue
+			}
+			copy(out.pix[(y*w+x)*3:(y*w+x)*3+3], src.pix[(sy*src.w+sx)*3:(sy*src.w+sx)*3+3])
+		}
+	}
+	return out
+}
+
+// resampleFilter returns a PIL filter kernel and its support radius.
+func resampleFilter(r ImageResample) (func(float64) float64, float64) {
+	switch r {
+	case ResampleBox:
+		return func(x float64) float64 {
+			if x > -0.5 && x <= 0.5 {
+				return 1
+			}
+			return 0
+		}, 0.5
+	case ResampleHamming:
+		return func(x float64) float64 {
+			x = math.Abs(x)
+			if x >= 1 {
+				return 0
+			}
+			if x == 0 {
+				return 1
+			}
+			return math.Sin(math.Pi*x) / (math.Pi * x) * (0.54 + 0.46*math.Cos(math.Pi*x))
+		}, 1
+	case ResampleBicubic:
+		const a = -0.5
+		return func(x float64) float64 {
+			x = math.Abs(x)
+			switch {
+			case x < 1:
+				return ((a+2)*x-(a+3))*x*x + 1
+			case x < 2:
+				return (((x-5)*x+8)*x - 4) * a
+			}
+			return 0
+		}, 2
+	case ResampleLanczos:
+		return func(x float64) float64 {
+			if x > -3 && x < 3 {
+				return sinc(x) * sinc(x/3)
+			}
+			return 0
+		}, 3
+	default: // ResampleBilinear
+		return func(x float64) float64 {
+			x = math.Abs(x)
+			if x < 1 {
+				return 1 - x
+			}
+			return 0
+		}, 1
+	}
+}
+
+// resizeRGB resizes src to w x h with PIL's separable convolution resampling
+// (antialiased when downscaling), rounding to 8-bit after each pass like PIL.
+func resizeRGB(src *rgbImage, w, h int, r ImageResample) *rgbImage {
+	if w == src.w && h == src.h {
+		return src
+	}
+	if r == ResampleNearest {
+		out := &rgbImage{w: w, h: h, pix: make([]float32, w*h*3)}
+		for y := 0; y < h; y++ {
+			sy := min(int((float64(y)+0.5)*float64(src.h)/float64(h)), src.h-1)
+			for x := 0; x < w; x++ {
+				sx := min(int((float64(x)+0.5)*float64(src.w)/float64(w)), src.w-1)
+				copy(out.pix[(y*w+x)*3:(y*w+x)*3+3], src.pix[(sy*src.w+sx)*3:(sy*src.w+sx)*3+3])
+			}
+		}
+		return out
+	}
+	filter, support := resampleFilter(r)
+	tmp := resampleAxis(src, w, true, filter, support)
+	return resampleAxis(tmp, h, false, filter, support)
+}
+
+// resampleAxis resizes one axis of src to n samples.
+func resampleAxis(src *rgbImage, n int, horizontal bool, filter func(float64) float64, support float64) *rgbImage {
+	inSize := src.h
+	if horizontal {
+		inSize = src.w
+	}
+	if n == inSize {
+		return src
+	}
+	scale := float64(inSize) / float64(n)
+	filterScale := math.Max(scale, 1)
+	radius := support * filterScale
+
+	type taps struct {
+		start   int
+		weights []float64
+	}
+	coeffs := make([]taps, n)
+	for i := range coeffs {
+		center := (float64(i) + 0.5) * scale
+		lo := max(int(center-radius+0.5), 0)
+		hi := min(int(center+radius+0.5), inSize)
+		ws := make([]float64, hi-lo)
+		var sum float64
+		for j := range ws {
+			ws[j] = filter((float64(lo+j) - center + 0.5) / filterScale)
+			sum += ws[j]
+		}
+		if sum != 0 {
+			for j := range ws {
+				ws[j] /= sum
+			}
+		}
+		coeffs[i] = taps{start: lo, weights: ws}
+	}
+
+	out := &rgbImage{w: src.w, h: src.h}
+	if horizontal {
+		out.w = n
+	} else {
+		out.h = n
+	}
+	out.pix = make([]float32, out.w*out.h*3)
+	for y := 0; y < out.h; y++ {
+		for x := 0; x < out.w; x++ {
+			i := x
+			if !horizontal {
+				i = y
+			}
+			t := coeffs[i]
+			var acc [3]float64
+			for j, wgt := range t.weights {
+				sx, sy := t.start+j, y
+				if !horizontal {
+					sx, sy = x, t.start+j
+				}
+				k := (sy*src.w + sx) * 3
+				acc[0] += wgt * float64(src.pix[k])
+				acc[1] += wgt * float64(src.pix[k+1])
+				acc[2] += wgt * float64(src.pix[k+2])
+			}
+			k := (y*out.w + x) * 3
+			for c := 0; c < 3; c++ {
+				out.pix[k+c] = float32(math.Min(255, math.Max(0, math.Round(acc[c]))))
+			}
+		}
+	}
+	return out
+}
+
+// LoadImage reads a PNG, JPEG or GIF file. URLs are not fetched; download
+// the image yourself and pass the bytes or the image.Image.
+func LoadImage(path string) (image.Image, error) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return nil, fmt.Errorf("LoadImage %s: URLs are not fetched", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeImage(data)
+}
+
+// DecodeImage decodes PNG, JPEG or GIF bytes.
+func DecodeImage(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	return img, nil
+}
+
+// imageFromInput accepts the image input forms pipelines take: a file path,
+// encoded bytes, or an image.Image.
+func imageFromInput(input any) (image.Image, error) {
+	switch t := input.(type) {
+	case string:
+		return LoadImage(t)
+	case []byte:
+		return DecodeImage(t)
+	case image.Image:
+		return t, nil
+	}
+	return nil, fmt.Errorf("unsupported image input %T", input)
+}
+
+// imageInputs splits batched image inputs ([]string, [][]byte,
+// []image.Image, []any) from single ones.
+func imageInputs(inputs any) []any {
+	switch t := inputs.(type) {
+	case []string:
+		out := make([]any, len(t))
+		for i, v := range t {
+			out[i] = v
+		}
+		return out
+	case [][]byte:
+		out := make([]any, len(t))
+		for i, v := range t {
+			out[i] = v
+		}
+		return out
+	case []image.Image:
+		out := make([]any, len(t))
+		for i, v := range t {
+			out[i] = v
+		}
+		return out
+	case []any:
+		return t
+	}
+	return []any{inputs}
+}
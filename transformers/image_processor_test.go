@@ -0,0 +1,154 @@
+package transformers
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestNewImageProcessorDefaults(t *testing.T) {
+	p := NewImageProcessor(map[string]any{
+		"size":       map[string]any{"shortest_edge": 224.0},
+		"image_mean": 0.5,
+		"crop_size":  []any{256.0, 192.0},
+	})
+	if p.Size != (ImageSize{ShortestEdge: 224}) || p.CropSize != (ImageSize{Height: 256, Width: 192}) {
+		t.Fatalf("sizes = %+v, %+v", p.Size, p.CropSize)
+	}
+	if !slices.Equal(p.ImageMean, []float32{0.5, 0.5, 0.5}) || p.RescaleFactor != 1.0/255 {
+		t.Fatalf("mean = %v, rescale = %v", p.ImageMean, p.RescaleFactor)
+	}
+	// An int size is the shortest edge only for processors that crop.
+	if s := parseImageSize(384.0, false); s != (ImageSize{Height: 384, Width: 384}) {
+		t.Fatalf("parseImageSize = %+v", s)
+	}
+}
+
+func TestImageProcessorValidate(t *testing.T) {
+	tests := []struct {
+		raw     map[string]any
+		wantErr bool
+	}{
+		{map[string]any{}, false},
+		{map[string]any{"image_mean": []any{}, "image_std": []any{0.5}}, true},
+		{map[string]any{"image_std": []any{}}, true},
+		{map[string]any{"image_std": []any{0.5, 0.0, 0.5}}, true},
+		{map[string]any{"image_std": []any{}, "do_normalize": false}, false},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for _, tt := range tests {
+		p := NewImageProcessor(tt.raw)
+		if err := p.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("validate(%v) = %v", tt.raw, err)
+		}
+		// Preprocess checks too, instead of dividing by zero.
+		if _, err := p.Preprocess(img); (err != nil) != tt.wantErr {
+			t.Errorf("Preprocess(%v) = %v", tt.raw, err)
+		}
+	}
+}
+
+func TestResizeTarget(t *testing.T) {
+	tests := []struct {
+		size         ImageSize
+		h, w         int
+		wantH, wantW int
+	}{
+		{ImageSize{Height: 224, Width: 224}, 480, 640, 224, 224},
+		{ImageSize{ShortestEdge: 224}, 480, 640, 224, 298},
+		{ImageSize{ShortestEdge: 224}, 640, 480, 298, 224},
+		{ImageSize{ShortestEdge: 800, LongestEdge: 1333}, 480, 1280, 500, 1333},
+		{ImageSize{LongestEdge: 512}, 480, 640, 384, 512},
+		{ImageSize{}, 10, 20, 10, 20},
+	}
+	for _, tt := range tests {
+		p := &ImageProcessor{Size: tt.size}
+		if h, w := p.resizeTarget(tt.h, tt.w); h != tt.wantH || w != tt.wantW {
+			t.Errorf("resizeTarget(%+v, %dx%d) = %dx%d, want %dx%d", tt.size, tt.h, tt.w, h, w, tt.wantH, tt.wantW)
+		}
+	}
+}
+
+// grayRow is a 1-pixel-high image whose channels all equal vals.
+func grayRow(vals ...float32) *rgbImage {
+	img := &rgbImage{w: len(vals), h: 1, pix: make([]float32, 3*len(vals))}
+	for i, v := range vals {
+		img.pix[3*i], img.pix[3*i+1], img.pix[3*i+2] = v, v, v
+	}
+	return img
+}
+
+func TestResizeRGB(t *testing.T) {
+	// Golden values from PIL's antialiased bilinear filter: downscaling by 2
+	// widens the tent to support 2, so output 0 weighs inputs 0..2 by
+	// 0.75, 0.75, 0.25.
+	got := resizeRGB(grayRow(0, 100, 200, 255), 2, 1, ResampleBilinear)
+	if got.pix[0] != 71 || got.pix[3] != 209 {
+		t.Fatalf("bilinear = %v", got.pix)
+	}
+	// A flat image stays flat under every filter.
+	for _, r := range []ImageResample{ResampleNearest, ResampleBilinear, ResampleBicubic, ResampleLanczos, ResampleBox, ResampleHamming} {
+		out := resizeRGB(grayRow(90, 90, 90, 90, 90), 3, 2, r)
+		for _, v := range out.pix {
+			if v != 90 {
+				t.Fatalf("filter %d: flat image became %v", r, out.pix)
+			}
+		}
+	}
+}
+
+func TestCenterCropRGB(t *testing.T) {
+	got := centerCropRGB(grayRow(1, 2, 3, 4, 5), 1, 3)
+	if got.w != 3 || got.pix[0] != 2 || got.pix[6] != 4 {
+		t.Fatalf("crop = %v", got.pix)
+	}
+	// Cropping larger than the image zero-pads around it.
+	got = centerCropRGB(grayRow(7), 3, 3)
+	if got.pix[3*4] != 7 || got.pix[0] != 0 {
+		t.Fatalf("pad = %v", got.pix)
+	}
+}
+
+func TestPreprocess(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.Set(x, y, color.RGBA{255, 0, 51, 255})
+		}
+	}
+	p := NewImageProcessor(map[string]any{
+		"do_resize":    false,
+		"do_pad":       true,
+		"size_divisor": 4.0,
+		"image_mean":   []any{0.5, 0.5, 0.5},
+		"image_std":    []any{0.5, 0.5, 0.5},
+	})
+	pv, err := p.Preprocess(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pv.Shape, []int64{1, 3, 4, 4}) || pv.OriginalSize != [2]int{2, 3} {
+		t.Fatalf("shape = %v, original = %v", pv.Shape, pv.OriginalSize)
+	}
+	// (v/255 - 0.5) / 0.5 per channel; padding stays 0 and is masked out.
+	want := [3]float32{1, -1, -0.6}
+	for c, w := range want {
+		if v := pv.Data[c*16]; math.Abs(float64(v-w)) > 1e-6 {
+			t.Errorf("channel %d = %v, want %v", c, v, w)
+		}
+		if v := pv.Data[c*16+3]; v != 0 {
+			t.Errorf("channel %d padding = %v", c, v)
+		}
+	}
+	if pv.PixelMask[2] != 1 || pv.PixelMask[3] != 0 || pv.PixelMask[8] != 0 {
+		t.Fatalf("mask = %v", pv.PixelMask)
+	}
+}
+
+func TestLoadImageRejectsURLs(t *testing.T) {
+	if _, err := LoadImage("https://example.com/cat.jpg"); err == nil {
+		t.Fatal("LoadImage fetched a URL")
+	}
+}
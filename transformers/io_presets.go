@@ -23,7 +23,7 @@ const (
 // resolveIONames sets m.inputNames and m.outputNames based on m.ioPreset.
 // For known presets, it uses a static mapping.
 // For IOPresetAuto (or unknown), it falls back to ONNX model introspection.
-func (m *ModelForCausalLM) resolveIONames(onnxPath string) error {
+func (m *ModelForCausalLM) resolveIONames(onnxPath string, opts *onnx.SessionOptions) error {
 	switch m.ioPreset {
 	case IOPresetSimpleCausal:
 		in, out, err := simpleCausalIONames()
@@ -46,7 +46,7 @@ func (m *ModelForCausalLM) resolveIONames(onnxPath string) error {
 	case IOPresetAuto:
 		fallthrough
 	default:
-		in, out, err := discoverIONamesFromModel(onnxPath)
+		in, out, err := discoverIONamesFromModel(onnxPath, opts)
 		if err != nil {
 			return err
 		}
@@ -106,12 +106,14 @@ func lfm2IONames(cfg *Config) ([]string, []string, error) {
 
 // discoverIONamesFromModel introspects the ONNX model to get input/output names.
 // This is the fallback when no preset is known or ioPreset == IOPresetAuto.
-func discoverIONamesFromModel(onnxPath string) ([]string, []string, error) {
+// opts may be nil; pass the session's own options so the signature is read
+// under the same optimization settings the session will use.
+func discoverIONamesFromModel(onnxPath string, opts *onnx.SessionOptions) ([]string, []string, error) {
 	if onnxPath == "" {
 		return nil, nil, fmt.Errorf("discoverIONamesFromModel: onnxPath is empty")
 	}
 
-	inputInfos, outputInfos, err := onnx.GetInputOutputInfo(onnxPath)
+	inputInfos, outputInfos, err := onnx.GetInputOutputInfoWithOptions(onnxPath, opts)
 	if err != nil {
 		return nil, nil, fmt.Errorf("discoverIONamesFromModel: %w", err)
 	}
@@ -0,0 +1,226 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// kvCache holds the present.* outputs of the previous decoder step, keyed by
+// output name. They are fed back as past_* inputs on the next step.
+type kvCache map[string]onnx.Value
+
+func (c kvCache) destroy() {
+	for _, v := range c {
+		if v != nil {
+			v.Destroy()
+		}
+	}
+}
+
+// update merges the present.* outputs of a decode step into c. Cross-attention
+// entries (*.encoder.*) depend only on the encoder output, so the prefill
+// values are kept; decode-branch graphs either omit them or return
+// placeholders.
+func (c kvCache) update(next kvCache) kvCache {
+	if c == nil {
+		return next
+	}
+	for name, v := range next {
+		old, ok := c[name]
+		if ok && strings.Contains(name, ".encoder.") {
+			v.Destroy()
+			continue
+		}
+		if ok && old != nil {
+			old.Destroy()
+		}
+		c[name] = v
+	}
+	return c
+}
+
+// decoderStep describes the inputs of one decoder call.
+type decoderStep struct {
+	ids     []int64 // tokens fed on this step
+	mask    []int64 // attention mask over past + ids
+	pastLen int     // tokens already held in past
+	past    kvCache // nil on the prefill step
+
+	// extra supplies additional named inputs (e.g. encoder_hidden_states).
+	// The caller keeps ownership of these values.
+	extra map[string]onnx.Value
+
+	// zero creates a placeholder for any other input, including the empty
+	// cache on prefill.
+	zero func(name string, seqLen int) (onnx.Value, error)
+}
+
+// runDecoderStep runs g once and returns the logits of the last position
+// plus the present.* outputs to carry into the next step.
+func (g *onnxGraph) runDecoderStep(s decoderStep) ([]float32, kvCache, error) {
+	inputs := make([]onnx.Value, len(g.inputNames))
+	var owned []onnx.Value
+	defer func() {
+		for _, v := range owned {
+			v.Destroy()
+		}
+	}()
+
+	seqLen := len(s.ids)
+	for i, name := range g.inputNames {
+		if v, ok := s.extra[name]; ok {
+			inputs[i] = v
+			continue
+		}
+
+		var (
+			v   onnx.Value
+			err error
+		)
+		switch {
+		case name == "input_ids" || name == "decoder_input_ids":
+			v, err = tensorFromInt64s(s.ids, []int64{1, int64(seqLen)})
+		case name == "attention_mask" || name == "decoder_attention_mask":
+			v, err = tensorFromInt64s(s.mask, []int64{1, int64(len(s.mask))})
+		case name == "position_ids":
+			pos := make([]int64, seqLen)
+			for j := range pos {
+				pos[j] = int64(s.pastLen + j)
+			}
+			v, err = tensorFromInt64s(pos, []int64{1, int64(seqLen)})
+		case name == "use_cache_branch":
+			v, err = onnx.NewTensor(onnx.NewShape(1), []bool{s.past != nil})
+		case s.past != nil && isCacheInput(name):
+			present, ok := g.presentFor(name, s.past)
+			if !ok {
+				return nil, nil, fmt.Errorf("decoder step: no cached value for %q", name)
+			}
+			inputs[i] = present
+			continue
+		default:
+			if s.zero == nil {
+				return nil, nil, fmt.Errorf("decoder step: unsupported input name %q", name)
+			}
+			v, err = s.zero(name, seqLen)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("create %s tensor: %w", name, err)
+		}
+		inputs[i] = v
+		owned = append(owned, v)
+	}
+
+	outputs := make([]onnx.Value, len(g.outputNames))
+	if err := g.run(inputs, outputs); err != nil {
+		return nil, nil, fmt.Errorf("onnx Run: %w", err)
+	}
+
+	var lastLogits []float32
+	present := kvCache{}
+	var firstErr error
+	for i, name := range g.outputNames {
+		v := outputs[i]
+		if v == nil {
+			continue
+		}
+		switch {
+		case name == "logits":
+			data, shape, err := float32sFromValue(v)
+			if err == nil && len(shape) != 3 {
+				err = fmt.Errorf("unexpected logits shape: %v", shape)
+			}
+			if err == nil {
+				// Copy out the last row; the tensor is released below.
+				vocab := int(shape[2])
+				start := (int(shape[1]) - 1) * vocab
+				lastLogits = append([]float32(nil), data[start:start+vocab]...)
+			} else if firstErr == nil {
+				firstErr = err
+			}
+			v.Destroy()
+		case isPresentOutput(name):
+			present[name] = v
+		default:
+			// Clean up any auto-allocated outputs we don't consume.
+			v.Destroy()
+		}
+	}
+	if firstErr == nil && lastLogits == nil {
+		firstErr = errors.New("onnx output 'logits' missing")
+	}
+	if firstErr != nil {
+		present.destroy()
+		return nil, nil, firstErr
+	}
+	return lastLogits, present, nil
+}
+
+// presentFor looks up the cached output that feeds pastName.
+func (g *onnxGraph) presentFor(pastName string, past kvCache) (onnx.Value, bool) {
+	for _, cand := range presentNameCandidates(pastName) {
+		if v, ok := past[cand]; ok && cand != pastName {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func isPresentOutput(name string) bool {
+	return strings.HasPrefix(name, "present")
+}
+
+// generateWithCache runs the prompt through the prefill graph once and then
+// feeds one token per step, carrying present.* outputs back in as past_*
+// inputs. Merged decoders switch subgraphs through use_cache_branch; split
+// exports switch to the decoder_with_past session after prefill.
+func (m *ModelForCausalLM) generateWithCache(
+	tokenizer *Tokenizer,
+	promptIDs []int64,
+	promptMask []int64,
+	opts GenerationOptions,
+) ([][]int64, error) {
+	st := &generationState{eosID: m.config.EOS_TOKEN_ID()}
+	ids := append([]int64(nil), promptIDs...)
+	mask := append([]int64(nil), promptMask...)
+
+	var past kvCache
+	defer func() { past.destroy() }()
+
+	for step := 0; step < opts.MaxNewTokens; step++ {
+		graph := &m.onnxGraph
+		stepIDs := ids
+		if past != nil {
+			stepIDs = ids[len(ids)-1:]
+			if m.withPast != nil {
+				graph = m.withPast
+			}
+		}
+
+		logits, present, err := graph.runDecoderStep(decoderStep{
+			ids:     stepIDs,
+			mask:    mask,
+			pastLen: len(ids) - len(stepIDs),
+			past:    past,
+			zero:    m.zeroTensorForInput,
+		})
+		if err != nil {
+			return nil, err
+		}
+		past.destroy()
+		past = present
+
+		// For now: greedy, as in generateSimpleCausal.
+		nextID := int64(argmaxF32(logits))
+		ids = append(ids, nextID)
+		mask = append(mask, 1)
+
+		if st.advance(tokenizer, nextID, step, opts) {
+			break
+		}
+	}
+
+	return [][]int64{st.generated}, nil
+}
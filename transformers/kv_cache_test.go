@@ -0,0 +1,184 @@
+package transformers
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+func TestPresentNameCandidates(t *testing.T) {
+	tests := []struct {
+		past string
+		want string // the candidate an exporter actually emits
+	}{
+		{"past_key_values.0.key", "present.0.key"},                     // Optimum / transformers.js
+		{"past_key_values.3.decoder.value", "present.3.decoder.value"}, // seq2seq self-attention
+		{"past_key_values.3.encoder.key", "present.3.encoder.key"},     // seq2seq cross-attention
+		{"past_conv.0", "present_conv.0"},                              // LFM2
+		{"past_ssm_state.1", "present_ssm_state.1"},                    // state-space layers
+		{"cache_params.0.conv", "present.cache_params.0.conv"},         // prefixed
+	}
+	for _, tt := range tests {
+		got := presentNameCandidates(tt.past)
+		if !slices.Contains(got, tt.want) {
+			t.Errorf("presentNameCandidates(%q) = %v, missing %q", tt.past, got, tt.want)
+		}
+	}
+
+	g := &onnxGraph{outputNames: []string{"logits", "present.0.key", "present_conv.0"}}
+	for past, want := range map[string]string{
+		"past_key_values.0.key": "present.0.key",
+		"past_conv.0":           "present_conv.0",
+		"past_key_values.1.key": "",
+	} {
+		got, ok := g.presentNameFor(past)
+		if got != want || ok != (want != "") {
+			t.Errorf("presentNameFor(%q) = %q, %v; want %q", past, got, ok, want)
+		}
+	}
+}
+
+// fakeValue stands in for an ORT tensor and records its destruction.
+type fakeValue struct {
+	onnx.Value
+	name      string
+	destroyed *[]string
+}
+
+func (v fakeValue) Destroy() error {
+	*v.destroyed = append(*v.destroyed, v.name)
+	return nil
+}
+
+func TestKVCacheUpdate(t *testing.T) {
+	var destroyed []string
+	val := func(name string) onnx.Value { return fakeValue{name: name, destroyed: &destroyed} }
+
+	// A nil cache takes the step's outputs as they are.
+	step := kvCache{"present.0.decoder.key": val("dec0"), "present.0.encoder.key": val("enc0")}
+	var c kvCache
+	c = c.update(step)
+	if len(c) != 2 || len(destroyed) != 0 {
+		t.Fatalf("update(nil) = %v, destroyed %v", c, destroyed)
+	}
+
+	// Self-attention entries are replaced; cross-attention keeps the prefill.
+	c = c.update(kvCache{"present.0.decoder.key": val("dec1"), "present.0.encoder.key": val("enc1")})
+	if got := c["present.0.decoder.key"].(fakeValue).name; got != "dec1" {
+		t.Errorf("decoder entry = %s, want dec1", got)
+	}
+	if got := c["present.0.encoder.key"].(fakeValue).name; got != "enc0" {
+		t.Errorf("encoder entry = %s, want the prefill value enc0", got)
+	}
+	slices.Sort(destroyed)
+	if !slices.Equal(destroyed, []string{"dec0", "enc1"}) {
+		t.Errorf("destroyed = %v, want [dec0 enc1]", destroyed)
+	}
+
+	// A decode graph that omits cross-attention outputs keeps the old ones,
+	// and new names are added.
+	destroyed = nil
+	c = c.update(kvCache{"present.1.decoder.key": val("dec1b")})
+	if len(c) != 3 || len(destroyed) != 0 {
+		t.Errorf("update(partial) = %v, destroyed %v", c, destroyed)
+	}
+}
+
+func TestResolveDecoderFiles(t *testing.T) {
+	t.Setenv("HF_HUB_OFFLINE", "1")
+	layout := func(t *testing.T, files ...string) string {
+		dir := t.TempDir()
+		for _, f := range files {
+			p := filepath.Join(dir, f)
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return dir
+	}
+	const (
+		single   = "model"
+		merged   = "decoder_model_merged"
+		split    = "decoder_model"
+		withPast = "decoder_with_past_model"
+	)
+
+	tests := []struct {
+		name         string
+		files        []string
+		dtype        string
+		layout       decoderLayout
+		decoder      string
+		withPastPath string
+		errPart      string
+	}{
+		{
+			name:    "single wins",
+			files:   []string{"onnx/model.onnx", "onnx/decoder_model_merged.onnx"},
+			layout:  decoderLayoutSingle,
+			decoder: "onnx/model.onnx",
+		},
+		{
+			name:    "merged",
+			files:   []string{"onnx/decoder_model_merged_q4.onnx", "onnx/decoder_model_q4.onnx", "onnx/decoder_with_past_model_q4.onnx"},
+			dtype:   "q4",
+			layout:  decoderLayoutMerged,
+			decoder: "onnx/decoder_model_merged_q4.onnx",
+		},
+		{
+			name:         "split",
+			files:        []string{"onnx/decoder_model_fp16.onnx", "onnx/decoder_with_past_model_fp16.onnx"},
+			dtype:        "fp16",
+			layout:       decoderLayoutSplit,
+			decoder:      "onnx/decoder_model_fp16.onnx",
+			withPastPath: "onnx/decoder_with_past_model_fp16.onnx",
+		},
+		{
+			name:    "split without with-past",
+			files:   []string{"onnx/decoder_model.onnx"},
+			errPart: "onnx/decoder_model.onnx + onnx/decoder_with_past_model.onnx",
+		},
+		{
+			name:    "other dtype only",
+			files:   []string{"onnx/model_q4.onnx"},
+			dtype:   "q8",
+			errPart: "onnx/model_quantized.onnx",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := layout(t, tt.files...)
+			got, err := resolveDecoderFiles(dir, tt.dtype, single, merged, split, withPast)
+			if tt.errPart != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.errPart)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := decoderFiles{layout: tt.layout, decoderPath: filepath.Join(dir, tt.decoder)}
+			if tt.withPastPath != "" {
+				want.withPastPath = filepath.Join(dir, tt.withPastPath)
+			}
+			if got != want {
+				t.Errorf("resolveDecoderFiles = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	// Seq2seq decoders have no single-graph fallback.
+	dir := layout(t, "onnx/model.onnx", "onnx/decoder_model_merged.onnx")
+	got, err := resolveDecoderFiles(dir, "", "", merged, split, withPast)
+	if err != nil || got.layout != decoderLayoutMerged {
+		t.Errorf("without a single base: %+v, %v; want merged", got, err)
+	}
+}
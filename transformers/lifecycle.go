@@ -0,0 +1,235 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// ErrClosed is returned when a closed model or generator is used.
+var ErrClosed = errors.New("transformers: use of closed model")
+
+// refCounted runs init when its first reference is taken and destroy when
+// the last one is released.
+type refCounted struct {
+	mu      sync.Mutex
+	refs    int
+	init    func() error
+	destroy func() error
+}
+
+// acquire takes a reference, running init if it is the first. release is
+// idempotent.
+func (r *refCounted) acquire() (release func(), err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refs == 0 {
+		if err := r.init(); err != nil {
+			return nil, err
+		}
+	}
+	r.refs++
+	var once sync.Once
+	return func() { once.Do(r.release) }, nil
+}
+
+func (r *refCounted) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.refs--; r.refs == 0 {
+		if err := r.destroy(); err != nil {
+			log.Printf("DestroyEnvironment: %v", err)
+		}
+	}
+}
+
+// ortEnvironment is the process-wide ORT environment.
+var ortEnvironment = &refCounted{
+	init: func() error {
+		if onnx.IsInitialized() {
+			return nil
+		}
+		if err := onnx.InitializeEnvironment(onnx.WithLogLevelWarning()); err != nil {
+			return fmt.Errorf("InitializeEnvironment: %w", err)
+		}
+		return nil
+	},
+	destroy: func() error {
+		if !onnx.IsInitialized() {
+			return nil
+		}
+		return onnx.DestroyEnvironment()
+	},
+}
+
+// acquireEnvironment takes a reference on the process-wide ORT environment,
+// initializing it on first use. Every live session holds one, and loaders
+// hold one while they inspect model files, so the environment is torn down
+// once the last model is closed. release is idempotent.
+func acquireEnvironment() (release func(), err error) {
+	return ortEnvironment.acquire()
+}
+
+// sessionHandle owns one ORT session and its environment reference. It is
+// separate from onnxGraph, which models embed by value, so a finalizer can
+// report sessions that were dropped without Close.
+type sessionHandle struct {
+	session    *onnx.DynamicAdvancedSession
+	path       string
+	releaseEnv func()
+	closed     atomic.Bool
+}
+
+func newSessionHandle(path string, session *onnx.DynamicAdvancedSession) (*sessionHandle, error) {
+	release, err := acquireEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	h := &sessionHandle{session: session, path: path, releaseEnv: release}
+	runtime.SetFinalizer(h, func(h *sessionHandle) {
+		if !h.closed.Load() {
+			log.Printf("transformers: ONNX session %s was garbage collected without Close; its native memory is leaked",
+				filepath.Base(h.path))
+		}
+	})
+	return h, nil
+}
+
+func (h *sessionHandle) Close() error {
+	if h == nil || !h.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	runtime.SetFinalizer(h, nil)
+	err := h.session.Destroy()
+	h.releaseEnv()
+	return err
+}
+
+// resourceTracker collects the sessions opened while a pipeline is built,
+// so the GeneratorHandle can free them. It reaches the loaders through
+// ModelLoadOptions.
+type resourceTracker struct {
+	mu      sync.Mutex
+	closers []io.Closer
+}
+
+func (t *resourceTracker) add(c io.Closer) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.closers = append(t.closers, c)
+	t.mu.Unlock()
+}
+
+// closeAll closes everything tracked, newest first, and returns the first
+// error.
+func (t *resourceTracker) closeAll() error {
+	t.mu.Lock()
+	closers := t.closers
+	t.closers = nil
+	t.mu.Unlock()
+	var first error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// trackerOptionKey carries the pipeline's resourceTracker in the options
+// map handed to task factories. It is added after validation and cannot be
+// set by callers.
+const trackerOptionKey = "\x00resources"
+
+// GeneratorHandle is a pipeline that owns its models: Close frees their
+// ONNX sessions, which a bare Generator closure cannot do. Use it wherever
+// pipelines are created and dropped during the life of the process, e.g.
+// when a server hot-swaps models:
+//
+//	h, err := NewGenerator("text-generation", modelID, nil)
+//	if err != nil { ... }
+//	defer h.Close()
+//	out, err := h.Generate(messages, map[string]any{"max_new_tokens": 32})
+//
+// Calls after Close return ErrClosed. Close waits for running calls to
+// finish before it frees the sessions, so it must not be called from inside
+// one (e.g. from a streamer).
+type GeneratorHandle struct {
+	gen       Generator
+	Task      string
+	ModelID   string
+	resources *resourceTracker
+
+	// mu is held for reading by every call and for writing by Close.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewGenerator is Pipeline returning a GeneratorHandle.
+func NewGenerator(task, modelID string, options map[string]any) (*GeneratorHandle, error) {
+	opts, err := ParsePipelineOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline: %w", err)
+	}
+	return NewGeneratorWithOptions(task, modelID, opts)
+}
+
+// NewGeneratorWithOptions is NewGenerator with typed options.
+func NewGeneratorWithOptions(task, modelID string, opts PipelineOptions) (*GeneratorHandle, error) {
+	m, err := opts.Map()
+	if err != nil {
+		return nil, err
+	}
+	tracker := &resourceTracker{}
+	m[trackerOptionKey] = tracker
+	gen, err := pipelineImpl(task, modelID, m)
+	if err != nil {
+		// Free whatever loaded before the failure.
+		_ = tracker.closeAll()
+		return nil, err
+	}
+	return &GeneratorHandle{
+		gen:       validatingGenerator(gen),
+		Task:      task,
+		ModelID:   modelID,
+		resources: tracker,
+	}, nil
+}
+
+// Generate runs the pipeline; see Generator.
+func (h *GeneratorHandle) Generate(inputs any, options map[string]any) ([]map[string]any, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	return h.gen(inputs, options)
+}
+
+// Generator returns the handle as a plain Generator, for code that takes
+// one. It stays owned by h.
+func (h *GeneratorHandle) Generator() Generator {
+	return h.Generate
+}
+
+// Close frees the pipeline's ONNX sessions and releases its reference on
+// the ORT environment, once running calls have returned. It is safe to
+// call more than once.
+func (h *GeneratorHandle) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	return h.resources.closeAll()
+}
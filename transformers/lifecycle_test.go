@@ -0,0 +1,152 @@
+package transformers
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRefCounted(t *testing.T) {
+	var inits, destroys int
+	r := &refCounted{
+		init:    func() error { inits++; return nil },
+		destroy: func() error { destroys++; return nil },
+	}
+	a, err := r.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := r.acquire()
+	if inits != 1 {
+		t.Fatalf("init ran %d times, want 1", inits)
+	}
+	a()
+	a() // idempotent
+	if destroys != 0 {
+		t.Fatal("destroyed while a reference is held")
+	}
+	b()
+	if destroys != 1 || r.refs != 0 {
+		t.Fatalf("destroys = %d, refs = %d; want 1, 0", destroys, r.refs)
+	}
+
+	// The next acquire starts over.
+	c, _ := r.acquire()
+	c()
+	if inits != 2 || destroys != 2 {
+		t.Fatalf("inits, destroys = %d, %d; want 2, 2", inits, destroys)
+	}
+
+	failing := &refCounted{
+		init:    func() error { return errors.New("no runtime") },
+		destroy: func() error { t.Fatal("destroy after a failed init"); return nil },
+	}
+	if _, err := failing.acquire(); err == nil || failing.refs != 0 {
+		t.Fatalf("failed init: err = %v, refs = %d", err, failing.refs)
+	}
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestResourceTrackerCloseAll(t *testing.T) {
+	var order []int
+	errFirst := errors.New("second closer failed")
+	tr := &resourceTracker{}
+	for i := 0; i < 3; i++ {
+		tr.add(closerFunc(func() error {
+			order = append(order, i)
+			switch i {
+			case 1:
+				return errFirst
+			case 0:
+				return errors.New("later error")
+			}
+			return nil
+		}))
+	}
+	if err := tr.closeAll(); err != errFirst {
+		t.Fatalf("closeAll = %v, want the first error, %v", err, errFirst)
+	}
+	if !reflect.DeepEqual(order, []int{2, 1, 0}) {
+		t.Fatalf("close order = %v, want newest first", order)
+	}
+	if err := tr.closeAll(); err != nil || len(order) != 3 {
+		t.Fatal("second closeAll closed again")
+	}
+
+	var nilTracker *resourceTracker
+	nilTracker.add(closerFunc(func() error { return nil })) // no-op
+}
+
+func newTestHandle(gen Generator, closes *int) *GeneratorHandle {
+	tr := &resourceTracker{}
+	tr.add(closerFunc(func() error { *closes++; return nil }))
+	return &GeneratorHandle{gen: gen, resources: tr}
+}
+
+func TestGeneratorHandleClose(t *testing.T) {
+	var closes int
+	h := newTestHandle(func(any, map[string]any) ([]map[string]any, error) {
+		return []map[string]any{{"ok": true}}, nil
+	}, &closes)
+	if out, err := h.Generate("x", nil); err != nil || len(out) != 1 {
+		t.Fatalf("Generate = %v, %v", out, err)
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Close(); err != nil || closes != 1 {
+		t.Fatalf("double Close: err = %v, closes = %d", err, closes)
+	}
+	if _, err := h.Generate("x", nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Generate after Close = %v, want ErrClosed", err)
+	}
+	if _, err := h.Generator()("x", nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Generator() after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestGeneratorHandleCloseWaitsForCalls(t *testing.T) {
+	var closes int
+	started, finish := make(chan struct{}), make(chan struct{})
+	h := newTestHandle(func(any, map[string]any) ([]map[string]any, error) {
+		close(started)
+		<-finish
+		return nil, nil
+	}, &closes)
+
+	go h.Generate("x", nil)
+	<-started
+	closed := make(chan struct{})
+	go func() {
+		h.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close freed the sessions while a call was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(finish)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return after the call finished")
+	}
+	if closes != 1 {
+		t.Fatalf("closes = %d, want 1", closes)
+	}
+}
+
+func TestTokenizerCloseIsNoOp(t *testing.T) {
+	tok := stubTokenizer(t, "hello")
+	if err := tok.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := tok.Encode("hello", false); err != nil || len(ids) != 1 {
+		t.Fatalf("Encode after Close = %v, %v", ids, err)
+	}
+}
@@ -0,0 +1,117 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotLocal is returned when a file must be read from disk (a local model
+// directory, or the cache with local_files_only) and is not there.
+var ErrNotLocal = errors.New("file not available locally")
+
+// localModelDir reports whether repoID names a model directory on disk. An
+// existing directory always wins over the Hub, as in transformers; IDs that
+// can only be paths ("./model", "/srv/model") must exist.
+func localModelDir(repoID string) (string, bool, error) {
+	fi, err := os.Stat(repoID)
+	if err == nil && fi.IsDir() {
+		return filepath.Clean(repoID), true, nil
+	}
+	if looksLikePath(repoID) {
+		if err == nil {
+			return "", false, fmt.Errorf("model %s: not a directory", repoID)
+		}
+		return "", false, fmt.Errorf("model directory: %w", err)
+	}
+	return "", false, nil
+}
+
+// looksLikePath reports whether repoID cannot be a Hub repo ID.
+func looksLikePath(repoID string) bool {
+	return filepath.IsAbs(repoID) || strings.HasPrefix(repoID, ".") || strings.ContainsRune(repoID, '\\')
+}
+
+func notLocalError(repoID, dir string) error {
+	if dir == filepath.Clean(repoID) {
+		return fmt.Errorf("%w in %s", ErrNotLocal, dir)
+	}
+	return fmt.Errorf("%w: %s is not in the cache (%s) and downloads are disabled", ErrNotLocal, repoID, dir)
+}
+
+// isOffline reports whether HF_HUB_OFFLINE is set, in which case Hub model
+// IDs are served from the cache only.
+func isOffline() bool {
+	switch strings.ToLower(os.Getenv("HF_HUB_OFFLINE")) {
+	case "", "0", "false", "no", "off":
+		return false
+	}
+	return true
+}
+
+// cachedModelDir resolves repoID for a local_files_only load: a local model
+// directory is kept, and a Hub model ID becomes its download cache, which
+// the load then reads like any model directory. Offline mode thus belongs
+// to the one load; others of the same repo may still download.
+func cachedModelDir(repoID string) (string, error) {
+	if dir, ok, err := localModelDir(repoID); err != nil || ok {
+		return dir, err
+	}
+	// Absolute, so the directory cannot be taken for a Hub ID if it
+	// disappears mid-load.
+	dir, err := filepath.Abs(hfCachePath(repoID))
+	if err != nil {
+		return "", err
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return "", notLocalError(repoID, dir)
+	}
+	return dir, nil
+}
+
+// checkModelDir is the FromDirectory precondition: dir must exist and be a
+// directory, so a typo is not mistaken for a Hub repo ID.
+func checkModelDir(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("model directory: %w", err)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("model %s: not a directory", dir)
+	}
+	return nil
+}
+
+// FromDirectory loads config.json (and generation_config.json, if present)
+// from a model directory laid out like a Hub repo.
+func (a autoConfig) FromDirectory(dir string) (*Config, error) {
+	if err := checkModelDir(dir); err != nil {
+		return nil, err
+	}
+	return a.FromPretrained(dir)
+}
+
+// FromDirectory loads tokenizer.json, its auxiliary files and the chat
+// template from a model directory laid out like a Hub repo.
+func (a autoTokenizer) FromDirectory(dir string) (*Tokenizer, error) {
+	if err := checkModelDir(dir); err != nil {
+		return nil, err
+	}
+	return a.FromPretrained(dir)
+}
+
+// FromDirectory loads the decoder from dir/onnx/, picking files by dtype as
+// FromPretrained does.
+func (a autoModelForCausalLM) FromDirectory(
+	dir string,
+	config *Config,
+	dtype string,
+	ioPreset IOPreset,
+) (*ModelForCausalLM, error) {
+	if err := checkModelDir(dir); err != nil {
+		return nil, err
+	}
+	return a.FromPretrained(dir, config, dtype, ioPreset)
+}
@@ -0,0 +1,63 @@
+package transformers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCachedModelDir(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("CACHE_DIR", cache)
+	t.Setenv("HF_HUB_OFFLINE", "")
+	cached := filepath.Join(cache, "huggingface.co", "acme", "cached", "resolve", "main")
+	if err := os.MkdirAll(cached, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	local := t.TempDir()
+
+	tests := []struct {
+		repoID  string
+		want    string
+		wantErr error
+	}{
+		{"acme/cached", cached, nil},
+		{local, local, nil},
+		{"acme/missing", "", ErrNotLocal},
+	}
+	for _, tt := range tests {
+		got, err := cachedModelDir(tt.repoID)
+		if tt.wantErr != nil {
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("cachedModelDir(%q): err = %v, want %v", tt.repoID, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("cachedModelDir(%q) = %q, %v; want %q", tt.repoID, got, err, tt.want)
+		}
+	}
+
+	// The resolved directory never downloads, while other loads of the
+	// same repo still may.
+	dir, _ := cachedModelDir("acme/cached")
+	if _, err := HFHubDownload(dir, "config.json"); !errors.Is(err, ErrNotLocal) {
+		t.Fatalf("missing file in cache: err = %v, want ErrNotLocal", err)
+	}
+	if _, download, err := hfResolveDir("acme/cached"); err != nil || !download {
+		t.Fatalf("hfResolveDir: download = %v, %v; want true", download, err)
+	}
+	t.Setenv("HF_HUB_OFFLINE", "1")
+	if _, download, err := hfResolveDir("acme/cached"); err != nil || download {
+		t.Fatalf("hfResolveDir under HF_HUB_OFFLINE: download = %v, %v; want false", download, err)
+	}
+}
+
+func TestPipelineLocalFilesOnlyNotCached(t *testing.T) {
+	t.Setenv("CACHE_DIR", t.TempDir())
+	_, err := Pipeline("text-classification", "acme/missing", map[string]any{"local_files_only": true})
+	if !errors.Is(err, ErrNotLocal) {
+		t.Fatalf("err = %v, want ErrNotLocal", err)
+	}
+}
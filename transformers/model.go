@@ -13,14 +13,16 @@ import (
 
 // ModelForCausalLM is our ONNX-backed language model wrapper.
 type ModelForCausalLM struct {
-	modelID     string
-	config      *Config
-	session     *onnx.DynamicAdvancedSession
-	ioPreset    IOPreset
-	inputNames  []string
-	outputNames []string
-	dtype       string // "q4", "fp16", etc.
-	inputInfo   map[string]onnx.InputOutputInfo
+	modelID  string
+	config   *Config
+	ioPreset IOPreset
+	dtype    string // "q4", "fp16", etc.
+
+	// onnxGraph is the prefill graph: model.onnx, decoder_model_merged.onnx
+	// or decoder_model.onnx depending on layout.
+	onnxGraph
+	layout   decoderLayout
+	withPast *onnxGraph // decode-step graph for decoderLayoutSplit
 }
 
 // autoModelForCausalLM is the HF-style static dispatcher:
@@ -30,46 +32,53 @@ type autoModelForCausalLM struct{}
 var AutoModelForCausalLM autoModelForCausalLM
 
 // FromPretrained constructs the model from HF Hub.
-func (autoModelForCausalLM) FromPretrained(
+func (a autoModelForCausalLM) FromPretrained(
 	modelID string,
 	config *Config,
 	dtype string,   // "q4", "fp16", "" -> chooses filename
 	ioPreset IOPreset,
+) (*ModelForCausalLM, error) {
+	return a.FromPretrainedWithOptions(modelID, config, dtype, ioPreset, ModelLoadOptions{})
+}
+
+// FromPretrainedWithOptions is FromPretrained with control over ONNX session
+// creation: graph optimization level and thread counts.
+func (autoModelForCausalLM) FromPretrainedWithOptions(
+	modelID string,
+	config *Config,
+	dtype string,
+	ioPreset IOPreset,
+	loadOpts ModelLoadOptions,
 ) (*ModelForCausalLM, error) {
 	if config == nil {
 		return nil, errors.New("AutoModelForCausalLM.FromPretrained: config is nil")
 	}
 
-	// choose ONNX filename from dtype
-	filename := "onnx/model.onnx"
-	switch dtype {
-	case "q4":
-		filename = "onnx/model_q4.onnx"
-	case "fp16":
-		filename = "onnx/model_fp16.onnx"
-	default:
-		filename = "onnx/model.onnx"
-	}
-
-	onnxPath, err := HFHubDownload(modelID, filename)
+	// Choose ONNX files from dtype: model*.onnx, else a merged decoder,
+	// else the decoder_model + decoder_with_past_model pair.
+	files, err := resolveDecoderFiles(modelID, dtype,
+		"model", "decoder_model_merged", "decoder_model", "decoder_with_past_model")
 	if err != nil {
 		return nil, fmt.Errorf("download onnx model: %w", err)
 	}
+	onnxPath := files.decoderPath
 
-	// Download external data files if present (best effort).
-	if strings.HasSuffix(filename, ".onnx") {
-		if files, _ := HFHubEnsureOptionalFiles(modelID, []string{filename + "_data"}); files != nil {
-			_ = files
-		}
+	// Hold the ORT environment while loading; each session takes its own
+	// reference.
+	releaseEnv, err := acquireEnvironment()
+	if err != nil {
+		return nil, err
 	}
+	defer releaseEnv()
 
-	// Environment should be initialized once per process.
-	if err := onnx.InitializeEnvironment(onnx.WithLogLevelWarning()); err != nil {
-		return nil, fmt.Errorf("InitializeEnvironment: %w", err)
+	sessOpts, err := loadOpts.newSessionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("session options: %w", err)
 	}
+	defer sessOpts.Destroy()
 
 	// Introspect input/output info to aid in creating zeroed optional inputs.
-	inInfos, _, err := onnx.GetInputOutputInfo(onnxPath)
+	inInfos, _, err := onnx.GetInputOutputInfoWithOptions(onnxPath, sessOpts)
 	if err != nil {
 		return nil, fmt.Errorf("GetInputOutputInfo: %w", err)
 	}
@@ -79,28 +88,34 @@ func (autoModelForCausalLM) FromPretrained(
 	}
 
 	m := &ModelForCausalLM{
-		modelID:  modelID,
-		config:   config,
-		ioPreset: ioPreset,
-		dtype:    dtype,
-		inputInfo: inputInfo,
+		modelID:   modelID,
+		config:    config,
+		ioPreset:  ioPreset,
+		dtype:     dtype,
+		onnxGraph: onnxGraph{inputInfo: inputInfo},
+		layout:    files.layout,
 	}
 
-	if err := m.resolveIONames(onnxPath); err != nil {
+	if err := m.resolveIONames(onnxPath, sessOpts); err != nil {
 		return nil, err
 	}
 
-	sess, err := onnx.NewDynamicAdvancedSession(
-		onnxPath,
-		m.inputNames,
-		m.outputNames,
-		nil, // session options
-	)
-	if err != nil {
-		return nil, fmt.Errorf("create ONNX session: %w", err)
+	if err := m.openSession(onnxPath, sessOpts, loadOpts); err != nil {
+		return nil, err
 	}
 
-	m.session = sess
+	if files.layout == decoderLayoutSplit {
+		withPast, err := newONNXGraph(files.withPastPath, loadOpts)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("load decoder_with_past: %w", err)
+		}
+		m.withPast = withPast
+		if !withPast.supportsKVCache() {
+			m.Close()
+			return nil, errors.New("decoder_with_past model has no past/present cache inputs")
+		}
+	}
 
 	logModelLoadInfo(modelID)
 
@@ -113,6 +128,14 @@ type GenerationOptions struct {
 	DoSample     bool
 	Streamer     func(ev PipelineStreamEvent) bool // return false to stop early
 	StopSequences []string
+
+	// DecoderInputIDs is the decoder prompt of encoder-decoder models;
+	// empty means decoder_start_token_id (+ forced_bos_token_id).
+	DecoderInputIDs []int64
+
+	// logitsProcessors edit the next-token logits in place before the
+	// greedy pick, given the IDs decoded so far (prompt included).
+	logitsProcessors []func(ids []int64, logits []float32)
 }
 
 // Generate runs a chat-style generation loop with optional streaming.
@@ -136,6 +159,12 @@ func (m *ModelForCausalLM) Generate(
 		opts.MaxNewTokens = 128
 	}
 
+	// Merged and split exports are stepped with a real KV cache.
+	switch m.layout {
+	case decoderLayoutMerged, decoderLayoutSplit:
+		return m.generateWithCache(tokenizer, inputIDs[0], attentionMask[0], opts)
+	}
+
 	switch m.ioPreset {
 	case IOPresetSimpleCausal:
 		return m.generateSimpleCausal(tokenizer, inputIDs[0], attentionMask[0], opts)
@@ -157,10 +186,7 @@ func (m *ModelForCausalLM) generateSimpleCausal(
 	curMask []int64,
 	opts GenerationOptions,
 ) ([][]int64, error) {
-	var generated []int64
-	eosID := m.config.EOS_TOKEN_ID()
-
-	var fullText string
+	st := &generationState{eosID: m.config.EOS_TOKEN_ID()}
 
 	for step := 0; step < opts.MaxNewTokens; step++ {
 		// Prepare input tensors
@@ -182,6 +208,16 @@ func (m *ModelForCausalLM) generateSimpleCausal(
 				inputs[i] = inputTensor
 			case "attention_mask":
 				inputs[i] = maskTensor
+			case "use_cache_branch":
+				// No cache is carried in this loop: always take the prefill branch.
+				t, err := onnx.NewTensor(onnx.NewShape(1), []bool{false})
+				if err != nil {
+					inputTensor.Destroy()
+					maskTensor.Destroy()
+					return nil, fmt.Errorf("create use_cache_branch tensor: %w", err)
+				}
+				inputs[i] = t
+				toDestroy = append(toDestroy, t)
 			case "position_ids":
 				pos := make([]int64, len(curIDs))
 				for j := range pos {
@@ -212,7 +248,7 @@ func (m *ModelForCausalLM) generateSimpleCausal(
 
 		outputs := make([]onnx.Value, len(m.outputNames))
 
-		if err := m.session.Run(inputs, outputs); err != nil {
+		if err := m.run(inputs, outputs); err != nil {
 			inputTensor.Destroy()
 			maskTensor.Destroy()
 			for _, v := range toDestroy {
@@ -267,54 +303,15 @@ func (m *ModelForCausalLM) generateSimpleCausal(
 		nextID := int64(argmaxF32(lastLogits))
 		logitsTensor.Destroy()
 
-		generated = append(generated, nextID)
 		curIDs = append(curIDs, nextID)
 		curMask = append(curMask, 1)
 
-		deltaText := ""
-		if tokenizer != nil {
-			txt, err := tokenizer.Decode([]int64{nextID})
-			if err == nil {
-				deltaText = txt
-				fullText += deltaText
-			}
-		}
-
-		// Stop sequence handling (string-based).
-		stopHit := false
-		for _, stop := range opts.StopSequences {
-			if stop == "" {
-				continue
-			}
-			if idx := strings.Index(fullText, stop); idx >= 0 {
-				fullText = fullText[:idx]
-				deltaText = "" // avoid streaming the stop tail
-				stopHit = true
-				break
-			}
-		}
-
-		done := eosID >= 0 && nextID == eosID
-
-		if opts.Streamer != nil {
-			ev := PipelineStreamEvent{
-				TokenID:   nextID,
-				DeltaText: deltaText,
-				FullText:  fullText,
-				Step:      step,
-				Done:      done || stopHit,
-			}
-			if !opts.Streamer(ev) {
-				break
-			}
-		}
-
-		if done || stopHit {
+		if st.advance(tokenizer, nextID, step, opts) {
 			break
 		}
 	}
 
-	return [][]int64{generated}, nil
+	return [][]int64{st.generated}, nil
 }
 
 func logModelLoadInfo(modelID string) {
@@ -341,44 +338,11 @@ func currentRSSMB() float64 {
 }
 
 func (m *ModelForCausalLM) zeroTensorForInput(name string, seqLen int) (onnx.Value, error) {
-	info, ok := m.inputInfo[name]
-	if !ok {
-		return nil, fmt.Errorf("Generate: unsupported input name %q", name)
-	}
-	isCache := strings.Contains(name, "past") || strings.Contains(name, "cache")
-	shape := make([]int64, len(info.Dimensions))
-	for i, d := range info.Dimensions {
-		if d <= 0 {
-			if i == 0 {
-				shape[i] = 1 // batch dim must be >=1
-			} else if isCache {
-				shape[i] = 0 // allow empty cache length
-			} else {
-				shape[i] = 1
-			}
-			// For non-cache, try to use seqLen if dimension is undefined and position-like
-			if !isCache && i == len(info.Dimensions)-1 && seqLen > 0 {
-				shape[i] = int64(seqLen)
-			}
-		} else {
-			shape[i] = d
-		}
-	}
+	return m.onnxGraph.zeroInput(name, seqLen, m.config)
+}
 
-	switch info.DataType {
-	case onnx.TensorElementDataTypeInt64:
-		count := int64(1)
-		for _, d := range shape {
-			count *= d
-		}
-		data := make([]int64, count)
-		return tensorFromInt64s(data, shape)
-	default:
-		count := int64(1)
-		for _, d := range shape {
-			count *= d
-		}
-		data := make([]float32, count)
-		return tensorFromFloat32s(data, shape)
-	}
+// Close frees the model's ONNX sessions. The model cannot be used
+// afterwards; closing twice is a no-op.
+func (m *ModelForCausalLM) Close() error {
+	return errors.Join(m.onnxGraph.Close(), m.withPast.Close())
 }
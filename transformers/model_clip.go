@@ -0,0 +1,323 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// CLIPModel wraps contrastive image-text exports (CLIP, SigLIP,
+// Chinese-CLIP). Repos ship the two towers as onnx/vision_model.onnx and
+// onnx/text_model.onnx, a combined onnx/model.onnx, or both; every method
+// works with either layout.
+type CLIPModel struct {
+	modelID string
+	config  *Config
+	dtype   string
+
+	vision   *EncoderModel // nil when only the combined graph is loaded
+	text     *EncoderModel
+	combined *EncoderModel // nil when the towers are loaded
+
+	// LogitScale and LogitBias turn cosine similarities into logits when
+	// the towers are loaded separately; the combined graph carries the
+	// trained values itself. LogitScale comes from the config's
+	// logit_scale_init_value (stored as a log) when it has one; otherwise
+	// CLIP defaults to the 100 clamp its checkpoints sit at. SigLIP's
+	// trained values (about 110 and -12.9 for the base models) differ per
+	// checkpoint, so without the config value they default to 0, which
+	// LogitsPerImage rejects: set them, or load the combined graph.
+	LogitScale float32
+	LogitBias  float32
+}
+
+// autoModelForZeroShotImageClassification is the HF-style static dispatcher:
+//
+//	model, err := AutoModelForZeroShotImageClassification.FromPretrained(...)
+type autoModelForZeroShotImageClassification struct{}
+
+var AutoModelForZeroShotImageClassification autoModelForZeroShotImageClassification
+
+// FromPretrained loads the vision and text towers, falling back to the
+// combined onnx/model.onnx when either is missing.
+func (a autoModelForZeroShotImageClassification) FromPretrained(
+	modelID string,
+	config *Config,
+	dtype string,
+) (*CLIPModel, error) {
+	return a.FromPretrainedWithOptions(modelID, config, dtype, ModelLoadOptions{})
+}
+
+// FromPretrainedWithOptions is FromPretrained with control over ONNX session
+// creation.
+func (autoModelForZeroShotImageClassification) FromPretrainedWithOptions(
+	modelID string,
+	config *Config,
+	dtype string,
+	loadOpts ModelLoadOptions,
+) (*CLIPModel, error) {
+	return loadCLIPModel(modelID, config, dtype, loadOpts, false)
+}
+
+// loadCLIPModel loads the towers or the combined graph. preferCombined picks
+// model.onnx first, so scores use the trained logit scale and bias.
+func loadCLIPModel(modelID string, config *Config, dtype string, loadOpts ModelLoadOptions, preferCombined bool) (*CLIPModel, error) {
+	if config == nil {
+		return nil, errors.New("AutoModelForZeroShotImageClassification.FromPretrained: config is nil")
+	}
+	releaseEnv, err := acquireEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseEnv()
+	m := &CLIPModel{
+		modelID: modelID,
+		config:  config,
+		dtype:   dtype,
+	}
+	m.LogitScale = clipLogitScale(config)
+	load := func(base string) (*EncoderModel, error) {
+		filename := "onnx/" + base + onnxDtypeSuffix(dtype) + ".onnx"
+		path, ok, err := fetchOptionalONNX(modelID, filename)
+		if err != nil {
+			return nil, fmt.Errorf("download %s: %w", filename, err)
+		}
+		if !ok {
+			return nil, nil
+		}
+		g, err := newONNXGraph(path, loadOpts)
+		if err != nil {
+			return nil, fmt.Errorf("load %s: %w", base, err)
+		}
+		return &EncoderModel{modelID: modelID, config: config, dtype: dtype, onnxGraph: g}, nil
+	}
+
+	// Close whatever loaded if a later graph fails.
+	fail := func(err error) (*CLIPModel, error) {
+		m.Close()
+		return nil, err
+	}
+	if preferCombined {
+		if m.combined, err = load("model"); err != nil {
+			return fail(err)
+		}
+	}
+	if m.combined == nil {
+		if m.vision, err = load("vision_model"); err != nil {
+			return fail(err)
+		}
+		if m.text, err = load("text_model"); err != nil {
+			return fail(err)
+		}
+		if m.vision == nil || m.text == nil {
+			// Only one tower: drop it for the combined graph.
+			m.vision.Close()
+			m.text.Close()
+			m.vision, m.text = nil, nil
+			if m.combined, err = load("model"); err != nil {
+				return fail(err)
+			}
+		}
+	}
+	if m.combined == nil && m.vision == nil {
+		return nil, fmt.Errorf("%s has neither onnx/vision_model%[2]s.onnx + onnx/text_model%[2]s.onnx nor onnx/model%[2]s.onnx", modelID, onnxDtypeSuffix(dtype))
+	}
+
+	logModelLoadInfo(modelID)
+	return m, nil
+}
+
+// isCLIPFamily reports whether cfg describes a contrastive image-text model.
+func isCLIPFamily(cfg *Config) bool {
+	switch cfg.ModelType() {
+	case "clip", "siglip", "chinese_clip":
+		return true
+	}
+	return false
+}
+
+// ImageEmbeddings returns the L2-normalized embedding of one preprocessed
+// image.
+func (m *CLIPModel) ImageEmbeddings(pv *PixelValues) ([]float32, error) {
+	var (
+		outs map[string]floatOutput
+		err  error
+	)
+	if m.vision != nil {
+		outs, err = m.vision.RunPixels(pv)
+	} else {
+		// The combined graph always wants text too; one pad token will do.
+		pad := []int64{m.textPadID(nil)}
+		outs, err = m.combined.runPixelsAndText(pv, newEncoderBatch([][]int64{pad}, nil, pad[0]))
+	}
+	if err != nil {
+		return nil, err
+	}
+	emb, err := m.embeddingOutput(outs, "image_embeds")
+	if err != nil {
+		return nil, err
+	}
+	return emb[0], nil
+}
+
+// TextEmbeddings returns one L2-normalized embedding per text.
+func (m *CLIPModel) TextEmbeddings(tokenizer *Tokenizer, texts []string) ([][]float32, error) {
+	batch, err := m.textBatch(tokenizer, texts)
+	if err != nil {
+		return nil, err
+	}
+	var outs map[string]floatOutput
+	if m.text != nil {
+		outs, err = m.text.Run(batch)
+	} else {
+		// ... and image too: a blank one at the vision tower's size.
+		size := int64(intOption(m.subConfig("vision_config"), "image_size", 224))
+		blank := &PixelValues{Data: make([]float32, 3*size*size), Shape: []int64{1, 3, size, size}}
+		outs, err = m.combined.runPixelsAndText(blank, batch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m.embeddingOutput(outs, "text_embeds")
+}
+
+// LogitsPerImage scores every text against every image, one row per image.
+// With the towers loaded the logits are LogitScale*cos + LogitBias.
+func (m *CLIPModel) LogitsPerImage(tokenizer *Tokenizer, images []*PixelValues, texts []string) ([][]float32, error) {
+	if err := m.checkLogitScale(); err != nil {
+		return nil, err
+	}
+	out := make([][]float32, len(images))
+	if m.combined != nil {
+		batch, err := m.textBatch(tokenizer, texts)
+		if err != nil {
+			return nil, err
+		}
+		for i, pv := range images {
+			outs, err := m.combined.runPixelsAndText(pv, batch)
+			if err != nil {
+				return nil, err
+			}
+			logits, err := m.combined.output(outs, "logits_per_image")
+			if err != nil {
+				return nil, err
+			}
+			out[i] = logits.data[:len(texts)]
+		}
+		return out, nil
+	}
+
+	textEmbs, err := m.TextEmbeddings(tokenizer, texts)
+	if err != nil {
+		return nil, err
+	}
+	for i, pv := range images {
+		imageEmb, err := m.ImageEmbeddings(pv)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = scaledSimilarities(imageEmb, textEmbs, m.LogitScale, m.LogitBias)
+	}
+	return out, nil
+}
+
+// scaledSimilarities is scale*dot + bias of one normalized image embedding
+// with every text embedding, i.e. one row of logits_per_image.
+func scaledSimilarities(imageEmb []float32, textEmbs [][]float32, scale, bias float32) []float32 {
+	out := make([]float32, len(textEmbs))
+	for j, t := range textEmbs {
+		var dot float32
+		for d := range t {
+			dot += t[d] * imageEmb[d]
+		}
+		out[j] = scale*dot + bias
+	}
+	return out
+}
+
+// clipLogitScale is the tower logit scale for cfg: exp(logit_scale_init_value)
+// when the config sets it, else 100 for CLIP and 0 (unknown) for SigLIP.
+func clipLogitScale(cfg *Config) float32 {
+	if v, ok := cfg.Raw()["logit_scale_init_value"].(float64); ok {
+		return float32(math.Exp(v))
+	}
+	if cfg.ModelType() == "siglip" {
+		return 0
+	}
+	return 100
+}
+
+// checkLogitScale fails when the towers are loaded and the logit scale is
+// unknown, rather than scoring with made-up values.
+func (m *CLIPModel) checkLogitScale() error {
+	if m.combined == nil && m.LogitScale == 0 {
+		return fmt.Errorf("%s: the logit scale and bias of the %s towers are unknown; load onnx/model.onnx or set logit_scale and logit_bias",
+			m.modelID, m.config.ModelType())
+	}
+	return nil
+}
+
+// textBatch tokenizes texts for the text tower. SigLIP was trained on
+// inputs padded to the full context, so its rows are padded to it.
+func (m *CLIPModel) textBatch(tokenizer *Tokenizer, texts []string) (*encoderBatch, error) {
+	if len(texts) == 0 {
+		return nil, errors.New("CLIPModel: no texts")
+	}
+	maxLen := intOption(m.subConfig("text_config"), "max_position_embeddings", 77)
+	padID := m.textPadID(tokenizer)
+	rows := make([][]int64, len(texts))
+	for i, text := range texts {
+		ids, _, err := tokenizer.encodeWithTypes(text, "", maxLen, truncateLongestFirst)
+		if err != nil {
+			return nil, fmt.Errorf("Encode: %w", err)
+		}
+		if m.config.ModelType() == "siglip" {
+			for len(ids) < maxLen {
+				ids = append(ids, padID)
+			}
+		}
+		rows[i] = ids
+	}
+	return newEncoderBatch(rows, nil, padID), nil
+}
+
+// textPadID is the tokenizer's pad token, else the text config's.
+func (m *CLIPModel) textPadID(tokenizer *Tokenizer) int64 {
+	if tokenizer != nil {
+		if id, ok := tokenizer.TokenToID(tokenizer.SpecialToken("pad_token")); ok {
+			return id
+		}
+	}
+	return int64(intOption(m.subConfig("text_config"), "pad_token_id", 0))
+}
+
+// embeddingOutput reads [batch, dim] projections (or SigLIP's pooler_output)
+// and normalizes each row.
+func (m *CLIPModel) embeddingOutput(outs map[string]floatOutput, name string) ([][]float32, error) {
+	o, ok := outs[name]
+	if !ok {
+		o, ok = outs["pooler_output"]
+	}
+	if !ok || len(o.shape) != 2 {
+		return nil, fmt.Errorf("%s: no %s output", m.modelID, name)
+	}
+	dim := int(o.shape[1])
+	res := make([][]float32, o.shape[0])
+	for i := range res {
+		res[i] = append([]float32(nil), o.data[i*dim:(i+1)*dim]...)
+		l2Normalize(res[i])
+	}
+	return res, nil
+}
+
+// subConfig returns a nested config section such as "text_config".
+func (m *CLIPModel) subConfig(name string) map[string]any {
+	sub, _ := m.config.Raw()[name].(map[string]any)
+	return sub
+}
+
+// Close frees the tower sessions. The model cannot be used afterwards;
+// closing twice is a no-op.
+func (m *CLIPModel) Close() error {
+	return errors.Join(m.vision.Close(), m.text.Close(), m.combined.Close())
+}
@@ -0,0 +1,56 @@
+package transformers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCLIPLogitScale(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+		want float32
+	}{
+		// openai/clip-vit-base-patch32 ships ln(1/0.07).
+		{"clip config", &Config{modelType: "clip", raw: map[string]any{"logit_scale_init_value": 2.6592}}, 14.2857},
+		{"clip without value", &Config{modelType: "clip", raw: map[string]any{}}, 100},
+		{"siglip without value", &Config{modelType: "siglip", raw: map[string]any{}}, 0},
+		{"siglip config", &Config{modelType: "siglip", raw: map[string]any{"logit_scale_init_value": math.Log(10)}}, 10},
+	}
+	for _, tt := range tests {
+		if got := clipLogitScale(tt.cfg); math.Abs(float64(got-tt.want)) > 1e-3 {
+			t.Errorf("%s: clipLogitScale = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScaledSimilarities(t *testing.T) {
+	// Normalized embeddings and the logits_per_image row they give with
+	// CLIP's clamped scale (100) and SigLIP-style scale and bias.
+	image := []float32{0.6, 0.8, 0}
+	texts := [][]float32{
+		{0.6, 0.8, 0},
+		{0, 0, 1},
+		{0.8, -0.6, 0},
+		{0, 0.6, 0.8},
+	}
+	tests := []struct {
+		scale, bias float32
+		want        []float32
+	}{
+		{100, 0, []float32{100, 0, 0, 48}},
+		{110, -12.9, []float32{97.1, -12.9, -12.9, 39.9}},
+	}
+	for _, tt := range tests {
+		got := scaledSimilarities(image, texts, tt.scale, tt.bias)
+		if len(got) != len(tt.want) {
+			t.Fatalf("scale %v: got %v, want %v", tt.scale, got, tt.want)
+		}
+		for j := range got {
+			if math.Abs(float64(got[j]-tt.want[j])) > 1e-3 {
+				t.Errorf("scale %v bias %v: got %v, want %v", tt.scale, tt.bias, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// EncoderModel is our ONNX-backed wrapper for encoder-only exports (BERT,
+// RoBERTa, MiniLM, BGE, ViT, ...). The task head, if any, is part of the graph, so
+// one wrapper serves embeddings, classification and span extraction; the
+// pipelines differ only in how they read the outputs.
+type EncoderModel struct {
+	modelID string
+	config  *Config
+	dtype   string
+
+	*onnxGraph
+}
+
+// autoModel is the HF-style static dispatcher:
+//
+//	model, err := AutoModel.FromPretrained(...)
+type autoModel struct{}
+
+var AutoModel autoModel
+
+// FromPretrained loads onnx/model<dtype suffix>.onnx from HF Hub.
+func (a autoModel) FromPretrained(
+	modelID string,
+	config *Config,
+	dtype string,
+) (*EncoderModel, error) {
+	return a.FromPretrainedWithOptions(modelID, config, dtype, ModelLoadOptions{})
+}
+
+// FromPretrainedWithOptions is FromPretrained with control over ONNX session
+// creation.
+func (autoModel) FromPretrainedWithOptions(
+	modelID string,
+	config *Config,
+	dtype string,
+	loadOpts ModelLoadOptions,
+) (*EncoderModel, error) {
+	if config == nil {
+		return nil, errors.New("AutoModel.FromPretrained: config is nil")
+	}
+	releaseEnv, err := acquireEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseEnv()
+	graph, err := loadEncoderGraph(modelID, dtype, "model", loadOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	logModelLoadInfo(modelID)
+
+	return &EncoderModel{
+		modelID:   modelID,
+		config:    config,
+		dtype:     dtype,
+		onnxGraph: graph,
+	}, nil
+}
+
+// encoderDtypeOption returns the "dtype" pipeline option for encoder tasks.
+// Encoder repos do not always ship q4 weights, so the default is the
+// full-precision onnx/model.onnx.
+func encoderDtypeOption(options map[string]any) string {
+	dtype, _ := options["dtype"].(string)
+	if dtype == "" {
+		dtype = "fp32"
+	}
+	return dtype
+}
+
+// encoderBatch is a right-padded batch of token sequences, row-major
+// [batch, seqLen].
+type encoderBatch struct {
+	ids     []int64
+	mask    []int64
+	typeIDs []int64
+	batch   int
+	seqLen  int
+}
+
+// newEncoderBatch pads rows to the longest one. typeRows may be nil.
+func newEncoderBatch(rows, typeRows [][]int64, padID int64) *encoderBatch {
+	b := &encoderBatch{batch: len(rows)}
+	for _, r := range rows {
+		b.seqLen = max(b.seqLen, len(r))
+	}
+	n := b.batch * b.seqLen
+	b.ids = make([]int64, n)
+	b.mask = make([]int64, n)
+	b.typeIDs = make([]int64, n)
+	for i, r := range rows {
+		base := i * b.seqLen
+		for j := 0; j < b.seqLen; j++ {
+			if j < len(r) {
+				b.ids[base+j] = r[j]
+				b.mask[base+j] = 1
+				if typeRows != nil && j < len(typeRows[i]) {
+					b.typeIDs[base+j] = typeRows[i][j]
+				}
+			} else {
+				b.ids[base+j] = padID
+			}
+		}
+	}
+	return b
+}
+
+// rowMask returns the attention mask of row i.
+func (b *encoderBatch) rowMask(i int) []int64 {
+	return b.mask[i*b.seqLen : (i+1)*b.seqLen]
+}
+
+// floatOutput is a float32 model output copied out of ORT memory.
+type floatOutput struct {
+	data  []float32
+	shape []int64
+}
+
+// Run feeds input_ids, attention_mask and token_type_ids (when the graph
+// takes them) and returns every float output by name.
+func (m *EncoderModel) Run(b *encoderBatch) (map[string]floatOutput, error) {
+	feeds := map[string]onnx.Value{}
+	defer destroyValues(feeds)
+	if err := m.addTextFeeds(feeds, b); err != nil {
+		return nil, err
+	}
+	return m.runFeeds(feeds, b.seqLen)
+}
+
+// addTextFeeds adds the token tensors of b the graph takes to feeds; the
+// caller destroys them.
+func (m *EncoderModel) addTextFeeds(feeds map[string]onnx.Value, b *encoderBatch) error {
+	if b.batch == 0 || b.seqLen == 0 {
+		return errors.New("EncoderModel.Run: empty batch")
+	}
+	shape := []int64{int64(b.batch), int64(b.seqLen)}
+	for name, data := range map[string][]int64{
+		"input_ids":      b.ids,
+		"attention_mask": b.mask,
+		"token_type_ids": b.typeIDs,
+	} {
+		if !m.hasInput(name) {
+			continue
+		}
+		t, err := tensorFromInt64s(data, shape)
+		if err != nil {
+			return fmt.Errorf("create %s tensor: %w", name, err)
+		}
+		feeds[name] = t
+	}
+	return nil
+}
+
+// runFeeds runs the graph and copies every float output out of ORT memory.
+// Inputs missing from feeds are zero-filled.
+func (m *EncoderModel) runFeeds(feeds map[string]onnx.Value, seqLen int) (map[string]floatOutput, error) {
+	outs, err := m.runNamed(feeds, seqLen, m.config)
+	if err != nil {
+		return nil, err
+	}
+	defer destroyValues(outs)
+
+	res := make(map[string]floatOutput, len(outs))
+	for name, v := range outs {
+		data, sh, err := float32sFromValue(v)
+		if err != nil {
+			continue // non-float outputs are not used by any task
+		}
+		res[name] = floatOutput{data: append([]float32(nil), data...), shape: append([]int64(nil), sh...)}
+	}
+	return res, nil
+}
+
+// output returns the first of names present in outs.
+func (m *EncoderModel) output(outs map[string]floatOutput, names ...string) (floatOutput, error) {
+	for _, name := range names {
+		if o, ok := outs[name]; ok {
+			return o, nil
+		}
+	}
+	return floatOutput{}, fmt.Errorf("%s: none of the outputs %v found (have %v)", m.modelID, names, m.outputNames)
+}
+
+// maxSequenceLength is the model's position limit, used to truncate inputs
+// the tokenizer does not truncate itself. RoBERTa-family models number
+// positions from pad_token_id+1, so those slots are unusable.
+func (m *EncoderModel) maxSequenceLength() int {
+	limit := intOption(m.config.Raw(), "max_position_embeddings", 512)
+	switch m.config.ModelType() {
+	case "roberta", "xlm-roberta", "camembert", "mpnet", "longformer":
+		if pad := m.config.PAD_TOKEN_ID(); pad >= 0 {
+			limit -= int(pad) + 1
+		}
+	}
+	return limit
+}
+
+// padID is the pad token, defaulting to 0 as BERT vocabularies do.
+func (m *EncoderModel) padID() int64 {
+	if id := m.config.PAD_TOKEN_ID(); id >= 0 {
+		return id
+	}
+	return 0
+}
+
+// Close frees the model's ONNX session. The model cannot be used
+// afterwards; closing twice is a no-op.
+func (m *EncoderModel) Close() error {
+	if m == nil {
+		return nil
+	}
+	return m.onnxGraph.Close()
+}
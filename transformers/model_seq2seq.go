@@ -0,0 +1,268 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// ModelForSeq2SeqLM is our ONNX-backed encoder-decoder wrapper (T5, BART,
+// Marian, NLLB). It loads onnx/encoder_model*.onnx plus a merged or split
+// decoder and decodes with self- and cross-attention KV caching.
+type ModelForSeq2SeqLM struct {
+	modelID string
+	config  *Config
+	dtype   string
+
+	encoder *onnxGraph
+	*seq2seqDecoder
+}
+
+// autoModelForSeq2SeqLM is the HF-style static dispatcher:
+//
+//	model, err := AutoModelForSeq2SeqLM.FromPretrained(...)
+type autoModelForSeq2SeqLM struct{}
+
+var AutoModelForSeq2SeqLM autoModelForSeq2SeqLM
+
+// FromPretrained constructs the model from HF Hub.
+func (a autoModelForSeq2SeqLM) FromPretrained(
+	modelID string,
+	config *Config,
+	dtype string,
+) (*ModelForSeq2SeqLM, error) {
+	return a.FromPretrainedWithOptions(modelID, config, dtype, ModelLoadOptions{})
+}
+
+// FromPretrainedWithOptions is FromPretrained with control over ONNX session
+// creation.
+func (autoModelForSeq2SeqLM) FromPretrainedWithOptions(
+	modelID string,
+	config *Config,
+	dtype string,
+	loadOpts ModelLoadOptions,
+) (*ModelForSeq2SeqLM, error) {
+	if config == nil {
+		return nil, errors.New("AutoModelForSeq2SeqLM.FromPretrained: config is nil")
+	}
+	releaseEnv, err := acquireEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseEnv()
+
+	encoder, err := loadEncoderGraph(modelID, dtype, "encoder_model", loadOpts)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := loadSeq2SeqDecoder(modelID, dtype, config, loadOpts)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+
+	logModelLoadInfo(modelID)
+
+	return &ModelForSeq2SeqLM{
+		modelID:        modelID,
+		config:         config,
+		dtype:          dtype,
+		encoder:        encoder,
+		seq2seqDecoder: decoder,
+	}, nil
+}
+
+// loadEncoderGraph loads onnx/<base><dtype suffix>.onnx.
+func loadEncoderGraph(modelID, dtype, base string, loadOpts ModelLoadOptions) (*onnxGraph, error) {
+	filename := "onnx/" + base + onnxDtypeSuffix(dtype) + ".onnx"
+	path, ok, err := fetchOptionalONNX(modelID, filename)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", filename, err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s not found in %s", filename, modelID)
+	}
+	g, err := newONNXGraph(path, loadOpts)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", base, err)
+	}
+	return g, nil
+}
+
+// Generate encodes the source sequence once and decodes greedily.
+// It currently supports batch=1 only. The decoder prompt defaults to
+// decoder_start_token_id (+ forced_bos_token_id); set
+// GenerationOptions.DecoderInputIDs to override it, e.g. for a target
+// language. Returned IDs exclude the prompt.
+func (m *ModelForSeq2SeqLM) Generate(
+	tokenizer *Tokenizer,
+	inputIDs [][]int64,
+	attentionMask [][]int64,
+	opts GenerationOptions,
+) ([][]int64, error) {
+	if len(inputIDs) != 1 || len(attentionMask) != 1 {
+		return nil, errors.New("Generate: only batch=1 is supported currently")
+	}
+	if opts.MaxNewTokens <= 0 {
+		opts.MaxNewTokens = 128
+	}
+
+	ids, mask := inputIDs[0], attentionMask[0]
+	idsTensor, err := tensorFromInt64s(ids, []int64{1, int64(len(ids))})
+	if err != nil {
+		return nil, fmt.Errorf("create input_ids tensor: %w", err)
+	}
+	defer idsTensor.Destroy()
+	maskTensor, err := tensorFromInt64s(mask, []int64{1, int64(len(mask))})
+	if err != nil {
+		return nil, fmt.Errorf("create attention_mask tensor: %w", err)
+	}
+	defer maskTensor.Destroy()
+
+	encOut, err := m.encoder.runNamed(map[string]onnx.Value{
+		"input_ids":      idsTensor,
+		"attention_mask": maskTensor,
+	}, len(ids), m.config)
+	if err != nil {
+		return nil, fmt.Errorf("encoder: %w", err)
+	}
+	defer destroyValues(encOut)
+	hidden, ok := encOut["last_hidden_state"]
+	if !ok {
+		return nil, errors.New("encoder output 'last_hidden_state' missing")
+	}
+
+	generated, err := m.generate(tokenizer, map[string]onnx.Value{
+		"encoder_hidden_states":  hidden,
+		"encoder_attention_mask": maskTensor,
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return [][]int64{generated}, nil
+}
+
+// seq2seqDecoder is the decoder half of an encoder-decoder export. It is
+// shared by text, speech and vision encoders, which differ only in how the
+// encoder_hidden_states are produced.
+type seq2seqDecoder struct {
+	config   *Config
+	decoder  *onnxGraph // decoder_model_merged.onnx or decoder_model.onnx
+	withPast *onnxGraph // decoder_with_past_model.onnx (split layout only)
+	layout   decoderLayout
+}
+
+func loadSeq2SeqDecoder(modelID, dtype string, config *Config, loadOpts ModelLoadOptions) (*seq2seqDecoder, error) {
+	files, err := resolveDecoderFiles(modelID, dtype,
+		"", "decoder_model_merged", "decoder_model", "decoder_with_past_model")
+	if err != nil {
+		return nil, fmt.Errorf("download onnx decoder: %w", err)
+	}
+	d := &seq2seqDecoder{config: config, layout: files.layout}
+	if d.decoder, err = newONNXGraph(files.decoderPath, loadOpts); err != nil {
+		return nil, fmt.Errorf("load decoder: %w", err)
+	}
+	if files.layout == decoderLayoutSplit {
+		if d.withPast, err = newONNXGraph(files.withPastPath, loadOpts); err != nil {
+			d.decoder.Close()
+			return nil, fmt.Errorf("load decoder_with_past: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// decoderStartID is decoder_start_token_id, falling back to pad (T5,
+// Marian) and then bos.
+func (d *seq2seqDecoder) decoderStartID() int64 {
+	start := d.config.DecoderStartTokenID()
+	if start < 0 {
+		start = d.config.PAD_TOKEN_ID()
+	}
+	if start < 0 {
+		start = d.config.BOS_TOKEN_ID()
+	}
+	return start
+}
+
+// decoderPrompt returns the IDs the decoder starts from.
+func (d *seq2seqDecoder) decoderPrompt(opts GenerationOptions) []int64 {
+	if len(opts.DecoderInputIDs) > 0 {
+		return opts.DecoderInputIDs
+	}
+	prompt := []int64{d.decoderStartID()}
+	if forced := d.config.ForcedBOSTokenID(); forced >= 0 {
+		prompt = append(prompt, forced)
+	}
+	return prompt
+}
+
+// generate decodes greedily, conditioned on the encoder outputs in extra
+// (encoder_hidden_states and optionally encoder_attention_mask). It returns
+// the generated IDs without the decoder prompt.
+func (d *seq2seqDecoder) generate(
+	tokenizer *Tokenizer,
+	extra map[string]onnx.Value,
+	opts GenerationOptions,
+) ([]int64, error) {
+	st := &generationState{eosID: d.config.EOS_TOKEN_ID()}
+	ids := append([]int64(nil), d.decoderPrompt(opts)...)
+
+	var past kvCache
+	defer func() { past.destroy() }()
+
+	for step := 0; step < opts.MaxNewTokens; step++ {
+		graph := d.decoder
+		stepIDs := ids
+		if past != nil {
+			stepIDs = ids[len(ids)-1:]
+			if d.withPast != nil {
+				graph = d.withPast
+			}
+		}
+		mask := make([]int64, len(ids))
+		for i := range mask {
+			mask[i] = 1
+		}
+
+		logits, present, err := graph.runDecoderStep(decoderStep{
+			ids:     stepIDs,
+			mask:    mask,
+			pastLen: len(ids) - len(stepIDs),
+			past:    past,
+			extra:   extra,
+			zero: func(name string, seqLen int) (onnx.Value, error) {
+				return graph.zeroInput(name, seqLen, d.config)
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		past = past.update(present)
+
+		for _, process := range opts.logitsProcessors {
+			process(ids, logits)
+		}
+		nextID := int64(argmaxF32(logits))
+		ids = append(ids, nextID)
+
+		if st.advance(tokenizer, nextID, step, opts) {
+			break
+		}
+	}
+
+	return st.generated, nil
+}
+
+// Close frees the encoder and decoder sessions. The model cannot be used
+// afterwards; closing twice is a no-op.
+func (m *ModelForSeq2SeqLM) Close() error {
+	return errors.Join(m.encoder.Close(), m.seq2seqDecoder.close())
+}
+
+func (d *seq2seqDecoder) close() error {
+	if d == nil {
+		return nil
+	}
+	return errors.Join(d.decoder.Close(), d.withPast.Close())
+}
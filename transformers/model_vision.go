@@ -0,0 +1,60 @@
+package transformers
+
+import (
+	"fmt"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// RunPixels feeds one preprocessed image as pixel_values (plus pixel_mask
+// when the graph takes it) and returns every float output by name.
+func (m *EncoderModel) RunPixels(pv *PixelValues) (map[string]floatOutput, error) {
+	feeds := map[string]onnx.Value{}
+	defer destroyValues(feeds)
+	if err := m.addPixelFeeds(feeds, pv); err != nil {
+		return nil, err
+	}
+	return m.runFeeds(feeds, 0)
+}
+
+// runPixelsAndText feeds an image and a batch of texts together, for
+// combined image-text graphs such as CLIP's model.onnx.
+func (m *EncoderModel) runPixelsAndText(pv *PixelValues, b *encoderBatch) (map[string]floatOutput, error) {
+	feeds := map[string]onnx.Value{}
+	defer destroyValues(feeds)
+	if err := m.addPixelFeeds(feeds, pv); err != nil {
+		return nil, err
+	}
+	if err := m.addTextFeeds(feeds, b); err != nil {
+		return nil, err
+	}
+	return m.runFeeds(feeds, b.seqLen)
+}
+
+// addPixelFeeds adds pixel_values and, when the graph takes it, pixel_mask
+// to feeds; the caller destroys them. Single-input graphs exported outside
+// transformers (RMBG's "input") get the pixels under their own name.
+func (m *EncoderModel) addPixelFeeds(feeds map[string]onnx.Value, pv *PixelValues) error {
+	name := "pixel_values"
+	if !m.hasInput(name) && len(m.inputNames) == 1 {
+		name = m.inputNames[0]
+	}
+	pixels, err := tensorFromFloat32s(pv.Data, pv.Shape)
+	if err != nil {
+		return fmt.Errorf("create %s tensor: %w", name, err)
+	}
+	feeds[name] = pixels
+
+	if m.hasInput("pixel_mask") {
+		h, w := pv.Shape[2], pv.Shape[3]
+		if pv.ChannelsLast {
+			h, w = pv.Shape[1], pv.Shape[2]
+		}
+		mask, err := tensorFromInt64s(pv.PixelMask, []int64{1, h, w})
+		if err != nil {
+			return fmt.Errorf("create pixel_mask tensor: %w", err)
+		}
+		feeds["pixel_mask"] = mask
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// ModelForVision2Seq is our ONNX wrapper for vision encoder-decoder
+// exports (ViT-GPT2, TrOCR, ...): onnx/encoder_model*.onnx encodes
+// pixel_values once and the text decoder cross-attends to the result.
+type ModelForVision2Seq struct {
+	modelID string
+	config  *Config
+	dtype   string
+
+	encoder *EncoderModel
+	*seq2seqDecoder
+}
+
+// autoModelForVision2Seq is the HF-style static dispatcher:
+//
+//	model, err := AutoModelForVision2Seq.FromPretrained(...)
+type autoModelForVision2Seq struct{}
+
+var AutoModelForVision2Seq autoModelForVision2Seq
+
+// FromPretrained constructs the model from HF Hub.
+func (a autoModelForVision2Seq) FromPretrained(
+	modelID string,
+	config *Config,
+	dtype string,
+) (*ModelForVision2Seq, error) {
+	return a.FromPretrainedWithOptions(modelID, config, dtype, ModelLoadOptions{})
+}
+
+// FromPretrainedWithOptions is FromPretrained with control over ONNX session
+// creation.
+func (autoModelForVision2Seq) FromPretrainedWithOptions(
+	modelID string,
+	config *Config,
+	dtype string,
+	loadOpts ModelLoadOptions,
+) (*ModelForVision2Seq, error) {
+	if config == nil {
+		return nil, errors.New("AutoModelForVision2Seq.FromPretrained: config is nil")
+	}
+	releaseEnv, err := acquireEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseEnv()
+
+	graph, err := loadEncoderGraph(modelID, dtype, "encoder_model", loadOpts)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := loadSeq2SeqDecoder(modelID, dtype, decoderKVConfig(config), loadOpts)
+	if err != nil {
+		graph.Close()
+		return nil, err
+	}
+
+	logModelLoadInfo(modelID)
+
+	return &ModelForVision2Seq{
+		modelID:        modelID,
+		config:         config,
+		dtype:          dtype,
+		encoder:        &EncoderModel{modelID: modelID, config: config, dtype: dtype, onnxGraph: graph},
+		seq2seqDecoder: decoder,
+	}, nil
+}
+
+// decoderKVConfig fills in the decoder head sizes that vision
+// encoder-decoder configs keep under "decoder", so empty KV caches get a
+// valid shape. Token IDs stay those of the top-level config.
+func decoderKVConfig(config *Config) *Config {
+	dec, ok := config.Raw()["decoder"].(map[string]any)
+	if !ok {
+		return config
+	}
+	cfg := *config
+	if cfg.numAttentionHeads == 0 {
+		cfg.numAttentionHeads = intOption(dec, "num_attention_heads",
+			intOption(dec, "n_head", intOption(dec, "decoder_attention_heads", 0)))
+	}
+	if cfg.numKeyValueHeads == 0 {
+		cfg.numKeyValueHeads = intOption(dec, "num_key_value_heads", 0)
+	}
+	if cfg.headDim == 0 && cfg.numAttentionHeads > 0 {
+		hidden := intOption(dec, "hidden_size", intOption(dec, "n_embd", intOption(dec, "d_model", 0)))
+		cfg.headDim = hidden / cfg.numAttentionHeads
+	}
+	return &cfg
+}
+
+// Generate encodes one preprocessed image and decodes greedily. Returned
+// IDs exclude the decoder prompt.
+func (m *ModelForVision2Seq) Generate(
+	tokenizer *Tokenizer,
+	pv *PixelValues,
+	opts GenerationOptions,
+) ([]int64, error) {
+	if opts.MaxNewTokens <= 0 {
+		opts.MaxNewTokens = 128
+	}
+	feeds := map[string]onnx.Value{}
+	defer destroyValues(feeds)
+	if err := m.encoder.addPixelFeeds(feeds, pv); err != nil {
+		return nil, err
+	}
+	encOut, err := m.encoder.runNamed(feeds, 0, m.config)
+	if err != nil {
+		return nil, fmt.Errorf("encoder: %w", err)
+	}
+	defer destroyValues(encOut)
+	hidden, ok := encOut["last_hidden_state"]
+	if !ok {
+		return nil, errors.New("encoder output 'last_hidden_state' missing")
+	}
+
+	return m.generate(tokenizer, map[string]onnx.Value{
+		"encoder_hidden_states": hidden,
+	}, opts)
+}
+
+// Close frees the encoder and decoder sessions. The model cannot be used
+// afterwards; closing twice is a no-op.
+func (m *ModelForVision2Seq) Close() error {
+	return errors.Join(m.encoder.Close(), m.seq2seqDecoder.close())
+}
@@ -0,0 +1,58 @@
+package transformers
+
+import "testing"
+
+func TestDecoderKVConfig(t *testing.T) {
+	tests := []struct {
+		name                    string
+		cfg                     *Config
+		heads, kvHeads, headDim int
+	}{
+		{
+			// ViT-GPT2 keeps GPT-2's names under "decoder".
+			name:    "gpt2 decoder",
+			cfg:     &Config{raw: map[string]any{"decoder": map[string]any{"n_head": 12.0, "n_embd": 768.0}}},
+			heads:   12,
+			headDim: 64,
+		},
+		{
+			// TrOCR uses BART-style names.
+			name:    "trocr decoder",
+			cfg:     &Config{raw: map[string]any{"decoder": map[string]any{"decoder_attention_heads": 16.0, "d_model": 1024.0}}},
+			heads:   16,
+			headDim: 64,
+		},
+		{
+			name:    "grouped-query decoder",
+			cfg:     &Config{raw: map[string]any{"decoder": map[string]any{"num_attention_heads": 8.0, "num_key_value_heads": 2.0, "hidden_size": 512.0}}},
+			heads:   8,
+			kvHeads: 2,
+			headDim: 64,
+		},
+		{
+			name:    "top-level values win",
+			cfg:     &Config{numAttentionHeads: 4, headDim: 16, raw: map[string]any{"decoder": map[string]any{"n_head": 12.0, "n_embd": 768.0}}},
+			heads:   4,
+			headDim: 16,
+		},
+		{
+			name:  "no decoder section",
+			cfg:   &Config{numAttentionHeads: 6, raw: map[string]any{}},
+			heads: 6,
+		},
+	}
+	for _, tt := range tests {
+		got := decoderKVConfig(tt.cfg)
+		if got.NumAttentionHeads() != tt.heads || got.NumKeyValueHeads() != tt.kvHeads || got.HeadDim() != tt.headDim {
+			t.Errorf("%s: heads %d, kv heads %d, head dim %d; want %d, %d, %d", tt.name,
+				got.NumAttentionHeads(), got.NumKeyValueHeads(), got.HeadDim(), tt.heads, tt.kvHeads, tt.headDim)
+		}
+	}
+
+	// The caller's config is not modified.
+	cfg := &Config{raw: map[string]any{"decoder": map[string]any{"n_head": 12.0, "n_embd": 768.0}}}
+	decoderKVConfig(cfg)
+	if cfg.NumAttentionHeads() != 0 || cfg.HeadDim() != 0 {
+		t.Errorf("decoderKVConfig modified its input: %+v", cfg)
+	}
+}
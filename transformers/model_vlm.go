@@ -0,0 +1,380 @@
+package transformers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// ModelForImageTextToText is our ONNX wrapper for vision-language chat
+// models exported transformers.js-style as three graphs (SmolVLM,
+// Idefics3):
+//
+//	onnx/vision_encoder.onnx        pixel_values -> image_features
+//	onnx/embed_tokens.onnx          input_ids -> inputs_embeds
+//	onnx/decoder_model_merged.onnx  inputs_embeds + KV cache -> logits
+//
+// The prompt's image tokens are embedded, then overwritten with the image
+// features before the decoder sees them.
+type ModelForImageTextToText struct {
+	modelID string
+	config  *Config
+	dtype   string
+
+	vision   *onnxGraph
+	embed    *onnxGraph
+	decoder  *onnxGraph
+	withPast *onnxGraph // decode-step graph for decoderLayoutSplit
+
+	// kvConfig carries the text model's head sizes for empty caches.
+	kvConfig *Config
+
+	imageTokenID int64
+	imageSeqLen  int // features per image
+	imageSize    int // square input of the vision encoder
+}
+
+// autoModelForImageTextToText is the HF-style static dispatcher:
+//
+//	model, err := AutoModelForImageTextToText.FromPretrained(...)
+type autoModelForImageTextToText struct{}
+
+var AutoModelForImageTextToText autoModelForImageTextToText
+
+// FromPretrained loads the three graphs for dtype from HF Hub.
+func (a autoModelForImageTextToText) FromPretrained(
+	modelID string,
+	config *Config,
+	dtype string,
+) (*ModelForImageTextToText, error) {
+	return a.FromPretrainedWithOptions(modelID, config, dtype, ModelLoadOptions{})
+}
+
+// FromPretrainedWithOptions is FromPretrained with control over ONNX session
+// creation.
+func (autoModelForImageTextToText) FromPretrainedWithOptions(
+	modelID string,
+	config *Config,
+	dtype string,
+	loadOpts ModelLoadOptions,
+) (*ModelForImageTextToText, error) {
+	if config == nil {
+		return nil, errors.New("AutoModelForImageTextToText.FromPretrained: config is nil")
+	}
+	releaseEnv, err := acquireEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseEnv()
+
+	m := &ModelForImageTextToText{
+		modelID: modelID,
+		config:  config,
+		dtype:   dtype,
+	}
+	if m.vision, err = loadEncoderGraph(modelID, dtype, "vision_encoder", loadOpts); err != nil {
+		return nil, err
+	}
+	if m.embed, err = loadEncoderGraph(modelID, dtype, "embed_tokens", loadOpts); err != nil {
+		m.Close()
+		return nil, err
+	}
+	files, err := resolveDecoderFiles(modelID, dtype,
+		"", "decoder_model_merged", "decoder_model", "decoder_with_past_model")
+	if err != nil {
+		m.Close()
+		return nil, fmt.Errorf("download onnx model: %w", err)
+	}
+	if m.decoder, err = newONNXGraph(files.decoderPath, loadOpts); err != nil {
+		m.Close()
+		return nil, fmt.Errorf("load decoder: %w", err)
+	}
+	if !m.decoder.hasInput("inputs_embeds") {
+		m.Close()
+		return nil, fmt.Errorf("%s: decoder has no inputs_embeds input", modelID)
+	}
+	if files.layout == decoderLayoutSplit {
+		if m.withPast, err = newONNXGraph(files.withPastPath, loadOpts); err != nil {
+			m.Close()
+			return nil, fmt.Errorf("load decoder_with_past: %w", err)
+		}
+	}
+
+	raw := config.Raw()
+	text, _ := raw["text_config"].(map[string]any)
+	vision, _ := raw["vision_config"].(map[string]any)
+	m.kvConfig = &Config{
+		numAttentionHeads: intOption(text, "num_attention_heads", 0),
+		numKeyValueHeads:  intOption(text, "num_key_value_heads", 0),
+		headDim:           intOption(text, "head_dim", 0),
+	}
+	if m.kvConfig.headDim == 0 && m.kvConfig.numAttentionHeads > 0 {
+		m.kvConfig.headDim = intOption(text, "hidden_size", 0) / m.kvConfig.numAttentionHeads
+	}
+
+	m.imageTokenID = int64(intOption(raw, "image_token_id", intOption(raw, "image_token_index", -1)))
+	m.imageSize = intOption(vision, "image_size", 512)
+	// Idefics3 pixel-shuffles (image_size/patch_size)^2 patches by
+	// scale_factor in each direction; processor_config.json says so too.
+	patches := m.imageSize / max(intOption(vision, "patch_size", 16), 1)
+	scale := max(intOption(raw, "scale_factor", 1), 1)
+	m.imageSeqLen = patches * patches / (scale * scale)
+	if pc := loadProcessorConfig(modelID); pc != nil {
+		m.imageSeqLen = intOption(pc, "image_seq_len", m.imageSeqLen)
+	}
+
+	logModelLoadInfo(modelID)
+	return m, nil
+}
+
+// loadProcessorConfig reads processor_config.json (best effort).
+func loadProcessorConfig(modelID string) map[string]any {
+	paths, err := HFHubEnsureOptionalFiles(modelID, []string{"processor_config.json"})
+	if err != nil {
+		return nil
+	}
+	path, ok := paths["processor_config.json"]
+	if !ok {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var raw map[string]any
+	if json.Unmarshal(data, &raw) != nil {
+		return nil
+	}
+	return raw
+}
+
+// isImageTextToText reports whether cfg describes a vision-language chat
+// model that ModelForImageTextToText can run.
+func isImageTextToText(cfg *Config) bool {
+	if _, ok := cfg.Raw()["vision_config"]; !ok {
+		return false
+	}
+	switch cfg.ModelType() {
+	case "idefics3", "smolvlm":
+		return true
+	}
+	return false
+}
+
+// ImageProcessor adapts the repo's preprocessor to the encoder's fixed
+// square input. Images are encoded whole: Idefics3's tiling into sub-images
+// (do_image_splitting) is not applied.
+func (m *ModelForImageTextToText) ImageProcessor() (*ImageProcessor, error) {
+	p, err := AutoImageProcessor.FromPretrained(m.modelID)
+	if err != nil {
+		return nil, err
+	}
+	p.DoResize = true
+	p.Size = ImageSize{Height: m.imageSize, Width: m.imageSize}
+	p.DoCenterCrop, p.DoPad = false, false
+	return p, nil
+}
+
+// ExpandImagePrompt replaces every <image> placeholder of a rendered prompt
+// with the model's image token sequence. Idefics3 vocabularies wrap the
+// features in <fake_token_around_image><global-img>...; others repeat
+// <image> once per feature.
+func (m *ModelForImageTextToText) ExpandImagePrompt(tokenizer *Tokenizer, prompt string) string {
+	features := strings.Repeat(imagePlaceholder, m.imageSeqLen)
+	if _, ok := tokenizer.TokenToID("<fake_token_around_image>"); ok {
+		features = "<fake_token_around_image><global-img>" + features + "<fake_token_around_image>"
+	}
+	return strings.ReplaceAll(prompt, imagePlaceholder, features)
+}
+
+// Generate decodes greedily from a prompt whose image tokens are filled
+// with the features of images, in order. Returned IDs exclude the prompt.
+func (m *ModelForImageTextToText) Generate(
+	tokenizer *Tokenizer,
+	inputIDs []int64,
+	images []*PixelValues,
+	opts GenerationOptions,
+) ([]int64, error) {
+	if opts.MaxNewTokens <= 0 {
+		opts.MaxNewTokens = 128
+	}
+	embeds, hidden, err := m.embedTokens(inputIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) > 0 {
+		imageID := m.imageTokenID
+		if imageID < 0 {
+			imageID, _ = tokenizer.TokenToID(imagePlaceholder)
+		}
+		if err := m.mergeImageFeatures(inputIDs, imageID, embeds, hidden, images); err != nil {
+			return nil, err
+		}
+	}
+
+	eosID := m.config.EOS_TOKEN_ID()
+	if eosID < 0 {
+		// VLM configs often keep it in text_config only.
+		if id, ok := tokenizer.TokenToID(tokenizer.SpecialToken("eos_token")); ok {
+			eosID = id
+		}
+	}
+	st := &generationState{eosID: eosID}
+	ids := append([]int64(nil), inputIDs...)
+	mask := make([]int64, len(ids))
+	for i := range mask {
+		mask[i] = 1
+	}
+	var past kvCache
+	defer func() { past.destroy() }()
+
+	for step := 0; step < opts.MaxNewTokens; step++ {
+		graph := m.decoder
+		stepIDs := ids
+		if past != nil {
+			stepIDs = ids[len(ids)-1:]
+			if embeds, _, err = m.embedTokens(stepIDs); err != nil {
+				return nil, err
+			}
+			if m.withPast != nil {
+				graph = m.withPast
+			}
+		}
+		embedsTensor, err := graph.floatInput("inputs_embeds", embeds, []int64{1, int64(len(stepIDs)), int64(hidden)})
+		if err != nil {
+			return nil, fmt.Errorf("create inputs_embeds tensor: %w", err)
+		}
+		logits, present, err := graph.runDecoderStep(decoderStep{
+			ids:     stepIDs,
+			mask:    mask,
+			pastLen: len(ids) - len(stepIDs),
+			past:    past,
+			extra:   map[string]onnx.Value{"inputs_embeds": embedsTensor},
+			zero: func(name string, seqLen int) (onnx.Value, error) {
+				return graph.zeroInput(name, seqLen, m.kvConfig)
+			},
+		})
+		embedsTensor.Destroy()
+		if err != nil {
+			return nil, err
+		}
+		past.destroy()
+		past = present
+
+		for _, p := range opts.logitsProcessors {
+			p(ids, logits)
+		}
+		nextID := int64(argmaxF32(logits))
+		ids = append(ids, nextID)
+		mask = append(mask, 1)
+
+		if st.advance(tokenizer, nextID, step, opts) {
+			break
+		}
+	}
+	return st.generated, nil
+}
+
+// embedTokens runs embed_tokens and returns [len(ids), hidden] row-major.
+func (m *ModelForImageTextToText) embedTokens(ids []int64) ([]float32, int, error) {
+	t, err := tensorFromInt64s(ids, []int64{1, int64(len(ids))})
+	if err != nil {
+		return nil, 0, fmt.Errorf("create input_ids tensor: %w", err)
+	}
+	defer t.Destroy()
+	outs, err := m.embed.runNamed(map[string]onnx.Value{"input_ids": t}, len(ids), m.config)
+	if err != nil {
+		return nil, 0, fmt.Errorf("embed_tokens: %w", err)
+	}
+	defer destroyValues(outs)
+	v, ok := outs["inputs_embeds"]
+	if !ok {
+		v = outs[m.embed.outputNames[0]]
+	}
+	data, shape, err := float32sFromValue(v)
+	if err != nil || len(shape) != 3 {
+		return nil, 0, fmt.Errorf("embed_tokens: expected float32 [1, seq, hidden] output")
+	}
+	return append([]float32(nil), data...), int(shape[2]), nil
+}
+
+// mergeImageFeatures encodes images and writes their features over the
+// embeddings of the imageID tokens in ids, in order.
+func (m *ModelForImageTextToText) mergeImageFeatures(ids []int64, imageID int64, embeds []float32, hidden int, images []*PixelValues) error {
+	per := len(images[0].Data)
+	pixels := make([]float32, 0, per*len(images))
+	for _, pv := range images {
+		if len(pv.Data) != per {
+			return errors.New("vision encoder: images must share one size")
+		}
+		pixels = append(pixels, pv.Data...)
+	}
+	n, c, h, w := int64(len(images)), images[0].Shape[1], images[0].Shape[2], images[0].Shape[3]
+
+	feeds := map[string]onnx.Value{}
+	defer destroyValues(feeds)
+	shape := []int64{n, c, h, w}
+	if info, ok := m.vision.inputInfo["pixel_values"]; ok && len(info.Dimensions) == 5 {
+		shape = []int64{1, n, c, h, w}
+	}
+	t, err := m.vision.floatInput("pixel_values", pixels, shape)
+	if err != nil {
+		return fmt.Errorf("create pixel_values tensor: %w", err)
+	}
+	feeds["pixel_values"] = t
+	if m.vision.hasInput("pixel_attention_mask") {
+		valid := make([]bool, n*h*w)
+		for i := range valid {
+			valid[i] = true
+		}
+		maskShape := []int64{n, h, w}
+		if len(shape) == 5 {
+			maskShape = []int64{1, n, h, w}
+		}
+		mt, err := onnx.NewTensor(onnx.NewShape(maskShape...), valid)
+		if err != nil {
+			return fmt.Errorf("create pixel_attention_mask tensor: %w", err)
+		}
+		feeds["pixel_attention_mask"] = mt
+	}
+
+	outs, err := m.vision.runNamed(feeds, 0, m.config)
+	if err != nil {
+		return fmt.Errorf("vision encoder: %w", err)
+	}
+	defer destroyValues(outs)
+	v, ok := outs["image_features"]
+	if !ok {
+		v = outs[m.vision.outputNames[0]]
+	}
+	features, _, err := float32sFromValue(v)
+	if err != nil {
+		return fmt.Errorf("vision encoder: %w", err)
+	}
+
+	rows, k := len(features)/hidden, 0
+	for i, id := range ids {
+		if id != imageID {
+			continue
+		}
+		if k == rows {
+			return fmt.Errorf("prompt has more image tokens than the %d image features", rows)
+		}
+		copy(embeds[i*hidden:(i+1)*hidden], features[k*hidden:(k+1)*hidden])
+		k++
+	}
+	if k != rows {
+		return fmt.Errorf("prompt has %d image tokens for %d image features", k, rows)
+	}
+	return nil
+}
+
+// Close frees the vision encoder, embedding and decoder sessions. The model
+// cannot be used afterwards; closing twice is a no-op.
+func (m *ModelForImageTextToText) Close() error {
+	return errors.Join(m.vision.Close(), m.embed.Close(), m.decoder.Close(), m.withPast.Close())
+}
@@ -0,0 +1,69 @@
+package transformers
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestExpandImagePrompt(t *testing.T) {
+	m := &ModelForImageTextToText{imageSeqLen: 3}
+	prompt := "User: <image> and <image> ?"
+
+	plain := stubTokenizer(t, "<image>", "User:")
+	want := "User: " + strings.Repeat("<image>", 3) + " and " + strings.Repeat("<image>", 3) + " ?"
+	if got := m.ExpandImagePrompt(plain, prompt); got != want {
+		t.Errorf("ExpandImagePrompt = %q, want %q", got, want)
+	}
+
+	idefics := stubTokenizer(t, "<image>", "<fake_token_around_image>", "<global-img>")
+	one := "<fake_token_around_image><global-img>" + strings.Repeat("<image>", 3) + "<fake_token_around_image>"
+	want = "User: " + one + " and " + one + " ?"
+	if got := m.ExpandImagePrompt(idefics, prompt); got != want {
+		t.Errorf("ExpandImagePrompt(idefics3) = %q, want %q", got, want)
+	}
+
+	if got := m.ExpandImagePrompt(plain, "no images"); got != "no images" {
+		t.Errorf("ExpandImagePrompt(no placeholder) = %q", got)
+	}
+}
+
+func TestFloat32ToFloat16(t *testing.T) {
+	tests := []struct {
+		f    float32
+		want uint16
+	}{
+		{0, 0x0000},
+		{float32(math.Copysign(0, -1)), 0x8000},
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{0.5, 0x3800},
+		{65504, 0x7bff}, // largest half
+		{70000, 0x7c00}, // overflows to +Inf
+		{float32(math.Inf(-1)), 0xfc00},
+		{6.103515625e-05, 0x0400},       // smallest normal
+		{5.960464477539063e-08, 0x0001}, // smallest subnormal
+		{1e-9, 0x0000},                  // underflows to zero
+		{1.0009765625, 0x3c01},          // exact
+		{1 + 1.0/2048, 0x3c00},          // tie, rounds to even (down)
+		{1 + 3.0/2048, 0x3c02},          // tie, rounds to even (up)
+	}
+	for _, tt := range tests {
+		if got := float32ToFloat16(tt.f); got != tt.want {
+			t.Errorf("float32ToFloat16(%v) = %#04x, want %#04x", tt.f, got, tt.want)
+		}
+	}
+	if got := float32ToFloat16(float32(math.NaN())); got&0x7c00 != 0x7c00 || got&0x3ff == 0 {
+		t.Errorf("float32ToFloat16(NaN) = %#04x, want a NaN", got)
+	}
+
+	// Every finite half survives the round trip.
+	for h := 0; h < 0x10000; h++ {
+		if h&0x7c00 == 0x7c00 {
+			continue
+		}
+		if got := float32ToFloat16(float16ToFloat32(uint16(h))); got != uint16(h) {
+			t.Fatalf("round trip %#04x -> %#04x", h, got)
+		}
+	}
+}
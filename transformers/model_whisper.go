@@ -0,0 +1,487 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// ModelForSpeechSeq2Seq is our ONNX-backed Whisper wrapper. It loads
+// onnx/encoder_model*.onnx plus a merged or split decoder; the encoder reads
+// log-mel input_features instead of token IDs.
+type ModelForSpeechSeq2Seq struct {
+	modelID string
+	config  *Config
+	dtype   string
+
+	encoder *onnxGraph
+	*seq2seqDecoder
+
+	whisper whisperGenerationConfig
+}
+
+// autoModelForSpeechSeq2Seq is the HF-style static dispatcher:
+//
+//	model, err := AutoModelForSpeechSeq2Seq.FromPretrained(...)
+type autoModelForSpeechSeq2Seq struct{}
+
+var AutoModelForSpeechSeq2Seq autoModelForSpeechSeq2Seq
+
+// FromPretrained constructs the model from HF Hub.
+func (a autoModelForSpeechSeq2Seq) FromPretrained(
+	modelID string,
+	config *Config,
+	dtype string,
+) (*ModelForSpeechSeq2Seq, error) {
+	return a.FromPretrainedWithOptions(modelID, config, dtype, ModelLoadOptions{})
+}
+
+// FromPretrainedWithOptions is FromPretrained with control over ONNX session
+// creation.
+func (autoModelForSpeechSeq2Seq) FromPretrainedWithOptions(
+	modelID string,
+	config *Config,
+	dtype string,
+	loadOpts ModelLoadOptions,
+) (*ModelForSpeechSeq2Seq, error) {
+	if config == nil {
+		return nil, errors.New("AutoModelForSpeechSeq2Seq.FromPretrained: config is nil")
+	}
+	if config.ModelType() != "whisper" {
+		return nil, fmt.Errorf("AutoModelForSpeechSeq2Seq: model_type %q is not supported (only whisper)", config.ModelType())
+	}
+	releaseEnv, err := acquireEnvironment()
+	if err != nil {
+		return nil, err
+	}
+	defer releaseEnv()
+
+	encoder, err := loadEncoderGraph(modelID, dtype, "encoder_model", loadOpts)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := loadSeq2SeqDecoder(modelID, dtype, config, loadOpts)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+
+	logModelLoadInfo(modelID)
+
+	return &ModelForSpeechSeq2Seq{
+		modelID:        modelID,
+		config:         config,
+		dtype:          dtype,
+		encoder:        encoder,
+		seq2seqDecoder: decoder,
+		whisper:        newWhisperGenerationConfig(config),
+	}, nil
+}
+
+// WhisperOptions controls one Whisper decode.
+type WhisperOptions struct {
+	// Language is a code ("en"), a name ("english") or a token ("<|en|>").
+	// Empty means detect it (multilingual models only).
+	Language string
+	// Task is "transcribe" (default) or "translate" (to English).
+	Task string
+	// Timestamps makes the model emit <|t|> segment boundaries.
+	Timestamps bool
+
+	MaxNewTokens int
+	Streamer     func(ev PipelineStreamEvent) bool
+}
+
+// Generate runs the encoder on one [n_mels x frames] log-mel window and
+// decodes it. Returned IDs exclude the decoder prompt and include timestamp
+// tokens when opts.Timestamps is set; see ParseSegments.
+func (m *ModelForSpeechSeq2Seq) Generate(
+	tokenizer *Tokenizer,
+	features []float32,
+	nMels int,
+	opts WhisperOptions,
+) ([]int64, error) {
+	if nMels <= 0 || len(features)%nMels != 0 {
+		return nil, fmt.Errorf("Generate: %d features do not split into %d mel bins", len(features), nMels)
+	}
+	if opts.MaxNewTokens <= 0 {
+		opts.MaxNewTokens = 448
+	}
+
+	feat, err := tensorFromFloat32s(features, []int64{1, int64(nMels), int64(len(features) / nMels)})
+	if err != nil {
+		return nil, fmt.Errorf("create input_features tensor: %w", err)
+	}
+	defer feat.Destroy()
+
+	encOut, err := m.encoder.runNamed(map[string]onnx.Value{"input_features": feat}, 0, m.config)
+	if err != nil {
+		return nil, fmt.Errorf("encoder: %w", err)
+	}
+	defer destroyValues(encOut)
+	hidden, ok := encOut["last_hidden_state"]
+	if !ok {
+		return nil, errors.New("encoder output 'last_hidden_state' missing")
+	}
+	extra := map[string]onnx.Value{"encoder_hidden_states": hidden}
+
+	w := &m.whisper
+	langID := int64(-1)
+	if w.multilingual {
+		if opts.Language != "" {
+			id, ok := w.languageID(opts.Language)
+			if !ok {
+				return nil, fmt.Errorf("whisper: unknown language %q", opts.Language)
+			}
+			langID = id
+		} else if langID, err = m.detectLanguage(extra); err != nil {
+			return nil, fmt.Errorf("detect language: %w", err)
+		}
+	}
+	prompt, err := w.prompt(langID, opts.Task, opts.Timestamps)
+	if err != nil {
+		return nil, err
+	}
+
+	genOpts := GenerationOptions{
+		MaxNewTokens:     min(opts.MaxNewTokens, w.maxLength-len(prompt)),
+		Streamer:         opts.Streamer,
+		DecoderInputIDs:  prompt,
+		logitsProcessors: w.logitsProcessors(len(prompt), opts.Timestamps),
+	}
+	return m.generate(tokenizer, extra, genOpts)
+}
+
+// detectLanguage runs a single decoder step from <|startoftranscript|> and
+// picks the most likely language token.
+func (m *ModelForSpeechSeq2Seq) detectLanguage(extra map[string]onnx.Value) (int64, error) {
+	w := &m.whisper
+	allowed := make(map[int64]bool, len(w.langToID))
+	for _, id := range w.langToID {
+		allowed[id] = true
+	}
+	ids, err := m.generate(nil, extra, GenerationOptions{
+		MaxNewTokens:    1,
+		DecoderInputIDs: []int64{w.startID},
+		logitsProcessors: []func([]int64, []float32){
+			func(_ []int64, logits []float32) {
+				for i := range logits {
+					if !allowed[int64(i)] {
+						logits[i] = float32(math.Inf(-1))
+					}
+				}
+			},
+		},
+	})
+	if err != nil {
+		return -1, err
+	}
+	if len(ids) != 1 || !allowed[ids[0]] {
+		return -1, errors.New("no language token decoded")
+	}
+	return ids[0], nil
+}
+
+// whisperGenerationConfig is the Whisper-specific part of
+// generation_config.json.
+type whisperGenerationConfig struct {
+	startID        int64 // <|startoftranscript|>
+	eosID          int64 // <|endoftext|>
+	noTimestampsID int64 // <|notimestamps|>; timestamps start right after it
+	langToID       map[string]int64
+	taskToID       map[string]int64
+	multilingual   bool
+
+	suppressTokens           []int64
+	beginSuppressTokens      []int64
+	maxInitialTimestampIndex int
+	maxLength                int
+}
+
+func newWhisperGenerationConfig(cfg *Config) whisperGenerationConfig {
+	gen := cfg.GenerationConfig()
+	w := whisperGenerationConfig{
+		startID:                  cfg.DecoderStartTokenID(),
+		eosID:                    cfg.EOS_TOKEN_ID(),
+		noTimestampsID:           -1,
+		langToID:                 tokenIDMap(gen["lang_to_id"]),
+		taskToID:                 tokenIDMap(gen["task_to_id"]),
+		suppressTokens:           tokenIDList(gen["suppress_tokens"]),
+		beginSuppressTokens:      tokenIDList(gen["begin_suppress_tokens"]),
+		maxInitialTimestampIndex: intOption(gen, "max_initial_timestamp_index", 50),
+		maxLength:                intOption(gen, "max_length", 448),
+	}
+	if id, ok := toInt64(gen["no_timestamps_token_id"]); ok {
+		w.noTimestampsID = id
+	}
+	if v, ok := gen["is_multilingual"].(bool); ok {
+		w.multilingual = v
+	} else {
+		w.multilingual = len(w.langToID) > 0
+	}
+	return w
+}
+
+func tokenIDMap(v any) map[string]int64 {
+	m, _ := v.(map[string]any)
+	out := make(map[string]int64, len(m))
+	for k, x := range m {
+		if id, ok := toInt64(x); ok {
+			out[k] = id
+		}
+	}
+	return out
+}
+
+func tokenIDList(v any) []int64 {
+	xs, _ := v.([]any)
+	out := make([]int64, 0, len(xs))
+	for _, x := range xs {
+		if id, ok := toInt64(x); ok {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// languageID resolves a language code, English name or <|xx|> token.
+func (w *whisperGenerationConfig) languageID(lang string) (int64, bool) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if code, ok := whisperLanguageCodes[lang]; ok {
+		lang = code
+	}
+	if !strings.HasPrefix(lang, "<|") {
+		lang = "<|" + lang + "|>"
+	}
+	id, ok := w.langToID[lang]
+	return id, ok
+}
+
+// prompt builds the forced decoder prefix:
+// <|startoftranscript|> [<|lang|> <|task|>] [<|notimestamps|>].
+func (w *whisperGenerationConfig) prompt(langID int64, task string, timestamps bool) ([]int64, error) {
+	ids := []int64{w.startID}
+	if w.multilingual {
+		if task == "" {
+			task = "transcribe"
+		}
+		taskID, ok := w.taskToID[task]
+		if !ok {
+			return nil, fmt.Errorf("whisper: unknown task %q (want transcribe or translate)", task)
+		}
+		ids = append(ids, langID, taskID)
+	} else if task == "translate" {
+		return nil, errors.New("whisper: translate needs a multilingual model")
+	}
+	if !timestamps && w.noTimestampsID >= 0 {
+		ids = append(ids, w.noTimestampsID)
+	}
+	return ids, nil
+}
+
+// timestampBegin is the ID of <|0.00|>.
+func (w *whisperGenerationConfig) timestampBegin() int64 { return w.noTimestampsID + 1 }
+
+// logitsProcessors mirrors transformers' SuppressTokens,
+// SuppressTokensAtBegin and WhisperTimeStamp logits processors.
+func (w *whisperGenerationConfig) logitsProcessors(beginIndex int, timestamps bool) []func([]int64, []float32) {
+	negInf := float32(math.Inf(-1))
+	suppress := func(logits []float32, ids []int64) {
+		for _, id := range ids {
+			if id >= 0 && int(id) < len(logits) {
+				logits[id] = negInf
+			}
+		}
+	}
+
+	procs := []func([]int64, []float32){
+		func(ids []int64, logits []float32) {
+			suppress(logits, w.suppressTokens)
+			if len(ids) == beginIndex {
+				suppress(logits, w.beginSuppressTokens)
+			}
+		},
+	}
+	if !timestamps || w.noTimestampsID < 0 {
+		return procs
+	}
+
+	tsBegin := int(w.timestampBegin())
+	eos := int(w.eosID)
+	return append(procs, func(ids []int64, logits []float32) {
+		if tsBegin >= len(logits) {
+			return
+		}
+		logits[w.noTimestampsID] = negInf
+		fill := func(from, to int) {
+			for i := max(from, 0); i < min(to, len(logits)); i++ {
+				logits[i] = negInf
+			}
+		}
+
+		// Timestamps come in pairs, except directly before EOS.
+		seq := ids[beginIndex:]
+		lastWasTS := len(seq) >= 1 && int(seq[len(seq)-1]) >= tsBegin
+		penultimateWasTS := len(seq) < 2 || int(seq[len(seq)-2]) >= tsBegin
+		if lastWasTS {
+			if penultimateWasTS {
+				fill(tsBegin, len(logits)) // must be text
+			} else {
+				fill(0, eos) // must be a timestamp or EOS
+			}
+		}
+
+		// Timestamps must not decrease.
+		lastTS := -1
+		for i := len(seq) - 1; i >= 0; i-- {
+			if int(seq[i]) >= tsBegin {
+				lastTS = int(seq[i])
+				break
+			}
+		}
+		if lastTS >= 0 {
+			if !(lastWasTS && !penultimateWasTS) {
+				lastTS++
+			}
+			fill(tsBegin, lastTS)
+		}
+
+		// The first token is a timestamp no later than max_initial_timestamp.
+		if len(ids) == beginIndex {
+			fill(0, tsBegin)
+			fill(tsBegin+w.maxInitialTimestampIndex+1, len(logits))
+		}
+
+		// Prefer a timestamp when their total probability beats any text token.
+		tsLogProb, maxTextLogProb := timestampLogProbs(logits, tsBegin)
+		if tsLogProb > maxTextLogProb {
+			fill(0, tsBegin)
+		}
+	})
+}
+
+// timestampLogProbs returns logsumexp over the timestamp tokens and the best
+// text token, both as log-probabilities (normalization cancels out).
+func timestampLogProbs(logits []float32, tsBegin int) (float64, float64) {
+	maxText := math.Inf(-1)
+	for _, v := range logits[:tsBegin] {
+		maxText = math.Max(maxText, float64(v))
+	}
+	maxTS := math.Inf(-1)
+	for _, v := range logits[tsBegin:] {
+		maxTS = math.Max(maxTS, float64(v))
+	}
+	if math.IsInf(maxTS, -1) {
+		return maxTS, maxText
+	}
+	var sum float64
+	for _, v := range logits[tsBegin:] {
+		sum += math.Exp(float64(v) - maxTS)
+	}
+	return maxTS + math.Log(sum), maxText
+}
+
+// WhisperSegment is one timestamped span of a transcription, in seconds.
+type WhisperSegment struct {
+	Start, End float64
+	Text       string
+}
+
+// whisperTimePrecision is the spacing of Whisper timestamp tokens.
+const whisperTimePrecision = 0.02
+
+// ParseSegments splits generated IDs at timestamp tokens. offset (seconds) is
+// added to every timestamp; a trailing segment without a closing timestamp
+// ends at end.
+func (m *ModelForSpeechSeq2Seq) ParseSegments(
+	tokenizer *Tokenizer,
+	ids []int64,
+	offset float64,
+	end float64,
+) ([]WhisperSegment, error) {
+	tsBegin := m.whisper.timestampBegin()
+	var (
+		segs    []WhisperSegment
+		text    []int64
+		start   = offset
+		hasOpen bool
+	)
+	flush := func(stop float64) error {
+		txt, err := tokenizer.Decode(text)
+		if err != nil {
+			return err
+		}
+		if txt = strings.TrimSpace(txt); txt != "" {
+			segs = append(segs, WhisperSegment{Start: start, End: stop, Text: txt})
+		}
+		text = text[:0]
+		return nil
+	}
+	for _, id := range ids {
+		switch {
+		case id >= tsBegin && m.whisper.noTimestampsID >= 0:
+			t := offset + float64(id-tsBegin)*whisperTimePrecision
+			if hasOpen && len(text) > 0 {
+				if err := flush(t); err != nil {
+					return nil, err
+				}
+				hasOpen = false
+				continue
+			}
+			start, hasOpen = t, true
+		case id < m.whisper.eosID:
+			if !hasOpen {
+				start, hasOpen = offset, true
+			}
+			text = append(text, id)
+		}
+	}
+	if len(text) > 0 {
+		if err := flush(end); err != nil {
+			return nil, err
+		}
+	}
+	return segs, nil
+}
+
+// whisperLanguageCodes maps lowercase English language names to Whisper
+// language codes, as in transformers' models/whisper/tokenization_whisper.py.
+var whisperLanguageCodes = map[string]string{
+	"english": "en", "chinese": "zh", "german": "de", "spanish": "es",
+	"russian": "ru", "korean": "ko", "french": "fr", "japanese": "ja",
+	"portuguese": "pt", "turkish": "tr", "polish": "pl", "catalan": "ca",
+	"dutch": "nl", "arabic": "ar", "swedish": "sv", "italian": "it",
+	"indonesian": "id", "hindi": "hi", "finnish": "fi", "vietnamese": "vi",
+	"hebrew": "he", "ukrainian": "uk", "greek": "el", "malay": "ms",
+	"czech": "cs", "romanian": "ro", "danish": "da", "hungarian": "hu",
+	"tamil": "ta", "norwegian": "no", "thai": "th", "urdu": "ur",
+	"croatian": "hr", "bulgarian": "bg", "lithuanian": "lt", "latin": "la",
+	"maori": "mi", "malayalam": "ml", "welsh": "cy", "slovak": "sk",
+	"telugu": "te", "persian": "fa", "latvian": "lv", "bengali": "bn",
+	"serbian": "sr", "azerbaijani": "az", "slovenian": "sl", "kannada": "kn",
+	"estonian": "et", "macedonian": "mk", "breton": "br", "basque": "eu",
+	"icelandic": "is", "armenian": "hy", "nepali": "ne", "mongolian": "mn",
+	"bosnian": "bs", "kazakh": "kk", "albanian": "sq", "swahili": "sw",
+	"galician": "gl", "marathi": "mr", "punjabi": "pa", "sinhala": "si",
+	"khmer": "km", "shona": "sn", "yoruba": "yo", "somali": "so",
+	"afrikaans": "af", "occitan": "oc", "georgian": "ka", "belarusian": "be",
+	"tajik": "tg", "sindhi": "sd", "gujarati": "gu", "amharic": "am",
+	"yiddish": "yi", "lao": "lo", "uzbek": "uz", "faroese": "fo",
+	"haitian creole": "ht", "pashto": "ps", "turkmen": "tk", "nynorsk": "nn",
+	"maltese": "mt", "sanskrit": "sa", "luxembourgish": "lb", "myanmar": "my",
+	"tibetan": "bo", "tagalog": "tl", "malagasy": "mg", "assamese": "as",
+	"tatar": "tt", "hawaiian": "haw", "lingala": "ln", "hausa": "ha",
+	"bashkir": "ba", "javanese": "jw", "sundanese": "su", "cantonese": "yue",
+	"burmese": "my", "valencian": "ca", "flemish": "nl", "haitian": "ht",
+	"letzeburgesch": "lb", "pushto": "ps", "panjabi": "pa", "moldavian": "ro",
+	"moldovan": "ro", "sinhalese": "si", "castilian": "es", "mandarin": "zh",
+}
+
+// Close frees the encoder and decoder sessions. The model cannot be used
+// afterwards; closing twice is a no-op.
+func (m *ModelForSpeechSeq2Seq) Close() error {
+	return errors.Join(m.encoder.Close(), m.seq2seqDecoder.close())
+}
@@ -0,0 +1,350 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// onnxGraph is one ONNX session plus the signature needed to feed it.
+type onnxGraph struct {
+	session     *onnx.DynamicAdvancedSession
+	handle      *sessionHandle // owns session; see Close
+	inputNames  []string
+	outputNames []string
+	inputInfo   map[string]onnx.InputOutputInfo
+}
+
+// newONNXGraph opens onnxPath with the model's own input/output names.
+func newONNXGraph(onnxPath string, loadOpts ModelLoadOptions) (*onnxGraph, error) {
+	sessOpts, err := loadOpts.newSessionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("session options: %w", err)
+	}
+	defer sessOpts.Destroy()
+
+	inInfos, outInfos, err := onnx.GetInputOutputInfoWithOptions(onnxPath, sessOpts)
+	if err != nil {
+		return nil, fmt.Errorf("GetInputOutputInfo: %w", err)
+	}
+	g := &onnxGraph{inputInfo: make(map[string]onnx.InputOutputInfo, len(inInfos))}
+	for _, info := range inInfos {
+		g.inputNames = append(g.inputNames, info.Name)
+		g.inputInfo[info.Name] = info
+	}
+	for _, info := range outInfos {
+		g.outputNames = append(g.outputNames, info.Name)
+	}
+
+	if err := g.openSession(onnxPath, sessOpts, loadOpts); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// openSession creates g's session from its input/output names. The session
+// is registered with loadOpts' tracker, if any, so a GeneratorHandle frees
+// it on Close.
+func (g *onnxGraph) openSession(sessionPath string, sessOpts *onnx.SessionOptions, loadOpts ModelLoadOptions) error {
+	sess, err := onnx.NewDynamicAdvancedSession(sessionPath, g.inputNames, g.outputNames, sessOpts)
+	if err != nil {
+		return fmt.Errorf("create ONNX session: %w", err)
+	}
+	h, err := newSessionHandle(sessionPath, sess)
+	if err != nil {
+		sess.Destroy()
+		return err
+	}
+	g.session, g.handle = sess, h
+	loadOpts.tracker.add(h)
+	return nil
+}
+
+// Close destroys the session. Later runs fail with ErrClosed; closing
+// twice is a no-op.
+func (g *onnxGraph) Close() error {
+	if g == nil {
+		return nil
+	}
+	return g.handle.Close()
+}
+
+// run is session.Run guarded against use after Close.
+func (g *onnxGraph) run(inputs, outputs []onnx.Value) error {
+	if g.handle == nil || g.handle.closed.Load() {
+		return ErrClosed
+	}
+	return g.session.Run(inputs, outputs)
+}
+
+// runNamed runs g with the given feeds and returns every output by name.
+// Inputs missing from feeds are zero-filled via zeroInput. The caller owns
+// the returned values.
+func (g *onnxGraph) runNamed(feeds map[string]onnx.Value, seqLen int, cfg *Config) (map[string]onnx.Value, error) {
+	inputs := make([]onnx.Value, len(g.inputNames))
+	var owned []onnx.Value
+	defer func() {
+		for _, v := range owned {
+			v.Destroy()
+		}
+	}()
+	for i, name := range g.inputNames {
+		if v, ok := feeds[name]; ok {
+			inputs[i] = v
+			continue
+		}
+		v, err := g.zeroInput(name, seqLen, cfg)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = v
+		owned = append(owned, v)
+	}
+
+	outputs := make([]onnx.Value, len(g.outputNames))
+	if err := g.run(inputs, outputs); err != nil {
+		return nil, fmt.Errorf("onnx Run: %w", err)
+	}
+	res := make(map[string]onnx.Value, len(outputs))
+	for i, name := range g.outputNames {
+		if outputs[i] != nil {
+			res[name] = outputs[i]
+		}
+	}
+	return res, nil
+}
+
+// destroyValues releases every value in vs.
+func destroyValues(vs map[string]onnx.Value) {
+	for _, v := range vs {
+		if v != nil {
+			v.Destroy()
+		}
+	}
+}
+
+func (g *onnxGraph) hasInput(name string) bool {
+	_, ok := g.inputInfo[name]
+	return ok
+}
+
+func (g *onnxGraph) hasOutput(name string) bool {
+	for _, n := range g.outputNames {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// floatInput builds a tensor for the float input name in the element type
+// the graph declares, so fp16 exports get half-precision data.
+func (g *onnxGraph) floatInput(name string, data []float32, shape []int64) (onnx.Value, error) {
+	return tensorFromFloat32sAs(g.inputInfo[name].DataType, data, shape)
+}
+
+// zeroInput builds a placeholder for an input the caller does not drive:
+// empty caches on prefill and zeroed optional tensors. cfg (may be nil) fills
+// in symbolic head dimensions of KV caches.
+func (g *onnxGraph) zeroInput(name string, seqLen int, cfg *Config) (onnx.Value, error) {
+	info, ok := g.inputInfo[name]
+	if !ok {
+		return nil, fmt.Errorf("Generate: unsupported input name %q", name)
+	}
+	isCache := isCacheInput(name) && name != "use_cache_branch"
+	shape := make([]int64, len(info.Dimensions))
+	for i, d := range info.Dimensions {
+		if d <= 0 {
+			if i == 0 {
+				shape[i] = 1 // batch dim must be >=1
+			} else if isCache {
+				shape[i] = 0 // allow empty cache length
+			} else {
+				shape[i] = 1
+			}
+			// For non-cache, try to use seqLen if dimension is undefined and position-like
+			if !isCache && i == len(info.Dimensions)-1 && seqLen > 0 {
+				shape[i] = int64(seqLen)
+			}
+		} else {
+			shape[i] = d
+		}
+	}
+
+	// Symbolic KV dims [batch, kv_heads, past_len, head_dim]: take heads and
+	// head size from config so the empty cache still has a valid shape.
+	if isCache && len(shape) == 4 && cfg != nil {
+		heads := cfg.NumKeyValueHeads()
+		if heads == 0 {
+			heads = cfg.NumAttentionHeads()
+		}
+		if info.Dimensions[1] <= 0 && heads > 0 {
+			shape[1] = int64(heads)
+		}
+		if info.Dimensions[3] <= 0 && cfg.HeadDim() > 0 {
+			shape[3] = int64(cfg.HeadDim())
+		}
+	}
+
+	return zeroTensor(info.DataType, shape)
+}
+
+// isCacheInput reports whether an input carries recurrent state (KV or conv
+// cache) that a previous step's present.* output can fill.
+func isCacheInput(name string) bool {
+	return strings.Contains(name, "past") || strings.Contains(name, "cache")
+}
+
+// presentNameCandidates lists output names that conventionally carry the next
+// value of a cache input across the common exporters:
+//
+//	past_key_values.0.key -> present.0.key (Optimum / transformers.js)
+//	past_conv.0           -> present_conv.0 (LFM2)
+func presentNameCandidates(pastName string) []string {
+	return []string{
+		strings.Replace(pastName, "past_key_values", "present", 1),
+		strings.Replace(pastName, "past", "present", 1),
+		"present." + pastName,
+	}
+}
+
+// presentNameFor returns the output of g that feeds pastName on the next step.
+func (g *onnxGraph) presentNameFor(pastName string) (string, bool) {
+	for _, cand := range presentNameCandidates(pastName) {
+		if cand != pastName && g.hasOutput(cand) {
+			return cand, true
+		}
+	}
+	return "", false
+}
+
+// supportsKVCache reports whether every cache input of g has a matching
+// present output, i.e. the graph can be stepped one token at a time.
+func (g *onnxGraph) supportsKVCache() bool {
+	found := false
+	for _, name := range g.inputNames {
+		if name == "use_cache_branch" || !isCacheInput(name) {
+			continue
+		}
+		if _, ok := g.presentNameFor(name); !ok {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// decoderLayout describes how a decoder export is split into ONNX files.
+type decoderLayout int
+
+const (
+	// onnx/model*.onnx: one graph, fed empty past tensors on every call.
+	decoderLayoutSingle decoderLayout = iota
+
+	// onnx/decoder_model_merged*.onnx: one graph with a boolean
+	// use_cache_branch input selecting the prefill or decode subgraph.
+	decoderLayoutMerged
+
+	// onnx/decoder_model*.onnx for prefill plus
+	// onnx/decoder_with_past_model*.onnx for decode steps.
+	decoderLayoutSplit
+)
+
+func (l decoderLayout) String() string {
+	switch l {
+	case decoderLayoutMerged:
+		return "merged"
+	case decoderLayoutSplit:
+		return "split"
+	default:
+		return "single"
+	}
+}
+
+// decoderFiles is the resolved set of local ONNX files for a decoder.
+type decoderFiles struct {
+	layout       decoderLayout
+	decoderPath  string
+	withPastPath string // decoderLayoutSplit only
+}
+
+// onnxDtypeSuffix maps a dtype option to the file suffix used by
+// transformers.js-style exports (model_q4.onnx, decoder_model_merged_fp16.onnx).
+func onnxDtypeSuffix(dtype string) string {
+	switch dtype {
+	case "q4", "fp16", "q4f16", "int8", "uint8", "bnb4":
+		return "_" + dtype
+	case "q8", "quantized":
+		return "_quantized"
+	default:
+		return ""
+	}
+}
+
+// resolveDecoderFiles finds the decoder export for dtype under onnx/ with the
+// given base names, trying in order <base>, <merged base> and the split pair.
+// External *.onnx_data files are fetched alongside when present.
+func resolveDecoderFiles(modelID, dtype, single, merged, split, withPast string) (decoderFiles, error) {
+	suffix := onnxDtypeSuffix(dtype)
+	name := func(base string) string { return "onnx/" + base + suffix + ".onnx" }
+
+	fetch := func(filename string) (string, bool, error) {
+		return fetchOptionalONNX(modelID, filename)
+	}
+
+	var tried []string
+	for _, c := range []struct {
+		base   string
+		layout decoderLayout
+	}{
+		{single, decoderLayoutSingle},
+		{merged, decoderLayoutMerged},
+	} {
+		if c.base == "" {
+			continue
+		}
+		tried = append(tried, name(c.base))
+		p, ok, err := fetch(name(c.base))
+		if err != nil {
+			return decoderFiles{}, err
+		}
+		if ok {
+			return decoderFiles{layout: c.layout, decoderPath: p}, nil
+		}
+	}
+
+	if split != "" && withPast != "" {
+		tried = append(tried, name(split)+" + "+name(withPast))
+		p, ok, err := fetch(name(split))
+		if err != nil {
+			return decoderFiles{}, err
+		}
+		if ok {
+			pp, ok, err := fetch(name(withPast))
+			if err != nil {
+				return decoderFiles{}, err
+			}
+			if ok {
+				return decoderFiles{layout: decoderLayoutSplit, decoderPath: p, withPastPath: pp}, nil
+			}
+		}
+	}
+
+	return decoderFiles{}, fmt.Errorf("no ONNX decoder for dtype %q in %s (tried %s)", dtype, modelID, strings.Join(tried, ", "))
+}
+
+// fetchOptionalONNX downloads an ONNX file if the repo has it, plus its
+// external *.onnx_data file when present. ok is false on 404.
+func fetchOptionalONNX(modelID, filename string) (path string, ok bool, err error) {
+	paths, err := HFHubEnsureOptionalFiles(modelID, []string{filename})
+	if err != nil {
+		return "", false, err
+	}
+	path, ok = paths[filename]
+	if ok {
+		// Download external data files if present (best effort).
+		_, _ = HFHubEnsureOptionalFiles(modelID, []string{filename + "_data"})
+	}
+	return path, ok, nil
+}
@@ -0,0 +1,452 @@
+package transformers
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// PipelineOptions is the typed form of the options map Pipeline takes.
+// Zero values keep the defaults. Task options without a field here
+// ("pooling", "hypothesis_template", "top_k" defaults, ...) go in Extra under
+// their HF names and are checked like the map form.
+type PipelineOptions struct {
+	// Dtype picks the ONNX weights: "fp32", "fp16", "q4", "q4f16", "q8",
+	// "int8", "uint8" or "bnb4". Empty is q4 for decoders, fp32 for encoders.
+	Dtype string
+
+	IntraOpNumThreads      int
+	InterOpNumThreads      int
+	GraphOptimizationLevel string // "disable", "basic", "extended" or "all"
+
+	// LocalFilesOnly loads the model from the cache without touching the
+	// Hub. A model ID that is a local directory never needs it.
+	LocalFilesOnly bool
+
+	// Completion sends []ChatMessage inputs through plain-text completion
+	// mode when true; string prompts always use it.
+	Completion *bool
+
+	Extra map[string]any
+}
+
+// CallOptions is the typed form of the per-call options map of a Generator,
+// covering generation. Other task options go in Extra.
+type CallOptions struct {
+	MaxNewTokens int
+	DoSample     bool
+	Stop         []string
+	Streamer     func(PipelineStreamEvent) bool
+
+	// ReturnFullText, AddSpecialTokens and Completion are nil for the
+	// task default.
+	ReturnFullText   *bool
+	AddSpecialTokens *bool
+	Completion       *bool
+
+	Extra map[string]any
+}
+
+// PipelineWithOptions is Pipeline with typed options.
+func PipelineWithOptions(task, modelID string, opts PipelineOptions) (Generator, error) {
+	h, err := NewGeneratorWithOptions(task, modelID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return h.Generator(), nil
+}
+
+// Call runs g with typed call options.
+func (g Generator) Call(inputs any, opts CallOptions) ([]map[string]any, error) {
+	m, err := opts.Map()
+	if err != nil {
+		return nil, err
+	}
+	return g(inputs, m)
+}
+
+// ParsePipelineOptions checks an options map and converts it to its typed
+// form. Unknown keys, call-only keys and values of the wrong type are
+// errors; integers of any Go type are accepted.
+func ParsePipelineOptions(m map[string]any) (PipelineOptions, error) {
+	norm, err := normalizeOptions(m, scopePipeline)
+	if err != nil {
+		return PipelineOptions{}, err
+	}
+	var o PipelineOptions
+	o.Dtype, _ = norm["dtype"].(string)
+	o.IntraOpNumThreads, _ = norm["intra_op_num_threads"].(int)
+	o.InterOpNumThreads, _ = norm["inter_op_num_threads"].(int)
+	o.GraphOptimizationLevel, _ = norm["graph_optimization_level"].(string)
+	o.LocalFilesOnly, _ = norm["local_files_only"].(bool)
+	o.Completion = boolPtr(norm, "completion")
+	o.Extra = extraOptions(norm,
+		"dtype", "intra_op_num_threads", "inter_op_num_threads",
+		"graph_optimization_level", "local_files_only", "completion")
+	return o, nil
+}
+
+// ParseCallOptions is ParsePipelineOptions for per-call options.
+func ParseCallOptions(m map[string]any) (CallOptions, error) {
+	norm, err := normalizeOptions(m, scopeCall)
+	if err != nil {
+		return CallOptions{}, err
+	}
+	var o CallOptions
+	o.MaxNewTokens, _ = norm["max_new_tokens"].(int)
+	o.DoSample, _ = norm["do_sample"].(bool)
+	o.Stop = parseStopSequences(norm["stop"])
+	o.Streamer, _ = norm["streamer"].(func(PipelineStreamEvent) bool)
+	o.ReturnFullText = boolPtr(norm, "return_full_text")
+	o.AddSpecialTokens = boolPtr(norm, "add_special_tokens")
+	o.Completion = boolPtr(norm, "completion")
+	o.Extra = extraOptions(norm,
+		"max_new_tokens", "do_sample", "stop", "streamer",
+		"return_full_text", "add_special_tokens", "completion")
+	return o, nil
+}
+
+// Map returns the validated map form of o, as the pipelines read it.
+func (o PipelineOptions) Map() (map[string]any, error) {
+	m := map[string]any{}
+	setIf(m, "dtype", o.Dtype, o.Dtype != "")
+	setIf(m, "intra_op_num_threads", o.IntraOpNumThreads, o.IntraOpNumThreads != 0)
+	setIf(m, "inter_op_num_threads", o.InterOpNumThreads, o.InterOpNumThreads != 0)
+	setIf(m, "graph_optimization_level", o.GraphOptimizationLevel, o.GraphOptimizationLevel != "")
+	setIf(m, "local_files_only", o.LocalFilesOnly, o.LocalFilesOnly)
+	if o.Completion != nil {
+		m["completion"] = *o.Completion
+	}
+	if err := mergeExtra(m, o.Extra); err != nil {
+		return nil, err
+	}
+	return normalizeOptions(m, scopePipeline)
+}
+
+// Map returns the validated map form of o, as the pipelines read it.
+func (o CallOptions) Map() (map[string]any, error) {
+	m := map[string]any{}
+	setIf(m, "max_new_tokens", o.MaxNewTokens, o.MaxNewTokens != 0)
+	setIf(m, "do_sample", o.DoSample, o.DoSample)
+	setIf(m, "stop", o.Stop, len(o.Stop) > 0)
+	if o.Streamer != nil {
+		m["streamer"] = o.Streamer
+	}
+	for key, p := range map[string]*bool{
+		"return_full_text":   o.ReturnFullText,
+		"add_special_tokens": o.AddSpecialTokens,
+		"completion":         o.Completion,
+	} {
+		if p != nil {
+			m[key] = *p
+		}
+	}
+	if err := mergeExtra(m, o.Extra); err != nil {
+		return nil, err
+	}
+	return normalizeOptions(m, scopeCall)
+}
+
+// validatingGenerator checks and normalizes every call's options map
+// before gen sees it.
+func validatingGenerator(gen Generator) Generator {
+	return func(inputs any, callOptions map[string]any) ([]map[string]any, error) {
+		norm, err := normalizeOptions(callOptions, scopeCall)
+		if err != nil {
+			return nil, err
+		}
+		return gen(inputs, norm)
+	}
+}
+
+type optionKind int
+
+const (
+	optInt optionKind = iota
+	optFloat
+	optBool
+	optString
+	optStrings // string, []string or []any of strings; []any becomes []string
+	optStreamer
+	optAny // task-specific shapes the pipeline checks itself
+)
+
+func (k optionKind) String() string {
+	return [...]string{"an integer", "a number", "a bool", "a string", "a string list", "a func(PipelineStreamEvent) bool", "any value"}[k]
+}
+
+type optionScope uint8
+
+const (
+	scopePipeline optionScope = 1 << iota // Pipeline(..., options)
+	scopeCall                             // generator(inputs, options)
+
+	scopeBoth = scopePipeline | scopeCall
+)
+
+type optionSpec struct {
+	kind  optionKind
+	scope optionScope
+	check func(v any) error // optional, after normalization
+}
+
+// knownOptions lists every option key a pipeline reads. Pipeline options
+// double as defaults for most call options, hence scopeBoth.
+var knownOptions = map[string]optionSpec{
+	// Model loading.
+	"dtype":                    {optString, scopePipeline, oneOf("", "fp32", "fp16", "q4", "q4f16", "q8", "quantized", "int8", "uint8", "bnb4")},
+	"intra_op_num_threads":     {optInt, scopePipeline, nonNegative},
+	"inter_op_num_threads":     {optInt, scopePipeline, nonNegative},
+	"graph_optimization_level": {optString, scopePipeline, oneOf("", "all", "extended", "basic", "disable", "none")},
+	"local_files_only":         {optBool, scopePipeline, nil},
+
+	// Generation.
+	"max_new_tokens":     {optInt, scopeCall, nonNegative},
+	"do_sample":          {optBool, scopeCall, nil},
+	"stop":               {optStrings, scopeCall, nil},
+	"streamer":           {optStreamer, scopeCall, nil},
+	"return_full_text":   {optBool, scopeBoth, nil},
+	"return_timestamps":  {optBool, scopeCall, nil},
+	"add_special_tokens": {optBool, scopeBoth, nil},
+	"completion":         {optBool, scopeBoth, nil},
+
+	// Task options.
+	"aggregation_strategy":     {optString, scopeBoth, nil},
+	"batch_size":               {optInt, scopeBoth, nil},
+	"candidate_labels":         {optStrings, scopeBoth, nil},
+	"chunk_length_s":           {optFloat, scopeBoth, nonNegative},
+	"context":                  {optString, scopeBoth, nil},
+	"doc_stride":               {optInt, scopeBoth, nonNegative},
+	"documents":                {optAny, scopeBoth, nil},
+	"function_to_apply":        {optString, scopeBoth, oneOf("", "none", "sigmoid", "softmax")},
+	"handle_impossible_answer": {optBool, scopeBoth, nil},
+	"hypothesis_template":      {optString, scopeBoth, nil},
+	"ignore_labels":            {optStrings, scopeBoth, nil},
+	"language":                 {optString, scopeBoth, nil},
+	"logit_bias":               {optFloat, scopePipeline, nil},
+	"logit_scale":              {optFloat, scopePipeline, nil},
+	"max_answer_len":           {optInt, scopeBoth, nonNegative},
+	"max_length":               {optInt, scopeBoth, nonNegative},
+	"max_seq_len":              {optInt, scopeBoth, nonNegative},
+	"multi_label":              {optBool, scopeBoth, nil},
+	"nms_threshold":            {optFloat, scopeBoth, nonNegative},
+	"normalize":                {optBool, scopeBoth, nil},
+	"pool":                     {optBool, scopeBoth, nil},
+	"pooling":                  {optString, scopeBoth, nil},
+	"prefix":                   {optString, scopeBoth, nil},
+	"src_lang":                 {optString, scopeBoth, nil},
+	"stride_length_s":          {optFloat, scopeBoth, nonNegative},
+	"targets":                  {optStrings, scopeBoth, nil},
+	"task":                     {optString, scopeBoth, nil},
+	"tgt_lang":                 {optString, scopeBoth, nil},
+	"threshold":                {optFloat, scopeBoth, nil},
+	"top_k":                    {optInt, scopeBoth, nil},
+	"top_n":                    {optInt, scopeBoth, nil},
+	"truncate_dim":             {optInt, scopeBoth, nonNegative},
+}
+
+// normalizeOptions validates m against knownOptions and returns a copy with
+// canonical value types: int for integers, float64 for numbers and
+// []string for []any string lists. A nil m gives an empty map.
+func normalizeOptions(m map[string]any, scope optionScope) (map[string]any, error) {
+	out := make(map[string]any, len(m))
+	for _, key := range sortedKeys(m) {
+		spec, ok := knownOptions[key]
+		if !ok && customOption(key) {
+			spec, ok = optionSpec{optAny, scopeBoth, nil}, true
+		}
+		if !ok {
+			if s := closestOption(key); s != "" {
+				return nil, fmt.Errorf("unknown option %q (did you mean %q?)", key, s)
+			}
+			return nil, fmt.Errorf("unknown option %q", key)
+		}
+		if spec.scope&scope == 0 {
+			if scope == scopeCall {
+				return nil, fmt.Errorf("option %q applies when creating the pipeline, not per call", key)
+			}
+			return nil, fmt.Errorf("option %q is a call option, not a pipeline option", key)
+		}
+		v, err := coerceOption(spec.kind, m[key])
+		if err != nil {
+			return nil, fmt.Errorf("option %q: %w", key, err)
+		}
+		if spec.check != nil {
+			if err := spec.check(v); err != nil {
+				return nil, fmt.Errorf("option %q: %w", key, err)
+			}
+		}
+		out[key] = v
+	}
+	return out, nil
+}
+
+func coerceOption(kind optionKind, v any) (any, error) {
+	bad := fmt.Errorf("want %s, got %T", kind, v)
+	switch kind {
+	case optInt:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return int(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if rv.Uint() > math.MaxInt {
+				return nil, fmt.Errorf("%d out of range", rv.Uint())
+			}
+			return int(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			// JSON-decoded numbers.
+			if f := rv.Float(); f == math.Trunc(f) && math.Abs(f) <= math.MaxInt32 {
+				return int(f), nil
+			}
+			return nil, fmt.Errorf("want an integer, got %v", v)
+		}
+	case optFloat:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(rv.Int()), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return float64(rv.Uint()), nil
+		case reflect.Float32, reflect.Float64:
+			return rv.Float(), nil
+		}
+	case optBool:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case optString:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case optStrings:
+		switch t := v.(type) {
+		case string, []string:
+			return t, nil
+		case []any:
+			out := make([]string, len(t))
+			for i, x := range t {
+				s, ok := x.(string)
+				if !ok {
+					return nil, fmt.Errorf("want strings, got %T at index %d", x, i)
+				}
+				out[i] = s
+			}
+			return out, nil
+		}
+	case optStreamer:
+		if fn, ok := v.(func(PipelineStreamEvent) bool); ok {
+			return fn, nil
+		}
+	case optAny:
+		return v, nil
+	}
+	return nil, bad
+}
+
+func nonNegative(v any) error {
+	switch t := v.(type) {
+	case int:
+		if t < 0 {
+			return fmt.Errorf("must be >= 0, got %d", t)
+		}
+	case float64:
+		if t < 0 || math.IsNaN(t) {
+			return fmt.Errorf("must be >= 0, got %v", t)
+		}
+	}
+	return nil
+}
+
+func oneOf(values ...string) func(any) error {
+	return func(v any) error {
+		s, _ := v.(string)
+		for _, x := range values {
+			if strings.EqualFold(s, x) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q is not one of %q", s, values[1:])
+	}
+}
+
+// closestOption suggests a known key within edit distance 2 of key.
+func closestOption(key string) string {
+	best, bestDist := "", 3
+	for _, k := range append(sortedKeys(knownOptions), customOptionKeys()...) {
+		if d := editDistance(key, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func setIf(m map[string]any, key string, v any, ok bool) {
+	if ok {
+		m[key] = v
+	}
+}
+
+// mergeExtra copies extra into m; a key already set by a typed field is an
+// error rather than a silent override.
+func mergeExtra(m, extra map[string]any) error {
+	for k, v := range extra {
+		if _, ok := m[k]; ok {
+			return fmt.Errorf("option %q is set both as a field and in Extra", k)
+		}
+		m[k] = v
+	}
+	return nil
+}
+
+func boolPtr(m map[string]any, key string) *bool {
+	if b, ok := m[key].(bool); ok {
+		return &b
+	}
+	return nil
+}
+
+func extraOptions(m map[string]any, typed ...string) map[string]any {
+	var extra map[string]any
+	for k, v := range m {
+		if !slices.Contains(typed, k) {
+			if extra == nil {
+				extra = map[string]any{}
+			}
+			extra[k] = v
+		}
+	}
+	return extra
+}
@@ -0,0 +1,116 @@
+package transformers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeOptions(t *testing.T) {
+	streamer := func(PipelineStreamEvent) bool { return true }
+	tests := []struct {
+		name    string
+		in      map[string]any
+		scope   optionScope
+		want    map[string]any
+		wantErr string
+	}{
+		{"nil", nil, scopeCall, map[string]any{}, ""},
+		{"int kinds", map[string]any{"max_new_tokens": int64(8), "top_k": uint8(3)}, scopeCall,
+			map[string]any{"max_new_tokens": 8, "top_k": 3}, ""},
+		{"json number", map[string]any{"max_new_tokens": 16.0}, scopeCall, map[string]any{"max_new_tokens": 16}, ""},
+		{"fractional int", map[string]any{"max_new_tokens": 1.5}, scopeCall, nil, "want an integer"},
+		{"int as float", map[string]any{"threshold": 1}, scopeCall, map[string]any{"threshold": 1.0}, ""},
+		{"string list", map[string]any{"stop": []any{"a", "b"}}, scopeCall, map[string]any{"stop": []string{"a", "b"}}, ""},
+		{"comma string kept", map[string]any{"candidate_labels": "a,b"}, scopeCall, map[string]any{"candidate_labels": "a,b"}, ""},
+		{"bad list", map[string]any{"stop": []any{"a", 1}}, scopeCall, nil, "index 1"},
+		{"streamer", map[string]any{"streamer": streamer}, scopeCall, nil, ""},
+		{"wrong type", map[string]any{"do_sample": "yes"}, scopeCall, nil, `option "do_sample": want a bool, got string`},
+		{"typo", map[string]any{"max_new_token": 8}, scopeCall, nil, `did you mean "max_new_tokens"`},
+		{"unknown", map[string]any{"zzzzzzzz": 1}, scopeCall, nil, `unknown option "zzzzzzzz"`},
+		{"pipeline-only per call", map[string]any{"dtype": "q4"}, scopeCall, nil, "applies when creating the pipeline"},
+		{"call-only at load", map[string]any{"max_new_tokens": 8}, scopePipeline, nil, "is a call option"},
+		{"oneOf", map[string]any{"dtype": "q5"}, scopePipeline, nil, "is not one of"},
+		{"oneOf case", map[string]any{"dtype": "Q4"}, scopePipeline, map[string]any{"dtype": "Q4"}, ""},
+		{"negative", map[string]any{"max_new_tokens": -1}, scopeCall, nil, "must be >= 0"},
+		{"both scopes", map[string]any{"top_k": 2}, scopePipeline, map[string]any{"top_k": 2}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeOptions(tt.in, tt.scope)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClosestOption(t *testing.T) {
+	for key, want := range map[string]string{
+		"max_new_tokns":   "max_new_tokens",
+		"dtyp":            "dtype",
+		"topk":            "top_k",
+		"completly_wrong": "",
+	} {
+		if got := closestOption(key); got != want {
+			t.Errorf("closestOption(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{{"", "", 0}, {"abc", "", 3}, {"kitten", "sitting", 3}, {"top_k", "top_n", 1}} {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPipelineOptionsRoundTrip(t *testing.T) {
+	yes := true
+	o := PipelineOptions{Dtype: "fp16", IntraOpNumThreads: 2, LocalFilesOnly: true, Completion: &yes, Extra: map[string]any{"top_k": 3}}
+	m, err := o.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := ParsePipelineOptions(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, o) {
+		t.Fatalf("round trip: got %+v, want %+v", back, o)
+	}
+
+	o.Extra = map[string]any{"dtype": "q4"}
+	if _, err := o.Map(); err == nil {
+		t.Fatal("a key set both as a field and in Extra must be an error")
+	}
+}
+
+func TestCallOptionsRoundTrip(t *testing.T) {
+	no := false
+	o := CallOptions{MaxNewTokens: 12, Stop: []string{"\n"}, ReturnFullText: &no}
+	m, err := o.Map()
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := ParseCallOptions(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, o) {
+		t.Fatalf("round trip: got %+v, want %+v", back, o)
+	}
+}
@@ -1,6 +1,7 @@
 package transformers
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -13,32 +14,86 @@ import (
 // a small-p alias in your own code if you dot-import the package:
 //
 //	var pipeline = transformers.Pipeline
+//
+// The options map is the HF-style form of PipelineOptions: keys are checked
+// by ParsePipelineOptions, so a misspelled key or a wrongly typed value is
+// an error, and the returned generator checks its call options the same way.
+//
+// The generator's ONNX sessions are never freed (dropping it logs a leak
+// warning once it is garbage collected); use NewGenerator for a handle
+// that can free them.
 func Pipeline(
 	task string,
 	modelID string,
 	options map[string]any,
 ) (Generator, error) {
-	return pipelineImpl(task, modelID, options)
+	opts, err := ParsePipelineOptions(options)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline: %w", err)
+	}
+	return PipelineWithOptions(task, modelID, opts)
 }
 
 // pipelineImpl is the internal implementation with a smaller name.
-// It mirrors the JS/Python `pipeline(...)` signature conceptually.
+// It mirrors the JS/Python `pipeline(...)` signature conceptually: tasks
+// come from the registry (see RegisterTask), and an empty modelID picks the
+// task's default model. modelID may be a local model directory, and
+// local_files_only keeps a Hub model ID to the cache.
 func pipelineImpl(
 	task string,
 	modelID string,
 	options map[string]any,
 ) (Generator, error) {
-	if task != "text-generation" {
-		return nil, fmt.Errorf("pipeline: task %q not implemented", task)
-	}
-
 	if options == nil {
 		options = map[string]any{}
 	}
+
+	t, name, ok := lookupTask(task)
+	if !ok {
+		return nil, fmt.Errorf("pipeline: task %q not implemented", task)
+	}
+	if modelID == "" {
+		if t.opts.DefaultModel == "" {
+			return nil, fmt.Errorf("pipeline: task %q has no default model; pass a model ID", task)
+		}
+		modelID = t.opts.DefaultModel
+	}
+	if localOnly, _ := options["local_files_only"].(bool); localOnly {
+		dir, err := cachedModelDir(modelID)
+		if err != nil {
+			return nil, fmt.Errorf("pipeline: %w", err)
+		}
+		modelID = dir
+	}
+	return t.factory(name, modelID, options)
+}
+
+// dtypeOption returns the "dtype" pipeline option, defaulting to q4.
+func dtypeOption(options map[string]any) string {
 	dtype, _ := options["dtype"].(string)
 	if dtype == "" {
 		dtype = "q4"
 	}
+	return dtype
+}
+
+// modelLoadOptionsFrom reads the session-related pipeline options.
+func modelLoadOptionsFrom(options map[string]any) ModelLoadOptions {
+	loadOpts := ModelLoadOptions{
+		IntraOpNumThreads: intOption(options, "intra_op_num_threads", 0),
+		InterOpNumThreads: intOption(options, "inter_op_num_threads", 0),
+	}
+	loadOpts.GraphOptimizationLevel, _ = options["graph_optimization_level"].(string)
+	loadOpts.tracker, _ = options[trackerOptionKey].(*resourceTracker)
+	return loadOpts
+}
+
+// newTextGenerationPipeline builds the "text-generation" task.
+func newTextGenerationPipeline(
+	modelID string,
+	options map[string]any,
+) (Generator, error) {
+	dtype := dtypeOption(options)
 
 	// 1. Config
 	config, err := AutoConfig.FromPretrained(modelID)
@@ -46,6 +101,11 @@ func pipelineImpl(
 		return nil, fmt.Errorf("load config: %w", err)
 	}
 
+	// Vision-language models take the same chat input.
+	if isImageTextToText(config) {
+		return newImageTextToTextPipeline(modelID, options)
+	}
+
 	// 2. Tokenizer
 	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
 	if err != nil {
@@ -55,48 +115,56 @@ func pipelineImpl(
 	// Prefer auto IO discovery to match model-defined inputs/outputs.
 	ioPreset := IOPresetAuto
 
+	loadOpts := modelLoadOptionsFrom(options)
+
 	// 3. Model
-	model, err := AutoModelForCausalLM.FromPretrained(
+	model, err := AutoModelForCausalLM.FromPretrainedWithOptions(
 		modelID,
 		config,
 		dtype,
 		ioPreset,
+		loadOpts,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("load model: %w", err)
 	}
 
-	// 4. Closure = generator(messages, options)
+	// 4. Closure = generator(messages or prompts, options)
 	generator := func(
-		messages []ChatMessage,
+		inputs any,
 		callOptions map[string]any,
 	) ([]map[string]any, error) {
 		if callOptions == nil {
 			callOptions = map[string]any{}
 		}
+		messages, isChat := inputs.([]ChatMessage)
+		if isChat && len(chatImages(messages)) > 0 {
+			return nil, fmt.Errorf("text-generation: %s has no vision encoder for image parts", modelID)
+		}
 
-		// Default to a short cap to avoid run-on generations.
-		maxNewTokens := 32
-		if v, ok := callOptions["max_new_tokens"]; ok {
-			switch t := v.(type) {
-			case int:
-				maxNewTokens = t
-			case float64:
-				maxNewTokens = int(t)
+		switch textGenerationMode(isChat, tokenizer.HasChatTemplate(), callOptions, options) {
+		case promptCompletion:
+			prompts, err := completionPrompts(inputs)
+			if err != nil {
+				return nil, err
 			}
-		}
-		doSample := false
-		if v, ok := callOptions["do_sample"]; ok {
-			if b, ok := v.(bool); ok {
-				doSample = b
+			fullText := boolOptionOr(callOptions, options, "return_full_text", true)
+			return generateCompletions(model, tokenizer, config, prompts, fullText, callOptions, options)
+		case promptRawChat:
+			prompts, err := completionPrompts(inputs)
+			if err != nil {
+				return nil, err
 			}
-		}
-
-		var streamerFn func(PipelineStreamEvent) bool
-		if v, ok := callOptions["streamer"]; ok {
-			if fn, ok := v.(func(PipelineStreamEvent) bool); ok {
-				streamerFn = fn
+			out, err := generateCompletions(model, tokenizer, config, prompts, false, callOptions, options)
+			if err != nil {
+				return nil, err
+			}
+			fullText := boolOptionOr(callOptions, options, "return_full_text", false)
+			for _, res := range out {
+				reply, _ := res["generated_text"].(string)
+				res["generated_text"] = chatTurns(messages, reply, fullText)
 			}
+			return out, nil
 		}
 
 		// 4a. Encode chat
@@ -106,20 +174,7 @@ func pipelineImpl(
 		}
 
 		// 4b. Generate token IDs
-		stopSeqs := parseStopSequences(callOptions["stop"])
-		if len(stopSeqs) == 0 && len(config.StopStrings()) > 0 {
-			stopSeqs = config.StopStrings()
-		}
-		if len(stopSeqs) == 0 {
-			stopSeqs = []string{"\nUser:", "\nuser:", "\nAssistant:", "\nassistant:"}
-		}
-
-		genOpts := GenerationOptions{
-			MaxNewTokens:  maxNewTokens,
-			DoSample:      doSample,
-			Streamer:      streamerFn,
-			StopSequences: stopSeqs,
-		}
+		genOpts := chatGenerationOptions(config, callOptions)
 		generatedBatch, err := model.Generate(tokenizer, inputIDsBatch, attnBatch, genOpts)
 		if err != nil {
 			return nil, fmt.Errorf("Generate: %w", err)
@@ -130,23 +185,18 @@ func pipelineImpl(
 		if err != nil {
 			return nil, fmt.Errorf("BatchDecode: %w", err)
 		}
+		raw := append([]string(nil), texts...)
 		for i, txt := range texts {
-			texts[i] = truncateAtStops(txt, stopSeqs)
+			texts[i] = truncateAtStops(txt, genOpts.StopSequences)
 		}
 
 		// Wrap into HF-style output:
 		// [{ "generated_text": [ { "role": "assistant", "content": text } ] }]
+		fullText := boolOptionOr(callOptions, options, "return_full_text", false)
 		out := make([]map[string]any, len(texts))
 		for i, txt := range texts {
-			trimmed := strings.TrimSpace(txt)
-			out[i] = map[string]any{
-				"generated_text": []map[string]any{
-					{
-						"role":    "assistant",
-						"content": trimmed,
-					},
-				},
-			}
+			out[i] = map[string]any{"generated_text": chatTurns(messages, txt, fullText)}
+			addGenerationStats(out[i], len(inputIDsBatch[0]), generatedBatch[i], raw[i], genOpts)
 		}
 		return out, nil
 	}
@@ -154,6 +204,269 @@ func pipelineImpl(
 	return generator, nil
 }
 
+// addGenerationStats adds the non-HF entries of a generation result:
+// "finish_reason" ("length" when max_new_tokens ran out, else "stop"),
+// "token_ids" and OpenAI-style "usage". text is the decoded output before
+// stop-string truncation.
+func addGenerationStats(m map[string]any, promptLen int, generated []int64, text string, opts GenerationOptions) {
+	reason := "stop"
+	if len(generated) >= opts.MaxNewTokens && cutAtStops(text, opts.StopSequences) == text {
+		reason = "length"
+	}
+	m["finish_reason"] = reason
+	m["token_ids"] = generated
+	m["usage"] = map[string]any{
+		"prompt_tokens":     promptLen,
+		"completion_tokens": len(generated),
+		"total_tokens":      promptLen + len(generated),
+	}
+}
+
+// promptMode is how text-generation prompts the model.
+type promptMode int
+
+const (
+	promptChat       promptMode = iota // chat template, chat output
+	promptRawChat                      // chat rendered as raw text, chat output
+	promptCompletion                   // raw text, string output
+)
+
+// textGenerationMode picks the prompt mode. Plain prompts and chats with
+// completion: true are completions. Completion-only checkpoints (no chat
+// template) get chats as raw text unless completion: false asks for the
+// default template; either way the output keeps the chat shape.
+func textGenerationMode(isChat, hasTemplate bool, callOptions, options map[string]any) promptMode {
+	completion, explicit := callOptions["completion"].(bool)
+	if !explicit {
+		completion, explicit = options["completion"].(bool)
+	}
+	switch {
+	case !isChat || completion:
+		return promptCompletion
+	case !hasTemplate && !explicit:
+		return promptRawChat
+	}
+	return promptChat
+}
+
+// chatTurns is the HF chat output: the assistant reply, after the input
+// messages when fullText (return_full_text) is set.
+func chatTurns(messages []ChatMessage, reply string, fullText bool) []map[string]any {
+	var turns []map[string]any
+	if fullText {
+		for _, m := range messages {
+			turns = append(turns, map[string]any{
+				"role":    string(m.Role),
+				"content": m.TextContent(),
+			})
+		}
+	}
+	return append(turns, map[string]any{
+		"role":    "assistant",
+		"content": strings.TrimSpace(reply),
+	})
+}
+
+// completionPrompts returns the raw prompts of completion mode: string
+// inputs as-is, or a chat flattened to its message texts, one per line.
+func completionPrompts(inputs any) ([]string, error) {
+	if messages, ok := inputs.([]ChatMessage); ok {
+		lines := make([]string, len(messages))
+		for i, m := range messages {
+			lines[i] = m.TextContent()
+		}
+		return []string{strings.Join(lines, "\n")}, nil
+	}
+	prompts, err := textInputs("text-generation", inputs)
+	if err != nil {
+		return nil, fmt.Errorf("text-generation: expected []ChatMessage, string or []string input, got %T", inputs)
+	}
+	return prompts, nil
+}
+
+// generateCompletions continues each prompt as plain text, HF-style:
+//
+//	[{ "generated_text": prompt + continuation }]
+//
+// Without fullText only the continuation is returned. add_special_tokens
+// defaults to true.
+func generateCompletions(
+	model *ModelForCausalLM,
+	tokenizer *Tokenizer,
+	config *Config,
+	prompts []string,
+	fullText bool,
+	callOptions, options map[string]any,
+) ([]map[string]any, error) {
+	addSpecial := boolOptionOr(callOptions, options, "add_special_tokens", true)
+	genOpts := generationOptions(config, callOptions)
+
+	out := make([]map[string]any, len(prompts))
+	for i, prompt := range prompts {
+		ids, err := tokenizer.Encode(prompt, addSpecial)
+		if err != nil {
+			return nil, fmt.Errorf("Encode: %w", err)
+		}
+		if len(ids) == 0 {
+			// Unconditional generation starts from BOS.
+			bos := config.BOS_TOKEN_ID()
+			if bos < 0 {
+				return nil, errors.New("text-generation: empty prompt and no bos_token_id")
+			}
+			ids = []int64{bos}
+		}
+		attn := make([]int64, len(ids))
+		for j := range attn {
+			attn[j] = 1
+		}
+		generated, err := model.Generate(tokenizer, [][]int64{ids}, [][]int64{attn}, genOpts)
+		if err != nil {
+			return nil, fmt.Errorf("Generate: %w", err)
+		}
+		text, err := tokenizer.Decode(generated[0])
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %w", err)
+		}
+		out[i] = map[string]any{}
+		addGenerationStats(out[i], len(ids), generated[0], text, genOpts)
+		text = cutAtStops(text, genOpts.StopSequences)
+		if fullText {
+			text = prompt + text
+		}
+		out[i]["generated_text"] = text
+	}
+	return out, nil
+}
+
+// chatGenerationOptions reads the generation call options shared by the
+// chat pipelines. Without stop strings from the call or the model, chats
+// stop at the next "User:"/"Assistant:" turn.
+func chatGenerationOptions(config *Config, callOptions map[string]any) GenerationOptions {
+	opts := generationOptions(config, callOptions)
+	if len(opts.StopSequences) == 0 {
+		opts.StopSequences = []string{"\nUser:", "\nuser:", "\nAssistant:", "\nassistant:"}
+	}
+	return opts
+}
+
+// generationOptions reads max_new_tokens, do_sample, streamer and stop.
+func generationOptions(config *Config, callOptions map[string]any) GenerationOptions {
+	// Default to a short cap to avoid run-on generations.
+	maxNewTokens := intOption(callOptions, "max_new_tokens", 32)
+	doSample := false
+	if v, ok := callOptions["do_sample"]; ok {
+		if b, ok := v.(bool); ok {
+			doSample = b
+		}
+	}
+
+	var streamerFn func(PipelineStreamEvent) bool
+	if v, ok := callOptions["streamer"]; ok {
+		if fn, ok := v.(func(PipelineStreamEvent) bool); ok {
+			streamerFn = fn
+		}
+	}
+
+	stopSeqs := parseStopSequences(callOptions["stop"])
+	if len(stopSeqs) == 0 && len(config.StopStrings()) > 0 {
+		stopSeqs = config.StopStrings()
+	}
+
+	return GenerationOptions{
+		MaxNewTokens:  maxNewTokens,
+		DoSample:      doSample,
+		Streamer:      streamerFn,
+		StopSequences: stopSeqs,
+	}
+}
+
+// textInputs accepts the single-string and batched forms of text inputs.
+func textInputs(task string, inputs any) ([]string, error) {
+	switch t := inputs.(type) {
+	case string:
+		return []string{t}, nil
+	case []string:
+		return t, nil
+	case []any:
+		out := make([]string, len(t))
+		for i, x := range t {
+			s, ok := x.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: expected string inputs, got %T", task, x)
+			}
+			out[i] = s
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s: expected string or []string input, got %T", task, inputs)
+}
+
+// stringOption reads a string option from the call options first, then the
+// pipeline options.
+func stringOption(callOptions, options map[string]any, key string) string {
+	if s, ok := callOptions[key].(string); ok && s != "" {
+		return s
+	}
+	s, _ := options[key].(string)
+	return s
+}
+
+// stringListOption reads a list-of-strings option (a string, []string or
+// []any of strings) from the call options first, then the pipeline options.
+// Empty strings are dropped. ok reports whether either map sets key, so an
+// explicit empty list can be told apart from a missing option.
+func stringListOption(callOptions, options map[string]any, key string) (list []string, ok bool) {
+	for _, o := range []map[string]any{callOptions, options} {
+		v, set := o[key]
+		if !set {
+			continue
+		}
+		switch t := v.(type) {
+		case string:
+			if t != "" {
+				list = append(list, t)
+			}
+		case []string:
+			for _, s := range t {
+				if s != "" {
+					list = append(list, s)
+				}
+			}
+		case []any:
+			for _, x := range t {
+				if s, isStr := x.(string); isStr && s != "" {
+					list = append(list, s)
+				}
+			}
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// boolOptionOr reads a bool option from the call options first, then the
+// pipeline options, and returns def when neither sets it.
+func boolOptionOr(callOptions, options map[string]any, key string, def bool) bool {
+	if v, ok := callOptions[key].(bool); ok {
+		return v
+	}
+	if v, ok := options[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
+// intOption reads an integer option, accepting JSON-decoded float64 too.
+func intOption(options map[string]any, key string, def int) int {
+	switch t := options[key].(type) {
+	case int:
+		return t
+	case float64:
+		return int(t)
+	}
+	return def
+}
+
 func parseStopSequences(v any) []string {
 	switch t := v.(type) {
 	case nil:
@@ -179,6 +492,12 @@ func parseStopSequences(v any) []string {
 }
 
 func truncateAtStops(s string, stops []string) string {
+	return strings.TrimSpace(cutAtStops(s, stops))
+}
+
+// cutAtStops drops everything from the first stop sequence on, keeping
+// whitespace (completions are appended to their prompt verbatim).
+func cutAtStops(s string, stops []string) string {
 	out := s
 	for _, stop := range stops {
 		if stop == "" {
@@ -188,5 +507,5 @@ func truncateAtStops(s string, stops []string) string {
 			out = out[:idx]
 		}
 	}
-	return strings.TrimSpace(out)
+	return out
 }
@@ -0,0 +1,203 @@
+package transformers
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// newASRPipeline builds the "automatic-speech-recognition" task for Whisper.
+// Inputs are WAV paths, WAV bytes, []float32 samples at the model rate,
+// *Audio, or {"raw": []float32, "sampling_rate": n}; see audioFromInput.
+//
+//	asr, _ := Pipeline("automatic-speech-recognition", "onnx-community/whisper-base", nil)
+//	out, _ := asr("speech.wav", map[string]any{"return_timestamps": true})
+//	// [{ "text": "...", "chunks": [{ "timestamp": [0, 2.5], "text": "..." }] }]
+//
+// Audio longer than one 30 s window, or any audio when chunk_length_s is set,
+// is transcribed in overlapping chunks; segments are kept from the chunk whose
+// unstrided core contains their midpoint.
+func newASRPipeline(modelID string, options map[string]any) (Generator, error) {
+	dtype := dtypeOption(options)
+
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	if config.ModelType() != "whisper" {
+		return nil, fmt.Errorf("automatic-speech-recognition: model_type %q is not supported (only whisper)", config.ModelType())
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	pp, err := loadPreprocessorConfig(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load preprocessor config: %w", err)
+	}
+	fe := newWhisperFeatureExtractor(pp)
+	model, err := AutoModelForSpeechSeq2Seq.FromPretrainedWithOptions(
+		modelID,
+		config,
+		dtype,
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		returnTimestamps, _ := callOptions["return_timestamps"].(bool)
+		chunkLen := floatOption(callOptions, options, "chunk_length_s", 0)
+		strideLen := floatOption(callOptions, options, "stride_length_s", chunkLen/6)
+
+		opts := WhisperOptions{
+			Language:     stringOption(callOptions, options, "language"),
+			Task:         stringOption(callOptions, options, "task"),
+			MaxNewTokens: intOption(callOptions, "max_new_tokens", 0),
+		}
+		if fn, ok := callOptions["streamer"].(func(PipelineStreamEvent) bool); ok {
+			opts.Streamer = fn
+		}
+
+		var out []map[string]any
+		for _, in := range audioInputs(inputs) {
+			audio, err := audioFromInput(in, fe.SamplingRate)
+			if err != nil {
+				return nil, err
+			}
+
+			windowSec := float64(fe.NSamples) / float64(fe.SamplingRate)
+			durSec := float64(len(audio.Samples)) / float64(fe.SamplingRate)
+			var segs []WhisperSegment
+			if chunkLen > 0 || durSec > windowSec {
+				cl := chunkLen
+				if cl <= 0 || cl > windowSec {
+					cl = windowSec
+				}
+				sl := strideLen
+				if chunkLen <= 0 {
+					sl = cl / 6
+				}
+				segs, err = transcribeChunked(model, tokenizer, fe, audio.Samples, cl, sl, opts)
+			} else {
+				opts.Timestamps = returnTimestamps
+				segs, err = transcribeWindow(model, tokenizer, fe, audio.Samples, 0, durSec, opts)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			texts := make([]string, len(segs))
+			chunks := make([]map[string]any, len(segs))
+			for i, s := range segs {
+				texts[i] = s.Text
+				chunks[i] = map[string]any{
+					"timestamp": []float64{roundCentis(s.Start), roundCentis(s.End)},
+					"text":      s.Text,
+				}
+			}
+			res := map[string]any{"text": strings.Join(texts, " ")}
+			if returnTimestamps {
+				res["chunks"] = chunks
+			}
+			out = append(out, res)
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// transcribeWindow decodes samples (at most one feature window) starting at
+// offset seconds.
+func transcribeWindow(
+	model *ModelForSpeechSeq2Seq,
+	tokenizer *Tokenizer,
+	fe *WhisperFeatureExtractor,
+	samples []float32,
+	offset float64,
+	end float64,
+	opts WhisperOptions,
+) ([]WhisperSegment, error) {
+	ids, err := model.Generate(tokenizer, fe.Extract(samples), fe.FeatureSize, opts)
+	if err != nil {
+		return nil, fmt.Errorf("Generate: %w", err)
+	}
+	return model.ParseSegments(tokenizer, ids, offset, end)
+}
+
+// transcribeChunked splits samples into chunkSec windows overlapping by
+// strideSec on each side, decodes each with timestamps and keeps the
+// segments whose midpoint lies in the chunk's core (the window minus its
+// strides; the first and last chunks have no outer stride).
+func transcribeChunked(
+	model *ModelForSpeechSeq2Seq,
+	tokenizer *Tokenizer,
+	fe *WhisperFeatureExtractor,
+	samples []float32,
+	chunkSec float64,
+	strideSec float64,
+	opts WhisperOptions,
+) ([]WhisperSegment, error) {
+	rate := float64(fe.SamplingRate)
+	chunkLen := int(chunkSec * rate)
+	stride := int(strideSec * rate)
+	step := chunkLen - 2*stride
+	if step <= 0 {
+		return nil, fmt.Errorf("automatic-speech-recognition: stride_length_s %.2f is too large for chunk_length_s %.2f", strideSec, chunkSec)
+	}
+
+	opts.Timestamps = true
+	var segs []WhisperSegment
+	for start := 0; start < len(samples); start += step {
+		stop := min(start+chunkLen, len(samples))
+		offset := float64(start) / rate
+		end := float64(stop) / rate
+
+		coreStart, coreEnd := offset, end
+		if start > 0 {
+			coreStart += strideSec
+		}
+		if stop < len(samples) {
+			coreEnd -= strideSec
+		}
+
+		chunk, err := transcribeWindow(model, tokenizer, fe, samples[start:stop], offset, end, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range chunk {
+			mid := (s.Start + s.End) / 2
+			if mid >= coreStart && mid < coreEnd {
+				segs = append(segs, s)
+			}
+		}
+		if stop == len(samples) {
+			break
+		}
+	}
+	return segs, nil
+}
+
+// floatOption reads a numeric option from the call options first, then the
+// pipeline options.
+func floatOption(callOptions, options map[string]any, key string, def float64) float64 {
+	for _, o := range []map[string]any{callOptions, options} {
+		switch t := o[key].(type) {
+		case float64:
+			return t
+		case int:
+			return float64(t)
+		}
+	}
+	return def
+}
+
+func roundCentis(x float64) float64 { return math.Round(x*100) / 100 }
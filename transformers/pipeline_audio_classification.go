@@ -0,0 +1,135 @@
+package transformers
+
+import (
+	"fmt"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// newAudioClassificationPipeline builds "audio-classification" for
+// wav2vec2, HuBERT and Audio Spectrogram Transformer classifiers:
+//
+//	clf, _ := Pipeline("audio-classification", "Xenova/ast-finetuned-audioset-10-10-0.4593", nil)
+//	out, _ := clf("dog.wav", map[string]any{"top_k": 3})
+//	// [{ "label": "Dog", "score": 0.91 }, ...]
+//
+// Audio is resampled to the extractor's sampling_rate. AST models get the
+// Kaldi filterbank of ASTFeatureExtractor; wav2vec2-style models get the raw
+// waveform, normalized when the preprocessor config sets do_normalize.
+// The result is flat rather than one list per input as in HF: for batched
+// inputs each entry carries "input_index" (see GroupClassificationResults).
+func newAudioClassificationPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	pp, err := loadPreprocessorConfig(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load preprocessor config: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	labels := id2Label(config)
+	rate := intOption(pp, "sampling_rate", 16000)
+
+	var ast *ASTFeatureExtractor
+	if t, _ := pp["feature_extractor_type"].(string); t == "ASTFeatureExtractor" || config.ModelType() == "audio-spectrogram-transformer" {
+		ast = newASTFeatureExtractor(pp)
+	}
+	normalize, _ := pp["do_normalize"].(bool)
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		topK := intOption(callOptions, "top_k", intOption(options, "top_k", 5))
+		fn := stringOption(callOptions, options, "function_to_apply")
+
+		clips := audioInputs(inputs)
+		var out []map[string]any
+		for i, in := range clips {
+			audio, err := audioFromInput(in, rate)
+			if err != nil {
+				return nil, err
+			}
+			if len(audio.Samples) == 0 {
+				return nil, fmt.Errorf("audio-classification: input %d is empty", i)
+			}
+
+			var outs map[string]floatOutput
+			if ast != nil {
+				values := ast.Extract(audio.Samples)
+				outs, err = runAudioClassifier(model, values, []int64{1, int64(ast.MaxLength), int64(ast.NumMelBins)}, false)
+			} else {
+				values := audio.Samples
+				if normalize {
+					values = normalizeWaveform(values)
+				}
+				outs, err = runAudioClassifier(model, values, []int64{1, int64(len(values))}, true)
+			}
+			if err != nil {
+				return nil, err
+			}
+			logits, err := model.output(outs, "logits")
+			if err != nil {
+				return nil, err
+			}
+			scores := logits.data
+			if fn == "" {
+				fn = defaultClassificationFunction(config, len(scores))
+			}
+			if err := applyClassificationFunction(fn, scores); err != nil {
+				return nil, err
+			}
+			for _, idx := range topIndices(scores, topK) {
+				entry := map[string]any{
+					"label": labelFor(labels, idx),
+					"score": float64(scores[idx]),
+				}
+				if len(clips) > 1 {
+					entry["input_index"] = i
+				}
+				out = append(out, entry)
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// runAudioClassifier feeds input_values of the given shape. Waveform models
+// also get an all-ones attention_mask when the export takes one, since
+// runFeeds would otherwise zero-fill it and mask out every sample.
+func runAudioClassifier(model *EncoderModel, values []float32, shape []int64, waveform bool) (map[string]floatOutput, error) {
+	feeds := map[string]onnx.Value{}
+	defer destroyValues(feeds)
+
+	t, err := tensorFromFloat32s(values, shape)
+	if err != nil {
+		return nil, fmt.Errorf("create input_values tensor: %w", err)
+	}
+	feeds["input_values"] = t
+	if waveform && model.hasInput("attention_mask") {
+		mask := make([]int64, len(values))
+		for i := range mask {
+			mask[i] = 1
+		}
+		m, err := tensorFromInt64s(mask, shape)
+		if err != nil {
+			return nil, fmt.Errorf("create attention_mask tensor: %w", err)
+		}
+		feeds["attention_mask"] = m
+	}
+	return model.runFeeds(feeds, 0)
+}
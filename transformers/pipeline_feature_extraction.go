@@ -0,0 +1,341 @@
+package transformers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strings"
+)
+
+// Pooling strategies for "feature-extraction".
+const (
+	PoolingNone      = "none"
+	PoolingMean      = "mean"
+	PoolingCLS       = "cls"
+	PoolingLastToken = "last_token"
+	PoolingMax       = "max"
+)
+
+// newFeatureExtractionPipeline builds the "feature-extraction" task
+// (sentence embeddings). Pooling and normalization default to the
+// sentence-transformers modules.json / 1_Pooling/config.json of the repo, or
+// to pooling "none" without them, and can be overridden per call:
+//
+//	embed, _ := Pipeline("feature-extraction", "Xenova/all-MiniLM-L6-v2", nil)
+//	out, _ := embed([]string{"hello", "world"}, map[string]any{"truncate_dim": 256})
+//	// [{ "embedding": []float32{...} }, ...]
+//
+// With pooling "none" each "embedding" is [][]float32, one row per token.
+// CLIP and SigLIP repos return the normalized text tower embedding instead.
+func newFeatureExtractionPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	if isCLIPFamily(config) {
+		return newCLIPTextFeatureExtractionPipeline(modelID, config, tokenizer, options)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	st := loadSentenceTransformersConfig(modelID)
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		texts, err := textInputs("feature-extraction", inputs)
+		if err != nil {
+			return nil, err
+		}
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+
+		pooling := strings.ReplaceAll(stringOption(callOptions, options, "pooling"), "-", "_")
+		if pooling == "" {
+			pooling = st.pooling
+		}
+		// A pooled graph output follows the repo's Pooling module, so it only
+		// stands in for the strategy that module declares.
+		graphPooled := pooling == st.pooling
+		switch pooling {
+		case PoolingNone, PoolingMean, PoolingCLS, PoolingLastToken, PoolingMax:
+		default:
+			return nil, fmt.Errorf("feature-extraction: unknown pooling %q", pooling)
+		}
+		normalize := st.normalize
+		for _, o := range []map[string]any{options, callOptions} {
+			if v, ok := o["normalize"].(bool); ok {
+				normalize = v
+			}
+		}
+		truncateDim := intOption(callOptions, "truncate_dim", intOption(options, "truncate_dim", 0))
+		batchSize := intOption(callOptions, "batch_size", intOption(options, "batch_size", 32))
+		if batchSize <= 0 {
+			batchSize = len(texts)
+		}
+
+		out := make([]map[string]any, 0, len(texts))
+		for lo := 0; lo < len(texts); lo += batchSize {
+			hi := min(lo+batchSize, len(texts))
+			embs, err := embedBatch(model, tokenizer, texts[lo:hi], pooling, graphPooled)
+			if err != nil {
+				return nil, err
+			}
+			for _, tokens := range embs {
+				for i, v := range tokens {
+					tokens[i] = truncateEmbedding(v, truncateDim, normalize)
+				}
+				if pooling == PoolingNone {
+					out = append(out, map[string]any{"embedding": tokens})
+				} else {
+					out = append(out, map[string]any{"embedding": tokens[0]})
+				}
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// newCLIPTextFeatureExtractionPipeline embeds texts with the text tower of a
+// CLIP/SigLIP repo, for matching against "image-feature-extraction" output.
+func newCLIPTextFeatureExtractionPipeline(modelID string, config *Config, tokenizer *Tokenizer, options map[string]any) (Generator, error) {
+	model, err := AutoModelForZeroShotImageClassification.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		texts, err := textInputs("feature-extraction", inputs)
+		if err != nil {
+			return nil, err
+		}
+		embs, err := model.TextEmbeddings(tokenizer, texts)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]map[string]any, len(embs))
+		for i, emb := range embs {
+			out[i] = map[string]any{"embedding": emb}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// embedBatch runs one padded batch and pools each row. Every result is a
+// list of vectors: one pooled vector, or one per kept token with "none".
+// graphPooled allows a "sentence_embedding" output to replace pooling.
+func embedBatch(model *EncoderModel, tokenizer *Tokenizer, texts []string, pooling string, graphPooled bool) ([][][]float32, error) {
+	rows := make([][]int64, len(texts))
+	types := make([][]int64, len(texts))
+	for i, text := range texts {
+		ids, tt, err := tokenizer.encodeWithTypes(text, "", model.maxSequenceLength(), truncateLongestFirst)
+		if err != nil {
+			return nil, fmt.Errorf("Encode: %w", err)
+		}
+		rows[i], types[i] = ids, tt
+	}
+	batch := newEncoderBatch(rows, types, model.padID())
+	outs, err := model.Run(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some sentence-transformers exports pool inside the graph.
+	if pooled, ok := outs["sentence_embedding"]; ok && graphPooled && pooling != PoolingNone && len(pooled.shape) == 2 {
+		dim := int(pooled.shape[1])
+		res := make([][][]float32, batch.batch)
+		for i := range res {
+			res[i] = [][]float32{append([]float32(nil), pooled.data[i*dim:(i+1)*dim]...)}
+		}
+		return res, nil
+	}
+
+	hidden, err := model.output(outs, "last_hidden_state", "token_embeddings")
+	if err != nil {
+		return nil, err
+	}
+	if len(hidden.shape) != 3 {
+		return nil, fmt.Errorf("feature-extraction: unexpected hidden state shape %v", hidden.shape)
+	}
+	dim := int(hidden.shape[2])
+	res := make([][][]float32, batch.batch)
+	for i := range res {
+		res[i] = poolTokens(hidden.data[i*batch.seqLen*dim:(i+1)*batch.seqLen*dim], batch.rowMask(i), dim, pooling)
+	}
+	return res, nil
+}
+
+// poolTokens pools one row of [seqLen x dim] token states under mask.
+func poolTokens(states []float32, mask []int64, dim int, pooling string) [][]float32 {
+	token := func(j int) []float32 { return states[j*dim : (j+1)*dim] }
+	var kept []int
+	for j, m := range mask {
+		if m != 0 {
+			kept = append(kept, j)
+		}
+	}
+	if len(kept) == 0 {
+		return [][]float32{make([]float32, dim)}
+	}
+
+	switch pooling {
+	case PoolingNone:
+		out := make([][]float32, len(kept))
+		for k, j := range kept {
+			out[k] = append([]float32(nil), token(j)...)
+		}
+		return out
+	case PoolingCLS:
+		return [][]float32{append([]float32(nil), token(kept[0])...)}
+	case PoolingLastToken:
+		return [][]float32{append([]float32(nil), token(kept[len(kept)-1])...)}
+	case PoolingMax:
+		out := append([]float32(nil), token(kept[0])...)
+		for _, j := range kept[1:] {
+			for d, v := range token(j) {
+				out[d] = max(out[d], v)
+			}
+		}
+		return [][]float32{out}
+	default: // PoolingMean
+		out := make([]float32, dim)
+		for _, j := range kept {
+			for d, v := range token(j) {
+				out[d] += v
+			}
+		}
+		inv := 1 / float32(len(kept))
+		for d := range out {
+			out[d] *= inv
+		}
+		return [][]float32{out}
+	}
+}
+
+// truncateEmbedding keeps the first dim values of v (Matryoshka truncation;
+// dim <= 0 keeps all) and then optionally scales them to unit length.
+func truncateEmbedding(v []float32, dim int, normalize bool) []float32 {
+	if dim > 0 && dim < len(v) {
+		v = v[:dim]
+	}
+	if normalize {
+		l2Normalize(v)
+	}
+	return v
+}
+
+// l2Normalize scales v to unit length in place.
+func l2Normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= inv
+	}
+}
+
+// sentenceTransformersConfig is the pooling setup declared by a
+// sentence-transformers repo.
+type sentenceTransformersConfig struct {
+	pooling   string
+	normalize bool
+}
+
+// loadSentenceTransformersConfig reads modules.json and the Pooling module
+// config (best effort). Repos without them pool with "none".
+func loadSentenceTransformersConfig(modelID string) sentenceTransformersConfig {
+	cfg := sentenceTransformersConfig{pooling: PoolingNone}
+	paths, err := HFHubEnsureOptionalFiles(modelID, []string{"modules.json"})
+	if err != nil || paths["modules.json"] == "" {
+		return cfg
+	}
+	data, err := os.ReadFile(paths["modules.json"])
+	if err != nil {
+		return cfg
+	}
+	var modules []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &modules); err != nil {
+		return cfg
+	}
+
+	for _, mod := range modules {
+		switch path.Ext(mod.Type) {
+		case ".Normalize":
+			cfg.normalize = true
+		case ".Pooling":
+			poolingFile := path.Join(mod.Path, "config.json")
+			p, err := HFHubEnsureOptionalFiles(modelID, []string{poolingFile})
+			if err != nil || p[poolingFile] == "" {
+				continue
+			}
+			raw, err := os.ReadFile(p[poolingFile])
+			if err != nil {
+				continue
+			}
+			var pc map[string]any
+			if json.Unmarshal(raw, &pc) != nil {
+				continue
+			}
+			cfg.pooling = poolingFromConfig(pc)
+		}
+	}
+	return cfg
+}
+
+// poolingFromConfig maps the pooling_mode_* flags of a sentence-transformers
+// Pooling config to one of our pooling strategies.
+func poolingFromConfig(pc map[string]any) string {
+	for _, m := range []struct{ key, pooling string }{
+		{"pooling_mode_cls_token", PoolingCLS},
+		{"pooling_mode_lasttoken", PoolingLastToken},
+		{"pooling_mode_max_tokens", PoolingMax},
+		{"pooling_mode_mean_tokens", PoolingMean},
+	} {
+		if on, _ := pc[m.key].(bool); on {
+			return m.pooling
+		}
+	}
+	if mode, _ := pc["pooling_mode"].(string); mode != "" {
+		switch mode {
+		case "cls", "max", "mean":
+			return mode
+		case "lasttoken":
+			return PoolingLastToken
+		}
+	}
+	return PoolingMean
+}
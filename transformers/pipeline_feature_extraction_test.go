@@ -0,0 +1,104 @@
+package transformers
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPoolTokens(t *testing.T) {
+	// Three tokens of dim 2; the last one is padding.
+	states := []float32{1, 4, 3, 2, 100, 100}
+	mask := []int64{1, 1, 0}
+	tests := []struct {
+		pooling string
+		want    [][]float32
+	}{
+		{PoolingNone, [][]float32{{1, 4}, {3, 2}}},
+		{PoolingCLS, [][]float32{{1, 4}}},
+		{PoolingLastToken, [][]float32{{3, 2}}},
+		{PoolingMax, [][]float32{{3, 4}}},
+		{PoolingMean, [][]float32{{2, 3}}},
+	}
+	for _, tt := range tests {
+		if got := poolTokens(states, mask, 2, tt.pooling); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("poolTokens(%s) = %v, want %v", tt.pooling, got, tt.want)
+		}
+	}
+
+	got := poolTokens(states, []int64{0, 0, 0}, 2, PoolingMean)
+	if !reflect.DeepEqual(got, [][]float32{{0, 0}}) {
+		t.Errorf("poolTokens(empty mask) = %v, want one zero vector", got)
+	}
+
+	// Pooled vectors must not alias the model output.
+	got = poolTokens(states, mask, 2, PoolingCLS)
+	got[0][0] = 42
+	if states[0] != 1 {
+		t.Error("poolTokens(cls) aliases the hidden states")
+	}
+}
+
+func TestPoolingFromConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		pc   map[string]any
+		want string
+	}{
+		{"cls flag", map[string]any{"pooling_mode_cls_token": true, "pooling_mode_mean_tokens": false}, PoolingCLS},
+		{"mean flag", map[string]any{"pooling_mode_cls_token": false, "pooling_mode_mean_tokens": true}, PoolingMean},
+		{"max flag", map[string]any{"pooling_mode_max_tokens": true}, PoolingMax},
+		{"last token flag", map[string]any{"pooling_mode_lasttoken": true}, PoolingLastToken},
+		{"mode string", map[string]any{"pooling_mode": "max"}, PoolingMax},
+		{"lasttoken string", map[string]any{"pooling_mode": "lasttoken"}, PoolingLastToken},
+		{"unknown mode", map[string]any{"pooling_mode": "weightedmean"}, PoolingMean},
+		{"empty", map[string]any{}, PoolingMean},
+	}
+	for _, tt := range tests {
+		if got := poolingFromConfig(tt.pc); got != tt.want {
+			t.Errorf("%s: poolingFromConfig = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestL2Normalize(t *testing.T) {
+	v := []float32{3, 4}
+	l2Normalize(v)
+	if math.Abs(float64(v[0])-0.6) > 1e-6 || math.Abs(float64(v[1])-0.8) > 1e-6 {
+		t.Errorf("l2Normalize([3 4]) = %v, want [0.6 0.8]", v)
+	}
+	zero := []float32{0, 0}
+	l2Normalize(zero)
+	if zero[0] != 0 || zero[1] != 0 {
+		t.Errorf("l2Normalize(zero) = %v, want it unchanged", zero)
+	}
+}
+
+func TestTruncateEmbedding(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         []float32
+		dim       int
+		normalize bool
+		want      []float32
+	}{
+		{"keep all", []float32{1, 2, 3}, 0, false, []float32{1, 2, 3}},
+		{"dim past length", []float32{1, 2, 3}, 8, false, []float32{1, 2, 3}},
+		{"truncate", []float32{1, 2, 3}, 2, false, []float32{1, 2}},
+		// Normalization comes after truncation, so the kept prefix has unit length.
+		{"truncate then normalize", []float32{3, 4, 12}, 2, true, []float32{0.6, 0.8}},
+	}
+	for _, tt := range tests {
+		got := truncateEmbedding(tt.v, tt.dim, tt.normalize)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if math.Abs(float64(got[i]-tt.want[i])) > 1e-6 {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// newFillMaskPipeline builds "fill-mask" for masked language models (BERT,
+// RoBERTa, ...). The mask token comes from special_tokens_map.json:
+//
+//	unmasker, _ := Pipeline("fill-mask", "Xenova/bert-base-uncased", nil)
+//	out, _ := unmasker("The capital of France is [MASK].", map[string]any{"top_k": 3})
+//	// [{ "score": 0.42, "token": 3000, "token_str": "paris", "sequence": "the capital of france is paris." }, ...]
+//
+// Every mask of every input yields top_k predictions; when there is more than
+// one input or mask, entries also carry "input_index" and "mask_index".
+func newFillMaskPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	maskToken := tokenizer.SpecialToken("mask_token")
+	if maskToken == "" {
+		return nil, fmt.Errorf("fill-mask: %s defines no mask_token", modelID)
+	}
+	maskID, ok := tokenizer.TokenToID(maskToken)
+	if !ok {
+		return nil, fmt.Errorf("fill-mask: mask token %q is not in the vocabulary", maskToken)
+	}
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		texts, err := textInputs("fill-mask", inputs)
+		if err != nil {
+			return nil, err
+		}
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		topK := intOption(callOptions, "top_k", intOption(options, "top_k", 5))
+		targets, err := fillMaskTargets(tokenizer, callOptions, options)
+		if err != nil {
+			return nil, err
+		}
+
+		rows := make([][]int64, len(texts))
+		types := make([][]int64, len(texts))
+		masks := make([][]int, len(texts))
+		for i, text := range texts {
+			want := strings.Count(text, maskToken)
+			if want == 0 {
+				return nil, fmt.Errorf("fill-mask: input %q has no %s token", text, maskToken)
+			}
+			ids, tt, err := tokenizer.encodeWithTypes(text, "", model.maxSequenceLength(), truncateLongestFirst)
+			if err != nil {
+				return nil, fmt.Errorf("Encode: %w", err)
+			}
+			rows[i], types[i] = ids, tt
+			for j, id := range ids {
+				if id == maskID {
+					masks[i] = append(masks[i], j)
+				}
+			}
+			if len(masks[i]) < want {
+				return nil, fmt.Errorf("fill-mask: input %d is longer than %d tokens and %d of its %d %s tokens were truncated",
+					i, model.maxSequenceLength(), want-len(masks[i]), want, maskToken)
+			}
+		}
+		batch := newEncoderBatch(rows, types, model.padID())
+		outs, err := model.Run(batch)
+		if err != nil {
+			return nil, err
+		}
+		logits, err := model.output(outs, "logits")
+		if err != nil {
+			return nil, err
+		}
+		if len(logits.shape) != 3 {
+			return nil, fmt.Errorf("fill-mask: unexpected logits shape %v", logits.shape)
+		}
+		vocab := int(logits.shape[2])
+		for _, id := range targets {
+			if id < 0 || int(id) >= vocab {
+				return nil, fmt.Errorf("fill-mask: target token %d is outside the %d-token vocabulary of the model", id, vocab)
+			}
+		}
+
+		var out []map[string]any
+		for i, ids := range rows {
+			for m, pos := range masks[i] {
+				probs := append([]float32(nil), logits.data[(i*batch.seqLen+pos)*vocab:(i*batch.seqLen+pos+1)*vocab]...)
+				softmaxF32(probs)
+
+				candidates := targets
+				if candidates == nil {
+					candidates = make([]int64, 0, topK)
+					for _, idx := range topIndices(probs, topK) {
+						candidates = append(candidates, int64(idx))
+					}
+				} else {
+					candidates = topTargets(probs, targets, topK)
+				}
+
+				for _, id := range candidates {
+					filled := append([]int64(nil), ids...)
+					filled[pos] = id
+					sequence, err := tokenizer.Decode(filled)
+					if err != nil {
+						return nil, fmt.Errorf("Decode: %w", err)
+					}
+					tokenStr, err := tokenizer.Decode([]int64{id})
+					if err != nil {
+						return nil, fmt.Errorf("Decode: %w", err)
+					}
+					entry := map[string]any{
+						"score":     float64(probs[id]),
+						"token":     id,
+						"token_str": strings.TrimSpace(tokenStr),
+						"sequence":  sequence,
+					}
+					if len(texts) > 1 || len(masks[i]) > 1 {
+						entry["input_index"] = i
+						entry["mask_index"] = m
+					}
+					out = append(out, entry)
+				}
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// fillMaskTargets resolves the "targets" option to token IDs. A target that
+// is not a single vocabulary token is tokenized and its first token used, as
+// HF does. nil means no restriction.
+func fillMaskTargets(tokenizer *Tokenizer, callOptions, options map[string]any) ([]int64, error) {
+	raw, _ := stringListOption(callOptions, options, "targets")
+	if raw == nil {
+		return nil, nil
+	}
+	ids := make([]int64, 0, len(raw))
+	for _, target := range raw {
+		if id, ok := tokenizer.TokenToID(target); ok {
+			ids = append(ids, id)
+			continue
+		}
+		enc, err := tokenizer.Encode(target, false)
+		if err != nil {
+			return nil, fmt.Errorf("fill-mask: encode target %q: %w", target, err)
+		}
+		if len(enc) == 0 {
+			return nil, fmt.Errorf("fill-mask: target %q encodes to no tokens", target)
+		}
+		ids = append(ids, enc[0])
+	}
+	return ids, nil
+}
+
+// topTargets orders targets by probability and keeps the best topK. IDs
+// outside probs are dropped.
+func topTargets(probs []float32, targets []int64, topK int) []int64 {
+	var (
+		valid  []int64
+		scores []float32
+	)
+	for _, id := range targets {
+		if id >= 0 && int(id) < len(probs) {
+			valid = append(valid, id)
+			scores = append(scores, probs[id])
+		}
+	}
+	var out []int64
+	for _, idx := range topIndices(scores, topK) {
+		out = append(out, valid[idx])
+	}
+	return out
+}
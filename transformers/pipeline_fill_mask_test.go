@@ -0,0 +1,47 @@
+package transformers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTopTargets(t *testing.T) {
+	probs := []float32{0.1, 0.5, 0.3, 0.1}
+	tests := []struct {
+		targets []int64
+		topK    int
+		want    []int64
+	}{
+		{[]int64{0, 1, 2}, 2, []int64{1, 2}},
+		{[]int64{3, 2}, 5, []int64{2, 3}},
+		{[]int64{2, 99, -1, 1}, 0, []int64{1, 2}},
+		{[]int64{99}, 1, nil},
+	}
+	for _, tt := range tests {
+		if got := topTargets(probs, tt.targets, tt.topK); !slices.Equal(got, tt.want) {
+			t.Errorf("topTargets(%v, %d) = %v, want %v", tt.targets, tt.topK, got, tt.want)
+		}
+	}
+}
+
+func TestFillMaskTargets(t *testing.T) {
+	tok := stubTokenizer(t, "paris", "london")
+	id := func(w string) int64 { v, _ := tok.TokenToID(w); return v }
+	tests := []struct {
+		name          string
+		call, options map[string]any
+		want          []int64
+	}{
+		{"unset", nil, nil, nil},
+		{"string", map[string]any{"targets": "paris"}, nil, []int64{id("paris")}},
+		{"[]any from JSON", nil, map[string]any{"targets": []any{"london", "paris"}}, []int64{id("london"), id("paris")}},
+		{"call wins", map[string]any{"targets": []string{"london"}}, map[string]any{"targets": "paris"}, []int64{id("london")}},
+		{"first token of a phrase", map[string]any{"targets": "paris london"}, nil, []int64{id("paris")}},
+	}
+	for _, tt := range tests {
+		got, err := fillMaskTargets(tok, tt.call, tt.options)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, %v; want %v", tt.name, got, err, tt.want)
+		}
+	}
+}
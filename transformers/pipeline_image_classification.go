@@ -0,0 +1,98 @@
+package transformers
+
+import "fmt"
+
+// newImageClassificationPipeline builds "image-classification" for ViT-style
+// exports (pixel_values -> logits):
+//
+//	clf, _ := Pipeline("image-classification", "Xenova/vit-base-patch16-224", nil)
+//	out, _ := clf("cat.jpg", map[string]any{"top_k": 3})
+//	// [{ "label": "tabby, tabby cat", "score": 0.72 }, ...]
+//
+// Inputs are file paths, encoded bytes, image.Image values, or a list of
+// those. For batched inputs each entry carries "input_index".
+func newImageClassificationPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	processor, err := AutoImageProcessor.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load image processor: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	labels := id2Label(config)
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		topK := intOption(callOptions, "top_k", intOption(options, "top_k", 5))
+		fn := stringOption(callOptions, options, "function_to_apply")
+
+		images := imageInputs(inputs)
+		var out []map[string]any
+		for i, in := range images {
+			img, err := imageFromInput(in)
+			if err != nil {
+				return nil, err
+			}
+			pv, err := processor.Preprocess(img)
+			if err != nil {
+				return nil, err
+			}
+			outs, err := model.RunPixels(pv)
+			if err != nil {
+				return nil, err
+			}
+			logits, err := model.output(outs, "logits")
+			if err != nil {
+				return nil, err
+			}
+			scores := logits.data
+			if fn == "" {
+				fn = defaultClassificationFunction(config, len(scores))
+			}
+			entries, err := labeledScores(scores, labels, fn, topK)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if len(images) > 1 {
+					entry["input_index"] = i
+				}
+				out = append(out, entry)
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// labeledScores applies fn to logits in place and returns the top_k labels
+// with their scores, best first.
+func labeledScores(logits []float32, labels []string, fn string, topK int) ([]map[string]any, error) {
+	if err := applyClassificationFunction(fn, logits); err != nil {
+		return nil, err
+	}
+	var out []map[string]any
+	for _, idx := range topIndices(logits, topK) {
+		out = append(out, map[string]any{
+			"label": labelFor(labels, idx),
+			"score": float64(logits[idx]),
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,119 @@
+package transformers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLabeledScores(t *testing.T) {
+	labels := []string{"cat", "dog", "", "bird"}
+	ln := func(x float64) float32 { return float32(math.Log(x)) }
+	tests := []struct {
+		name   string
+		logits []float32
+		fn     string
+		topK   int
+		want   []map[string]any
+	}{
+		{
+			name:   "softmax top 2",
+			logits: []float32{ln(1), ln(6), ln(2), ln(1)},
+			fn:     "softmax",
+			topK:   2,
+			want: []map[string]any{
+				{"label": "dog", "score": 0.6},
+				{"label": "LABEL_2", "score": 0.2},
+			},
+		},
+		{
+			name:   "sigmoid keeps independent scores",
+			logits: []float32{0, ln(3), -50, 50},
+			fn:     "sigmoid",
+			topK:   0,
+			want: []map[string]any{
+				{"label": "bird", "score": 1.0},
+				{"label": "dog", "score": 0.75},
+				{"label": "cat", "score": 0.5},
+				{"label": "LABEL_2", "score": 0.0},
+			},
+		},
+		{
+			name:   "top_k past the labels",
+			logits: []float32{2, 1},
+			fn:     "none",
+			topK:   5,
+			want: []map[string]any{
+				{"label": "cat", "score": 2.0},
+				{"label": "dog", "score": 1.0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := labeledScores(tt.logits, labels, tt.fn, tt.topK)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		for i, w := range tt.want {
+			score := got[i]["score"].(float64)
+			if got[i]["label"] != w["label"] || math.Abs(score-w["score"].(float64)) > 1e-6 {
+				t.Errorf("%s: entry %d = %v, want %v", tt.name, i, got[i], w)
+			}
+		}
+	}
+	if _, err := labeledScores([]float32{1}, labels, "relu", 1); err == nil {
+		t.Error("unknown function: expected an error")
+	}
+}
+
+func TestImageInputs(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 1, 1))
+	tests := []struct {
+		in   any
+		want []any
+	}{
+		{"a.png", []any{"a.png"}},
+		{[]string{"a.png", "b.png"}, []any{"a.png", "b.png"}},
+		{[][]byte{{1}, {2}}, []any{[]byte{1}, []byte{2}}},
+		{[]image.Image{img}, []any{img}},
+		{[]any{"a.png", img}, []any{"a.png", img}},
+	}
+	for _, tt := range tests {
+		if got := imageInputs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("imageInputs(%T) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestImageFromInput(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(1, 0, color.NRGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := imageFromInput(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := decoded.At(1, 0).RGBA(); decoded.Bounds().Dx() != 2 || r != 0xffff {
+		t.Errorf("decoded PNG: bounds %v, pixel %v", decoded.Bounds(), decoded.At(1, 0))
+	}
+	if got, err := imageFromInput(image.Image(src)); err != nil || got != image.Image(src) {
+		t.Errorf("imageFromInput(image.Image) = %v, %v; want the image itself", got, err)
+	}
+	if _, err := imageFromInput([]byte("not an image")); err == nil {
+		t.Error("garbage bytes: expected an error")
+	}
+	if _, err := imageFromInput(42); err == nil {
+		t.Error("imageFromInput(42): expected an error")
+	}
+}
@@ -0,0 +1,139 @@
+package transformers
+
+import "fmt"
+
+// newImageFeatureExtractionPipeline builds "image-feature-extraction". CLIP
+// and SigLIP repos return the normalized projected image embedding, in the
+// same space as the "feature-extraction" text embedding of the same repo;
+// other vision backbones (ViT, DINOv2, ...) return their pooled output,
+// normalized only with normalize:
+//
+//	embed, _ := Pipeline("image-feature-extraction", "Xenova/clip-vit-base-patch32", nil)
+//	out, _ := embed([]string{"a.jpg", "b.jpg"}, nil)
+//	// [{ "embedding": []float32{...} }, ...]
+//
+// With pool false a backbone returns every token state as [][]float32.
+func newImageFeatureExtractionPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	processor, err := AutoImageProcessor.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load image processor: %w", err)
+	}
+
+	var (
+		clip     *CLIPModel
+		backbone *EncoderModel
+	)
+	if isCLIPFamily(config) {
+		clip, err = AutoModelForZeroShotImageClassification.FromPretrainedWithOptions(
+			modelID,
+			config,
+			encoderDtypeOption(options),
+			modelLoadOptionsFrom(options),
+		)
+	} else {
+		backbone, err = AutoModel.FromPretrainedWithOptions(
+			modelID,
+			config,
+			encoderDtypeOption(options),
+			modelLoadOptionsFrom(options),
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		normalize, pool := false, true
+		for _, o := range []map[string]any{options, callOptions} {
+			if v, ok := o["normalize"].(bool); ok {
+				normalize = v
+			}
+			if v, ok := o["pool"].(bool); ok {
+				pool = v
+			}
+		}
+
+		images := imageInputs(inputs)
+		out := make([]map[string]any, 0, len(images))
+		for _, in := range images {
+			img, err := imageFromInput(in)
+			if err != nil {
+				return nil, err
+			}
+			pv, err := processor.Preprocess(img)
+			if err != nil {
+				return nil, err
+			}
+
+			if clip != nil {
+				emb, err := clip.ImageEmbeddings(pv)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, map[string]any{"embedding": emb})
+				continue
+			}
+
+			tokens, err := imageFeatures(backbone, pv, pool)
+			if err != nil {
+				return nil, err
+			}
+			if normalize {
+				for _, v := range tokens {
+					l2Normalize(v)
+				}
+			}
+			if pool {
+				out = append(out, map[string]any{"embedding": tokens[0]})
+			} else {
+				out = append(out, map[string]any{"embedding": tokens})
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// imageFeatures runs a vision backbone on one image. Pooled, it prefers the
+// graph's image_embeds or pooler_output and falls back to the first (CLS)
+// token; otherwise it returns every token state.
+func imageFeatures(model *EncoderModel, pv *PixelValues, pool bool) ([][]float32, error) {
+	outs, err := model.RunPixels(pv)
+	if err != nil {
+		return nil, err
+	}
+	if pool {
+		for _, name := range []string{"image_embeds", "pooler_output"} {
+			if o, ok := outs[name]; ok && len(o.shape) == 2 {
+				return [][]float32{append([]float32(nil), o.data[:o.shape[1]]...)}, nil
+			}
+		}
+	}
+	hidden, err := model.output(outs, "last_hidden_state")
+	if err != nil {
+		return nil, err
+	}
+	if len(hidden.shape) != 3 {
+		return nil, fmt.Errorf("image-feature-extraction: unexpected hidden state shape %v", hidden.shape)
+	}
+	n, dim := int(hidden.shape[1]), int(hidden.shape[2])
+	if pool {
+		n = 1
+	}
+	tokens := make([][]float32, n)
+	for j := range tokens {
+		tokens[j] = append([]float32(nil), hidden.data[j*dim:(j+1)*dim]...)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,245 @@
+package transformers
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// newImageSegmentationPipeline builds "image-segmentation" for semantic
+// segmentation models (SegFormer, ...) and for background-removal mattes
+// (RMBG-style graphs with a single-channel output):
+//
+//	segmenter, _ := Pipeline("image-segmentation", "Xenova/segformer-b0-finetuned-ade-512-512", nil)
+//	out, _ := segmenter("room.jpg", nil)
+//	// [{ "label": "wall", "score": 0.91, "mask": *image.Gray }, ...]
+//
+// Semantic models return one mask per predicted label (255 where the label
+// wins the per-pixel argmax) and the label's mean probability over it.
+// Mattes return a single "foreground" entry whose mask is the alpha channel
+// and whose score is nil; ApplyAlphaMask cuts the subject out with it.
+// Masks have the original image size. For batched inputs each entry
+// carries "input_index".
+func newImageSegmentationPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	processor, err := AutoImageProcessor.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load image processor: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	labels := id2Label(config)
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		images := imageInputs(inputs)
+		var out []map[string]any
+		for i, in := range images {
+			img, err := imageFromInput(in)
+			if err != nil {
+				return nil, err
+			}
+			pv, err := processor.Preprocess(img)
+			if err != nil {
+				return nil, err
+			}
+			outs, err := model.RunPixels(pv)
+			if err != nil {
+				return nil, err
+			}
+			names := append([]string{"logits", "alphas", "output"}, model.outputNames...)
+			pred, err := model.output(outs, names...)
+			if err != nil {
+				return nil, err
+			}
+			entries, err := segmentationEntries(pred, pv, labels)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if len(images) > 1 {
+					entry["input_index"] = i
+				}
+				out = append(out, entry)
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// segmentationEntries turns a [1, C, h, w] prediction into result entries:
+// one "foreground" matte for single-channel outputs, else a mask per label.
+func segmentationEntries(pred floatOutput, pv *PixelValues, labels []string) ([]map[string]any, error) {
+	if len(pred.shape) != 4 {
+		return nil, fmt.Errorf("image-segmentation: unexpected output shape %v", pred.shape)
+	}
+	if pred.shape[1] == 1 {
+		return []map[string]any{{
+			"label": "foreground",
+			"score": nil,
+			"mask":  alphaMask(pred, pv),
+		}}, nil
+	}
+	var entries []map[string]any
+	for _, s := range semanticMasks(pred, pv) {
+		entries = append(entries, map[string]any{
+			"label": labelFor(labels, s.class),
+			"score": s.score,
+			"mask":  s.mask,
+		})
+	}
+	return entries, nil
+}
+
+// segment is one label's mask from semantic segmentation.
+type segment struct {
+	class int
+	score float64
+	mask  *image.Gray
+}
+
+// semanticMasks upsamples [1, C, h, w] logits to the original image
+// bilinearly, takes the per-pixel argmax and returns a mask per label that
+// wins any pixel, in label order.
+func semanticMasks(logits floatOutput, pv *PixelValues) []segment {
+	classes, gh, gw := int(logits.shape[1]), int(logits.shape[2]), int(logits.shape[3])
+	oh, ow := pv.OriginalSize[0], pv.OriginalSize[1]
+	ys, xs := segmentationTaps(pv, gh, gw)
+
+	winners := make([]int, oh*ow)
+	probSum := make([]float64, classes)
+	count := make([]int, classes)
+	vals := make([]float64, classes)
+	for y := 0; y < oh; y++ {
+		for x := 0; x < ow; x++ {
+			best := 0
+			for c := 0; c < classes; c++ {
+				plane := logits.data[c*gh*gw : (c+1)*gh*gw]
+				vals[c] = float64(ys[y].sample(xs[x], plane, gw))
+				if vals[c] > vals[best] {
+					best = c
+				}
+			}
+			var sum float64
+			for c := range vals {
+				sum += math.Exp(vals[c] - vals[best])
+			}
+			winners[y*ow+x] = best
+			probSum[best] += 1 / sum
+			count[best]++
+		}
+	}
+
+	var out []segment
+	for c := 0; c < classes; c++ {
+		if count[c] == 0 {
+			continue
+		}
+		mask := image.NewGray(image.Rect(0, 0, ow, oh))
+		for p, w := range winners {
+			if w == c {
+				mask.Pix[p] = 255
+			}
+		}
+		out = append(out, segment{class: c, score: probSum[c] / float64(count[c]), mask: mask})
+	}
+	return out
+}
+
+// alphaMask upsamples a [1, 1, h, w] matte to the original image. Graphs
+// that end before their sigmoid get it applied here.
+func alphaMask(pred floatOutput, pv *PixelValues) *image.Gray {
+	gh, gw := int(pred.shape[2]), int(pred.shape[3])
+	oh, ow := pv.OriginalSize[0], pv.OriginalSize[1]
+	ys, xs := segmentationTaps(pv, gh, gw)
+
+	plane := pred.data[:gh*gw]
+	for _, v := range plane {
+		if v < 0 || v > 1 {
+			plane = make([]float32, gh*gw)
+			for i, v := range pred.data[:gh*gw] {
+				plane[i] = float32(1 / (1 + math.Exp(-float64(v))))
+			}
+			break
+		}
+	}
+	mask := image.NewGray(image.Rect(0, 0, ow, oh))
+	for y := 0; y < oh; y++ {
+		for x := 0; x < ow; x++ {
+			a := ys[y].sample(xs[x], plane, gw)
+			mask.Pix[y*ow+x] = uint8(math.Round(float64(min(max(a, 0), 1)) * 255))
+		}
+	}
+	return mask
+}
+
+// bilinearTap is one output coordinate's two source indices and the weight
+// of the second.
+type bilinearTap struct {
+	i0, i1 int
+	f      float32
+}
+
+// sample interpolates plane (row stride w) at row tap t and column tap x.
+func (t bilinearTap) sample(x bilinearTap, plane []float32, w int) float32 {
+	top := plane[t.i0*w+x.i0]*(1-x.f) + plane[t.i0*w+x.i1]*x.f
+	bottom := plane[t.i1*w+x.i0]*(1-x.f) + plane[t.i1*w+x.i1]*x.f
+	return top*(1-t.f) + bottom*t.f
+}
+
+// segmentationTaps maps original-image rows and columns onto an output grid
+// of gh x gw cells covering the model input, padding included, with
+// half-pixel centers (align_corners=False).
+func segmentationTaps(pv *PixelValues, gh, gw int) (ys, xs []bilinearTap) {
+	inH, inW := pv.Shape[2], pv.Shape[3]
+	if pv.ChannelsLast {
+		inH, inW = pv.Shape[1], pv.Shape[2]
+	}
+	axis := func(n, resized int, in int64, grid int) []bilinearTap {
+		taps := make([]bilinearTap, n)
+		for i := range taps {
+			pos := (float64(i)+0.5)*float64(resized)/float64(n)*float64(grid)/float64(in) - 0.5
+			pos = math.Min(math.Max(pos, 0), float64(grid-1))
+			i0 := int(pos)
+			taps[i] = bilinearTap{i0: i0, i1: min(i0+1, grid-1), f: float32(pos - float64(i0))}
+		}
+		return taps
+	}
+	return axis(pv.OriginalSize[0], pv.ResizedSize[0], inH, gh),
+		axis(pv.OriginalSize[1], pv.ResizedSize[1], inW, gw)
+}
+
+// ApplyAlphaMask returns img with mask as its alpha channel, e.g. to cut
+// out the subject found by a background-removal model. mask must have
+// img's size.
+func ApplyAlphaMask(img image.Image, mask *image.Gray) (*image.NRGBA, error) {
+	b := img.Bounds()
+	if b.Dx() != mask.Rect.Dx() || b.Dy() != mask.Rect.Dy() {
+		return nil, fmt.Errorf("ApplyAlphaMask: mask is %v, image is %v", mask.Rect.Size(), b.Size())
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(out, out.Rect, img, b.Min, draw.Src)
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			c := out.NRGBAAt(x, y)
+			c.A = uint8(uint16(c.A) * uint16(mask.GrayAt(mask.Rect.Min.X+x, mask.Rect.Min.Y+y).Y) / 255)
+			out.SetNRGBA(x, y, c)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,153 @@
+package transformers
+
+import (
+	"image"
+	"math"
+	"slices"
+	"testing"
+)
+
+// maskRows renders a mask as one string per row, '#' where it is set.
+func maskRows(m *image.Gray) []string {
+	var rows []string
+	for y := 0; y < m.Rect.Dy(); y++ {
+		row := make([]byte, m.Rect.Dx())
+		for x := range row {
+			row[x] = '.'
+			if m.GrayAt(x, y).Y != 0 {
+				row[x] = '#'
+			}
+		}
+		rows = append(rows, string(row))
+	}
+	return rows
+}
+
+func sigmoid(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+
+func TestSegmentationEntriesSemantic(t *testing.T) {
+	// 2x2 logits for three classes over a 4x4 input: class 0 wins the left
+	// column, class 1 the right one, class 2 nowhere.
+	pred := floatOutput{
+		shape: []int64{1, 3, 2, 2},
+		data: []float32{
+			5, -5, 5, -5,
+			-5, 5, -5, 5,
+			-100, -100, -100, -100,
+		},
+	}
+	pv := &PixelValues{Shape: []int64{1, 3, 4, 4}, OriginalSize: [2]int{4, 4}, ResizedSize: [2]int{4, 4}}
+	entries, err := segmentationEntries(pred, pv, []string{"wall", "floor", "sky"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (sky wins no pixel)", len(entries))
+	}
+
+	want := []struct {
+		label string
+		rows  []string
+		score float64
+	}{
+		// Bilinear upsampling puts the class boundary between columns 1 and
+		// 2; columns 0/3 sit on a logit gap of 10, columns 1/2 on one of 5.
+		{"wall", []string{"##..", "##..", "##..", "##.."}, (sigmoid(10) + sigmoid(5)) / 2},
+		{"floor", []string{"..##", "..##", "..##", "..##"}, (sigmoid(10) + sigmoid(5)) / 2},
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e["label"] != w.label {
+			t.Errorf("entry %d label = %v, want %s", i, e["label"], w.label)
+		}
+		if got := maskRows(e["mask"].(*image.Gray)); !slices.Equal(got, w.rows) {
+			t.Errorf("%s mask = %q, want %q", w.label, got, w.rows)
+		}
+		if got := e["score"].(float64); math.Abs(got-w.score) > 1e-4 {
+			t.Errorf("%s score = %v, want %v", w.label, got, w.score)
+		}
+	}
+
+	// Without id2label the HF fallback names are used.
+	entries, _ = segmentationEntries(pred, pv, nil)
+	if entries[0]["label"] != "LABEL_0" || entries[1]["label"] != "LABEL_1" {
+		t.Errorf("labels without id2label = %v, %v", entries[0]["label"], entries[1]["label"])
+	}
+}
+
+func TestSemanticMasksIgnorePadding(t *testing.T) {
+	// A 1x4 image resized to 2x4 and padded to 4x4: the bottom logit row only
+	// covers padding and must not reach the mask.
+	pred := floatOutput{
+		shape: []int64{1, 2, 2, 2},
+		data: []float32{
+			3, 3, -3, -3,
+			-3, -3, 3, 3,
+		},
+	}
+	pv := &PixelValues{Shape: []int64{1, 3, 4, 4}, OriginalSize: [2]int{1, 4}, ResizedSize: [2]int{2, 4}}
+	segs := semanticMasks(pred, pv)
+	if len(segs) != 1 || segs[0].class != 0 {
+		t.Fatalf("got %d segments, want class 0 only", len(segs))
+	}
+	if got := maskRows(segs[0].mask); !slices.Equal(got, []string{"####"}) {
+		t.Errorf("mask = %q, want one full row", got)
+	}
+}
+
+func TestSegmentationEntriesMatte(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []float32
+		alpha uint8
+	}{
+		{"probabilities", []float32{0.25}, 64},
+		{"logits get a sigmoid", []float32{-1}, 69},
+		{"saturated logits", []float32{20}, 255},
+	}
+	pv := &PixelValues{Shape: []int64{1, 3, 2, 2}, OriginalSize: [2]int{3, 2}, ResizedSize: [2]int{2, 2}}
+	for _, tt := range tests {
+		pred := floatOutput{shape: []int64{1, 1, 1, 1}, data: tt.data}
+		entries, err := segmentationEntries(pred, pv, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0]["label"] != "foreground" || entries[0]["score"] != nil {
+			t.Fatalf("%s: entries = %v", tt.name, entries)
+		}
+		mask := entries[0]["mask"].(*image.Gray)
+		if mask.Rect.Dx() != 2 || mask.Rect.Dy() != 3 {
+			t.Errorf("%s: mask is %v, want the 2x3 original", tt.name, mask.Rect.Size())
+		}
+		for _, a := range mask.Pix {
+			if a != tt.alpha {
+				t.Errorf("%s: alpha = %d, want %d", tt.name, a, tt.alpha)
+				break
+			}
+		}
+	}
+
+	if _, err := segmentationEntries(floatOutput{shape: []int64{1, 4, 4}}, pv, nil); err == nil {
+		t.Error("3-D output: expected an error")
+	}
+}
+
+func TestApplyAlphaMask(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 2, 1))
+	img.Pix = []uint8{10, 200}
+	mask := image.NewGray(image.Rect(0, 0, 2, 1))
+	mask.Pix = []uint8{0, 255}
+	out, err := ApplyAlphaMask(img, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a0, a1 := out.NRGBAAt(0, 0).A, out.NRGBAAt(1, 0).A; a0 != 0 || a1 != 255 {
+		t.Errorf("alpha = %d, %d; want 0, 255", a0, a1)
+	}
+	if c := out.NRGBAAt(1, 0); c.R != 200 || c.G != 200 || c.B != 200 {
+		t.Errorf("color = %v, want gray 200", c)
+	}
+	if _, err := ApplyAlphaMask(img, image.NewGray(image.Rect(0, 0, 1, 1))); err == nil {
+		t.Error("size mismatch: expected an error")
+	}
+}
@@ -0,0 +1,104 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// newImageTextToTextPipeline builds "image-text-to-text": chat with image
+// parts for vision-language models (SmolVLM, Idefics3). "text-generation"
+// routes these models here too, so the chat API is the same:
+//
+//	chat, _ := Pipeline("image-text-to-text", "HuggingFaceTB/SmolVLM-256M-Instruct", nil)
+//	out, _ := chat([]ChatMessage{{
+//		Role:  RoleUser,
+//		Parts: []ContentPart{ImagePart("screenshot.png"), TextPart("What does the error say?")},
+//	}}, map[string]any{"max_new_tokens": 64})
+//	// [{ "generated_text": [{ "role": "assistant", "content": "..." }] }]
+//
+// Each image part becomes an <image> placeholder in the rendered chat
+// template, which is expanded into the model's image tokens; their
+// embeddings are replaced by the vision encoder's features.
+func newImageTextToTextPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	model, err := AutoModelForImageTextToText.FromPretrainedWithOptions(
+		modelID,
+		config,
+		dtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	processor, err := model.ImageProcessor()
+	if err != nil {
+		return nil, fmt.Errorf("load image processor: %w", err)
+	}
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		messages, ok := inputs.([]ChatMessage)
+		if !ok {
+			return nil, fmt.Errorf("image-text-to-text: expected []ChatMessage input, got %T", inputs)
+		}
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+
+		var pixels []*PixelValues
+		for _, in := range chatImages(messages) {
+			img, err := imageFromInput(in)
+			if err != nil {
+				return nil, err
+			}
+			pv, err := processor.Preprocess(img)
+			if err != nil {
+				return nil, err
+			}
+			pixels = append(pixels, pv)
+		}
+
+		prompt, err := tokenizer.renderChatTemplate(messages)
+		if err != nil {
+			return nil, fmt.Errorf("chat template: %w", err)
+		}
+		if n := strings.Count(prompt, imagePlaceholder); n != len(pixels) {
+			return nil, fmt.Errorf("image-text-to-text: chat template kept %d of %d image placeholders", n, len(pixels))
+		}
+		ids, err := tokenizer.Encode(model.ExpandImagePrompt(tokenizer, prompt), true)
+		if err != nil {
+			return nil, fmt.Errorf("Encode: %w", err)
+		}
+
+		genOpts := chatGenerationOptions(config, callOptions)
+		generated, err := model.Generate(tokenizer, ids, pixels, genOpts)
+		if err != nil {
+			return nil, fmt.Errorf("Generate: %w", err)
+		}
+		text, err := tokenizer.Decode(generated)
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %w", err)
+		}
+		res := map[string]any{}
+		addGenerationStats(res, len(ids), generated, text, genOpts)
+		text = truncateAtStops(text, genOpts.StopSequences)
+		res["generated_text"] = []map[string]any{
+			{
+				"role":    "assistant",
+				"content": strings.TrimSpace(text),
+			},
+		}
+		return []map[string]any{res}, nil
+	}
+
+	return generator, nil
+}
@@ -0,0 +1,79 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// newImageToTextPipeline builds "image-to-text" (captioning, OCR) for
+// vision encoder-decoder exports:
+//
+//	captioner, _ := Pipeline("image-to-text", "Xenova/vit-gpt2-image-captioning", nil)
+//	out, _ := captioner("photo.jpg", nil)
+//	// [{ "generated_text": "a dog running on the beach" }]
+//
+// Batched inputs give one result per image, in order.
+func newImageToTextPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	processor, err := AutoImageProcessor.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load image processor: %w", err)
+	}
+	model, err := AutoModelForVision2Seq.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	params := config.GenerationConfig()
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		genOpts := GenerationOptions{
+			MaxNewTokens: intOption(callOptions, "max_new_tokens", intOption(params, "max_length", 64)),
+		}
+		if fn, ok := callOptions["streamer"].(func(PipelineStreamEvent) bool); ok {
+			genOpts.Streamer = fn
+		}
+
+		images := imageInputs(inputs)
+		out := make([]map[string]any, len(images))
+		for i, in := range images {
+			img, err := imageFromInput(in)
+			if err != nil {
+				return nil, err
+			}
+			pv, err := processor.Preprocess(img)
+			if err != nil {
+				return nil, err
+			}
+			ids, err := model.Generate(tokenizer, pv, genOpts)
+			if err != nil {
+				return nil, fmt.Errorf("Generate: %w", err)
+			}
+			text, err := tokenizer.Decode(ids)
+			if err != nil {
+				return nil, fmt.Errorf("Decode: %w", err)
+			}
+			out[i] = map[string]any{"generated_text": strings.TrimSpace(text)}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
@@ -0,0 +1,200 @@
+package transformers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// newObjectDetectionPipeline builds "object-detection" for set-prediction
+// detectors (DETR, YOLOS, RT-DETR, ...) whose graphs return per-query
+// class logits and normalized (cx, cy, w, h) boxes:
+//
+//	detector, _ := Pipeline("object-detection", "Xenova/detr-resnet-50", nil)
+//	out, _ := detector("street.jpg", map[string]any{"threshold": 0.9})
+//	// [{ "label": "car", "score": 0.99, "box": { "xmin": 12, "ymin": 40, "xmax": 210, "ymax": 160 } }, ...]
+//
+// Boxes are pixel coordinates in the original image, best score first.
+// For batched inputs each entry carries "input_index".
+func newObjectDetectionPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	processor, err := AutoImageProcessor.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load image processor: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	labels := id2Label(config)
+	// RT-DETR-style heads score each class independently; DETR and YOLOS
+	// softmax over the classes plus a trailing "no object" class.
+	sigmoid := strings.HasPrefix(config.ModelType(), "rt_detr") || config.ModelType() == "d_fine"
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		threshold := floatOption(callOptions, options, "threshold", 0.5)
+		nmsThreshold := floatOption(callOptions, options, "nms_threshold", 0)
+
+		images := imageInputs(inputs)
+		var out []map[string]any
+		for i, in := range images {
+			img, err := imageFromInput(in)
+			if err != nil {
+				return nil, err
+			}
+			pv, err := processor.Preprocess(img)
+			if err != nil {
+				return nil, err
+			}
+			outs, err := model.RunPixels(pv)
+			if err != nil {
+				return nil, err
+			}
+			logits, err := model.output(outs, "logits")
+			if err != nil {
+				return nil, err
+			}
+			boxes, err := model.output(outs, "pred_boxes", "boxes")
+			if err != nil {
+				return nil, err
+			}
+			dets, err := decodeDetections(logits, boxes, sigmoid, pv.OriginalSize, threshold)
+			if err != nil {
+				return nil, err
+			}
+			if nmsThreshold > 0 {
+				dets = nonMaxSuppression(dets, nmsThreshold)
+			}
+			for _, d := range dets {
+				entry := map[string]any{
+					"label": labelFor(labels, d.class),
+					"score": d.score,
+					"box": map[string]any{
+						"xmin": int(math.Round(d.box[0])),
+						"ymin": int(math.Round(d.box[1])),
+						"xmax": int(math.Round(d.box[2])),
+						"ymax": int(math.Round(d.box[3])),
+					},
+				}
+				if len(images) > 1 {
+					entry["input_index"] = i
+				}
+				out = append(out, entry)
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// detection is one predicted object; box is (xmin, ymin, xmax, ymax) in
+// original-image pixels.
+type detection struct {
+	class int
+	score float64
+	box   [4]float64
+}
+
+// decodeDetections turns [1, queries, classes] logits and [1, queries, 4]
+// normalized center boxes into detections above threshold, best first.
+// size is the original image (height, width). Softmax heads take each
+// query's best real class; sigmoid heads, as in HF's RT-DETR post-processing,
+// take the top `queries` (query, class) scores, so a query may yield more
+// than one class.
+func decodeDetections(logits, boxes floatOutput, sigmoid bool, size [2]int, threshold float64) ([]detection, error) {
+	if len(logits.shape) != 3 || len(boxes.shape) != 3 || boxes.shape[2] != 4 || logits.shape[1] != boxes.shape[1] {
+		return nil, fmt.Errorf("object-detection: unexpected output shapes %v and %v", logits.shape, boxes.shape)
+	}
+	queries, classes := int(logits.shape[1]), int(logits.shape[2])
+	if !sigmoid && classes < 2 {
+		return nil, fmt.Errorf("object-detection: softmax head has %d classes, want at least one besides \"no object\"", classes)
+	}
+	h, w := float64(size[0]), float64(size[1])
+	box := func(q int) [4]float64 {
+		b := boxes.data[q*4 : q*4+4]
+		cx, cy, bw, bh := float64(b[0]), float64(b[1]), float64(b[2]), float64(b[3])
+		return [4]float64{
+			math.Max(0, (cx-bw/2)*w),
+			math.Max(0, (cy-bh/2)*h),
+			math.Min(w, (cx+bw/2)*w),
+			math.Min(h, (cy+bh/2)*h),
+		}
+	}
+
+	var dets []detection
+	if sigmoid {
+		scores := append([]float32(nil), logits.data[:queries*classes]...)
+		if err := applyClassificationFunction("sigmoid", scores); err != nil {
+			return nil, err
+		}
+		for _, idx := range topIndices(scores, queries) {
+			if float64(scores[idx]) < threshold {
+				break
+			}
+			dets = append(dets, detection{class: idx % classes, score: float64(scores[idx]), box: box(idx / classes)})
+		}
+		return dets, nil
+	}
+	for q := 0; q < queries; q++ {
+		scores := append([]float32(nil), logits.data[q*classes:(q+1)*classes]...)
+		softmaxF32(scores)
+		scores = scores[:classes-1] // drop "no object"
+		best := topIndices(scores, 1)[0]
+		if float64(scores[best]) < threshold {
+			continue
+		}
+		dets = append(dets, detection{class: best, score: float64(scores[best]), box: box(q)})
+	}
+	sort.SliceStable(dets, func(a, b int) bool { return dets[a].score > dets[b].score })
+	return dets, nil
+}
+
+// nonMaxSuppression drops detections overlapping a better one of the same
+// class by more than iouThreshold. dets must be sorted best first.
+func nonMaxSuppression(dets []detection, iouThreshold float64) []detection {
+	var kept []detection
+	for _, d := range dets {
+		keep := true
+		for _, k := range kept {
+			if k.class == d.class && boxIoU(k.box, d.box) > iouThreshold {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// boxIoU is the intersection over union of two (xmin, ymin, xmax, ymax) boxes.
+func boxIoU(a, b [4]float64) float64 {
+	iw := math.Min(a[2], b[2]) - math.Max(a[0], b[0])
+	ih := math.Min(a[3], b[3]) - math.Max(a[1], b[1])
+	if iw <= 0 || ih <= 0 {
+		return 0
+	}
+	inter := iw * ih
+	union := (a[2]-a[0])*(a[3]-a[1]) + (b[2]-b[0])*(b[3]-b[1]) - inter
+	if union <= 0 {
+		return 0
+	}
+	return inter / union
+}
@@ -0,0 +1,104 @@
+package transformers
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestBoxIoU(t *testing.T) {
+	tests := []struct {
+		a, b [4]float64
+		want float64
+	}{
+		{[4]float64{0, 0, 10, 10}, [4]float64{0, 0, 10, 10}, 1},
+		{[4]float64{0, 0, 10, 10}, [4]float64{5, 0, 15, 10}, 50.0 / 150},
+		{[4]float64{0, 0, 10, 10}, [4]float64{10, 0, 20, 10}, 0}, // touching
+		{[4]float64{0, 0, 10, 10}, [4]float64{20, 20, 30, 30}, 0},
+		{[4]float64{0, 0, 0, 0}, [4]float64{0, 0, 0, 0}, 0}, // degenerate
+	}
+	for _, tt := range tests {
+		if got := boxIoU(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("boxIoU(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestNonMaxSuppression(t *testing.T) {
+	dets := []detection{
+		{class: 0, score: 0.9, box: [4]float64{0, 0, 10, 10}},
+		{class: 0, score: 0.8, box: [4]float64{1, 0, 11, 10}},   // overlaps the first
+		{class: 1, score: 0.7, box: [4]float64{0, 0, 10, 10}},   // other class
+		{class: 0, score: 0.6, box: [4]float64{50, 50, 60, 60}}, // elsewhere
+	}
+	kept := nonMaxSuppression(dets, 0.5)
+	var scores []float64
+	for _, d := range kept {
+		scores = append(scores, d.score)
+	}
+	if len(kept) != 3 || kept[0].score != 0.9 || kept[1].score != 0.7 || kept[2].score != 0.6 {
+		t.Fatalf("kept scores %v, want [0.9 0.7 0.6]", scores)
+	}
+}
+
+func TestDecodeDetectionsSoftmax(t *testing.T) {
+	// Two queries over (cat, dog, no object).
+	logits := floatOutput{shape: []int64{1, 2, 3}, data: []float32{
+		0, 5, 0, // dog
+		0, 0, 9, // no object
+	}}
+	boxes := floatOutput{shape: []int64{1, 2, 4}, data: []float32{
+		0.5, 0.5, 0.5, 0.5,
+		0.5, 0.5, 1, 1,
+	}}
+	dets, err := decodeDetections(logits, boxes, false, [2]int{100, 200}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 1 || dets[0].class != 1 {
+		t.Fatalf("detections = %+v, want one dog", dets)
+	}
+	if want := [4]float64{50, 25, 150, 75}; dets[0].box != want {
+		t.Fatalf("box = %v, want %v", dets[0].box, want)
+	}
+
+	one := floatOutput{shape: []int64{1, 2, 1}, data: []float32{1, 2}}
+	if _, err := decodeDetections(one, boxes, false, [2]int{100, 200}, 0.5); err == nil || !strings.Contains(err.Error(), "no object") {
+		t.Fatalf("single-class softmax head: err = %v", err)
+	}
+	if _, err := decodeDetections(logits, floatOutput{shape: []int64{1, 3, 4}}, false, [2]int{1, 1}, 0.5); err == nil {
+		t.Fatal("mismatched query counts accepted")
+	}
+}
+
+func TestDecodeDetectionsSigmoidTopK(t *testing.T) {
+	// Query 0 is both classes; query 1 is nothing; query 2 is weakly class 1.
+	logits := floatOutput{shape: []int64{1, 3, 2}, data: []float32{
+		4, 3,
+		-9, -9,
+		-9, 1,
+	}}
+	boxes := floatOutput{shape: []int64{1, 3, 4}, data: make([]float32, 12)}
+	dets, err := decodeDetections(logits, boxes, true, [2]int{10, 10}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, d := range dets {
+		got = append(got, d.class)
+	}
+	if len(dets) != 3 || dets[0].class != 0 || dets[1].class != 1 || dets[2].class != 1 {
+		t.Fatalf("classes = %v, want [0 1 1]: top-k over queries x classes", got)
+	}
+	for i := 1; i < len(dets); i++ {
+		if dets[i].score > dets[i-1].score {
+			t.Fatal("detections not sorted best first")
+		}
+	}
+
+	// Top-k keeps at most `queries` entries, even if more pass threshold.
+	dets, _ = decodeDetections(logits, boxes, true, [2]int{10, 10}, 0)
+	if len(dets) != 3 {
+		t.Fatalf("%d detections, want top 3", len(dets))
+	}
+}
@@ -0,0 +1,350 @@
+package transformers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// newQuestionAnsweringPipeline builds the extractive "question-answering"
+// task for SQuAD-style span heads (start_logits/end_logits):
+//
+//	qa, _ := Pipeline("question-answering", "Xenova/distilbert-base-cased-distilled-squad", nil)
+//	out, _ := qa(map[string]any{"question": "Who lives in Berlin?", "context": "Wolfgang lives in Berlin."}, nil)
+//	// [{ "answer": "Wolfgang", "score": 0.98, "start": 0, "end": 8 }]
+//
+// Inputs are {"question", "context"} maps (or a list of them), or a question
+// string with "context" in the call options. start/end are character (rune)
+// offsets into the context, as in HF. Contexts longer than max_seq_len are
+// split into windows that overlap by doc_stride tokens, run batch_size
+// windows at a time.
+func newQuestionAnsweringPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	// Windows are cut here, by max_seq_len and doc_stride; truncation from
+	// tokenizer.json would drop the context before they are.
+	tokenizer = tokenizer.untruncated()
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		pairs, err := questionContextInputs(inputs, stringOption(callOptions, options, "context"))
+		if err != nil {
+			return nil, err
+		}
+		opts := qaOptions{
+			topK:            intOption(callOptions, "top_k", intOption(options, "top_k", 1)),
+			docStride:       intOption(callOptions, "doc_stride", intOption(options, "doc_stride", 128)),
+			maxSeqLen:       intOption(callOptions, "max_seq_len", intOption(options, "max_seq_len", 384)),
+			maxAnswerLen:    intOption(callOptions, "max_answer_len", intOption(options, "max_answer_len", 15)),
+			allowImpossible: boolOption(callOptions, options, "handle_impossible_answer"),
+			batchSize:       intOption(callOptions, "batch_size", intOption(options, "batch_size", 32)),
+		}
+		opts.maxSeqLen = min(opts.maxSeqLen, model.maxSequenceLength())
+
+		var out []map[string]any
+		for i, p := range pairs {
+			answers, err := answerQuestion(model, tokenizer, p[0], p[1], opts)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range answers {
+				m := map[string]any{
+					"answer": a.answer,
+					"score":  a.score,
+					"start":  runeOffset(p[1], a.start),
+					"end":    runeOffset(p[1], a.end),
+				}
+				if len(pairs) > 1 {
+					m["input_index"] = i
+				}
+				out = append(out, m)
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+type qaOptions struct {
+	topK            int
+	docStride       int
+	maxSeqLen       int
+	maxAnswerLen    int
+	allowImpossible bool
+	batchSize       int // windows per model run; <= 0 runs all at once
+}
+
+type qaAnswer struct {
+	answer     string
+	score      float64
+	start, end int // byte offsets into the context
+}
+
+// answerQuestion scores every span of every context window and returns the
+// top_k answers, best first.
+func answerQuestion(model *EncoderModel, tokenizer *Tokenizer, question, context string, opts qaOptions) ([]qaAnswer, error) {
+	enc, err := tokenizer.EncodePairWithOffsets(question, context)
+	if err != nil {
+		return nil, fmt.Errorf("Encode: %w", err)
+	}
+	windows, err := qaWindows(enc, opts.maxSeqLen, opts.docStride)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize := opts.batchSize
+	if batchSize <= 0 {
+		batchSize = len(windows)
+	}
+	var answers []qaAnswer
+	nullBest := math.Inf(1)
+	for lo := 0; lo < len(windows); lo += batchSize {
+		chunk := windows[lo:min(lo+batchSize, len(windows))]
+		rows := make([][]int64, len(chunk))
+		types := make([][]int64, len(chunk))
+		for i, w := range chunk {
+			rows[i], types[i] = w.ids, w.typeIDs
+		}
+		batch := newEncoderBatch(rows, types, model.padID())
+		outs, err := model.Run(batch)
+		if err != nil {
+			return nil, err
+		}
+		startLogits, err := model.output(outs, "start_logits")
+		if err != nil {
+			return nil, err
+		}
+		endLogits, err := model.output(outs, "end_logits")
+		if err != nil {
+			return nil, err
+		}
+		for i, w := range chunk {
+			from, to := i*batch.seqLen, i*batch.seqLen+len(w.ids)
+			spans, null := qaSpans(w, enc, context, startLogits.data[from:to], endLogits.data[from:to], opts.maxAnswerLen)
+			answers = append(answers, spans...)
+			nullBest = math.Min(nullBest, null)
+		}
+	}
+	if opts.allowImpossible {
+		answers = append(answers, qaAnswer{score: nullBest})
+	}
+
+	sort.SliceStable(answers, func(a, b int) bool { return answers[a].score > answers[b].score })
+	// Overlapping windows score the same span more than once; keep the best.
+	seen := map[[2]int]bool{}
+	var top []qaAnswer
+	for _, a := range answers {
+		key := [2]int{a.start, a.end}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		top = append(top, a)
+		if opts.topK > 0 && len(top) == opts.topK {
+			break
+		}
+	}
+	return top, nil
+}
+
+// qaSpans scores the context spans of one window, at most maxAnswerLen
+// tokens long, from its start and end logits. null is the score of the
+// first token ([CLS]/<s>), which stands for "no answer".
+func qaSpans(w qaWindow, enc *Encoding, context string, startLogits, endLogits []float32, maxAnswerLen int) (answers []qaAnswer, null float64) {
+	n := len(w.ids)
+	starts := qaProbs(startLogits, w.isContext)
+	ends := qaProbs(endLogits, w.isContext)
+	for s := 1; s < n; s++ {
+		if !w.isContext[s] {
+			continue
+		}
+		for e := s; e < n && e < s+maxAnswerLen; e++ {
+			if !w.isContext[e] {
+				continue
+			}
+			span := [2]int{enc.Offsets[w.tokenIndex[s]][0], enc.Offsets[w.tokenIndex[e]][1]}
+			answers = append(answers, qaAnswer{
+				answer: spanText(context, span[0], span[1]),
+				score:  starts[s] * ends[e],
+				start:  span[0],
+				end:    span[1],
+			})
+		}
+	}
+	return answers, starts[0] * ends[0]
+}
+
+// qaProbs softmaxes logits over the context tokens and the first token;
+// every other position gets probability 0.
+func qaProbs(logits []float32, isContext []bool) []float64 {
+	probs := make([]float64, len(logits))
+	maxV := math.Inf(-1)
+	for j, v := range logits {
+		if j == 0 || isContext[j] {
+			maxV = math.Max(maxV, float64(v))
+		}
+	}
+	var sum float64
+	for j, v := range logits {
+		if j == 0 || isContext[j] {
+			probs[j] = math.Exp(float64(v) - maxV)
+			sum += probs[j]
+		}
+	}
+	for j := range probs {
+		probs[j] /= sum
+	}
+	return probs
+}
+
+// qaWindow is one model input: the question plus a slice of the context.
+type qaWindow struct {
+	ids        []int64
+	typeIDs    []int64
+	isContext  []bool
+	tokenIndex []int // position in the full encoding
+}
+
+// qaWindows splits a (question, context) encoding into windows of at most
+// maxLen tokens. Each window repeats the special tokens and the question and
+// holds a run of context tokens; consecutive runs overlap by stride tokens.
+func qaWindows(enc *Encoding, maxLen, stride int) ([]qaWindow, error) {
+	// Layout: specials, question, specials, context, specials.
+	n := len(enc.IDs)
+	j := 0
+	for j < n && enc.SpecialTokensMask[j] != 0 {
+		j++
+	}
+	for j < n && enc.SpecialTokensMask[j] == 0 {
+		j++
+	}
+	for j < n && enc.SpecialTokensMask[j] != 0 {
+		j++
+	}
+	ctxStart := j
+	ctxEnd := n
+	for ctxEnd > ctxStart && enc.SpecialTokensMask[ctxEnd-1] != 0 {
+		ctxEnd--
+	}
+	if ctxStart >= ctxEnd {
+		return nil, fmt.Errorf("question-answering: empty context")
+	}
+
+	room := maxLen - ctxStart - (n - ctxEnd)
+	if room <= 0 {
+		return nil, fmt.Errorf("question-answering: question is too long for max_seq_len %d", maxLen)
+	}
+	step := room - min(stride, room/2)
+	if step <= 0 {
+		step = 1
+	}
+
+	var windows []qaWindow
+	for lo := ctxStart; ; lo += step {
+		hi := min(lo+room, ctxEnd)
+		var w qaWindow
+		add := func(k int, isCtx bool) {
+			w.ids = append(w.ids, enc.IDs[k])
+			w.typeIDs = append(w.typeIDs, enc.TypeIDs[k])
+			w.isContext = append(w.isContext, isCtx)
+			w.tokenIndex = append(w.tokenIndex, k)
+		}
+		for k := 0; k < ctxStart; k++ {
+			add(k, false)
+		}
+		for k := lo; k < hi; k++ {
+			add(k, true)
+		}
+		for k := ctxEnd; k < n; k++ {
+			add(k, false)
+		}
+		windows = append(windows, w)
+		if hi == ctxEnd {
+			break
+		}
+	}
+	return windows, nil
+}
+
+// questionContextInputs accepts {"question", "context"} maps, lists of them,
+// or question strings answered against defaultContext.
+func questionContextInputs(inputs any, defaultContext string) ([][2]string, error) {
+	one := func(x any) ([2]string, error) {
+		switch t := x.(type) {
+		case string:
+			if defaultContext == "" {
+				return [2]string{}, fmt.Errorf("question-answering: a question string needs the \"context\" option")
+			}
+			return [2]string{t, defaultContext}, nil
+		case map[string]any:
+			q, _ := t["question"].(string)
+			c, _ := t["context"].(string)
+			if q == "" || c == "" {
+				return [2]string{}, fmt.Errorf("question-answering: input needs \"question\" and \"context\" strings")
+			}
+			return [2]string{q, c}, nil
+		case map[string]string:
+			if t["question"] == "" || t["context"] == "" {
+				return [2]string{}, fmt.Errorf("question-answering: input needs \"question\" and \"context\" strings")
+			}
+			return [2]string{t["question"], t["context"]}, nil
+		}
+		return [2]string{}, fmt.Errorf("question-answering: unsupported input %T", x)
+	}
+
+	var items []any
+	switch t := inputs.(type) {
+	case []map[string]any:
+		for _, m := range t {
+			items = append(items, m)
+		}
+	case []string:
+		for _, s := range t {
+			items = append(items, s)
+		}
+	case []any:
+		items = t
+	default:
+		items = []any{inputs}
+	}
+	out := make([][2]string, len(items))
+	for i, x := range items {
+		p, err := one(x)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = p
+	}
+	return out, nil
+}
+
+// boolOption reads a bool option from the call options first, then the
+// pipeline options.
+func boolOption(callOptions, options map[string]any, key string) bool {
+	if v, ok := callOptions[key].(bool); ok {
+		return v
+	}
+	v, _ := options[key].(bool)
+	return v
+}
@@ -0,0 +1,162 @@
+package transformers
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+)
+
+// qaTestEncoding is [CLS] q q [SEP] aa bb cc dd ee ff [SEP] over the context
+// "aa bb cc dd ee ff".
+func qaTestEncoding() *Encoding {
+	enc := &Encoding{}
+	add := func(id int64, special bool, off [2]int) {
+		enc.IDs = append(enc.IDs, id)
+		enc.TypeIDs = append(enc.TypeIDs, 0)
+		enc.Offsets = append(enc.Offsets, off)
+		mask := int64(0)
+		if special {
+			mask = 1
+		}
+		enc.SpecialTokensMask = append(enc.SpecialTokensMask, mask)
+	}
+	add(101, true, [2]int{})
+	add(1, false, [2]int{0, 1})
+	add(2, false, [2]int{2, 3})
+	add(102, true, [2]int{})
+	for i := 0; i < 6; i++ {
+		add(int64(10+i), false, [2]int{3 * i, 3*i + 2})
+	}
+	add(102, true, [2]int{})
+	return enc
+}
+
+func TestQAWindows(t *testing.T) {
+	enc := qaTestEncoding()
+	tests := []struct {
+		name    string
+		maxLen  int
+		stride  int
+		want    [][]int // context token indices per window
+		wantErr string
+	}{
+		{"one window", 16, 2, [][]int{{4, 5, 6, 7, 8, 9}}, ""},
+		{"stride overlap", 8, 2, [][]int{{4, 5, 6}, {6, 7, 8}, {8, 9}}, ""},
+		{"no stride", 8, 0, [][]int{{4, 5, 6}, {7, 8, 9}}, ""},
+		{"stride capped at half", 9, 10, [][]int{{4, 5, 6, 7}, {6, 7, 8, 9}}, ""},
+		{"question fills the window", 5, 2, nil, "question is too long"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows, err := qaWindows(enc, tt.maxLen, tt.stride)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got [][]int
+			for _, w := range windows {
+				if len(w.ids) > tt.maxLen {
+					t.Fatalf("window of %d tokens exceeds %d", len(w.ids), tt.maxLen)
+				}
+				// Every window repeats [CLS] q q [SEP] and ends with [SEP].
+				if !reflect.DeepEqual(w.tokenIndex[:4], []int{0, 1, 2, 3}) || w.tokenIndex[len(w.tokenIndex)-1] != 10 {
+					t.Fatalf("window layout %v", w.tokenIndex)
+				}
+				var ctx []int
+				for k, isCtx := range w.isContext {
+					if isCtx {
+						ctx = append(ctx, w.tokenIndex[k])
+					}
+				}
+				got = append(got, ctx)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("context windows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A question with no context after it.
+	empty := &Encoding{
+		IDs:               []int64{101, 1, 102},
+		TypeIDs:           []int64{0, 0, 0},
+		Offsets:           make([][2]int, 3),
+		SpecialTokensMask: []int64{1, 0, 1},
+	}
+	if _, err := qaWindows(empty, 8, 2); err == nil || !strings.Contains(err.Error(), "empty context") {
+		t.Fatalf("empty context: err = %v", err)
+	}
+}
+
+func TestQAProbsMasksQuestion(t *testing.T) {
+	probs := qaProbs([]float32{0, 100, 0, 0}, []bool{false, false, true, true})
+	if probs[1] != 0 {
+		t.Fatalf("question token got probability %v", probs[1])
+	}
+	var sum float64
+	for _, p := range probs {
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-9 || math.Abs(probs[0]-1.0/3) > 1e-9 {
+		t.Fatalf("probs = %v, want [1/3 0 1/3 1/3]", probs)
+	}
+}
+
+func TestQASpans(t *testing.T) {
+	const context = "aa bb cc dd ee ff"
+	enc := qaTestEncoding()
+	windows, err := qaWindows(enc, 16, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := windows[0]
+	start := make([]float32, len(w.ids))
+	end := make([]float32, len(w.ids))
+	start[2], end[2] = 50, 50 // question tokens: masked out
+	start[5], end[7] = 10, 10 // "bb" .. "dd"
+
+	spans, null := qaSpans(w, enc, context, start, end, 15)
+	sort.SliceStable(spans, func(a, b int) bool { return spans[a].score > spans[b].score })
+	if best := spans[0]; best.answer != "bb cc dd" || best.start != 3 || best.end != 11 {
+		t.Fatalf("best span = %+v, want \"bb cc dd\" [3, 11)", best)
+	}
+	if null >= spans[0].score {
+		t.Fatalf("null score %v beats the answer %v", null, spans[0].score)
+	}
+	for _, s := range spans {
+		if s.start < 0 || s.end > len(context) || s.start >= s.end {
+			t.Fatalf("span %+v outside the context", s)
+		}
+	}
+
+	spans, _ = qaSpans(w, enc, context, start, end, 2)
+	for _, s := range spans {
+		if s.answer == "bb cc dd" {
+			t.Fatal("span longer than max_answer_len kept")
+		}
+	}
+}
+
+func TestUntruncated(t *testing.T) {
+	tok := stubTokenizer(t, "a")
+	if tok.untruncated() != tok {
+		t.Fatal("a tokenizer without truncation must be returned as is")
+	}
+	tok.tok.WithTruncation(&tokenizer.TruncationParams{MaxLength: 4})
+	u := tok.untruncated()
+	if u.tok.GetTruncation() != nil {
+		t.Fatal("truncation kept")
+	}
+	if tok.tok.GetTruncation() == nil {
+		t.Fatal("the shared tokenizer lost its truncation")
+	}
+}
@@ -0,0 +1,235 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// newText2TextPipeline builds the encoder-decoder tasks:
+// "text2text-generation", "summarization", "translation" and
+// "translation_xx_to_yy".
+//
+//	translator, _ := Pipeline("translation", "Xenova/nllb-200-distilled-600M", nil)
+//	out, _ := translator("Hello", map[string]any{"src_lang": "eng_Latn", "tgt_lang": "fra_Latn"})
+//	// [{ "translation_text": "Bonjour" }]
+func newText2TextPipeline(
+	task string,
+	modelID string,
+	options map[string]any,
+) (Generator, error) {
+	dtype := dtypeOption(options)
+
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	model, err := AutoModelForSeq2SeqLM.FromPretrainedWithOptions(
+		modelID,
+		config,
+		dtype,
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+
+	outputKey := "generated_text"
+	switch {
+	case task == "summarization":
+		outputKey = "summary_text"
+	case strings.HasPrefix(task, "translation"):
+		outputKey = "translation_text"
+	}
+	// translation_en_to_fr names its languages in the task itself.
+	taskSrc, taskTgt := parseTranslationTask(task)
+	multilingual := isMultilingualTranslator(tokenizer, config)
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		texts, err := textInputs(task, inputs)
+		if err != nil {
+			return nil, err
+		}
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+
+		srcLang := stringOption(callOptions, options, "src_lang")
+		if srcLang == "" {
+			srcLang = taskSrc
+		}
+		tgtLang := stringOption(callOptions, options, "tgt_lang")
+		if tgtLang == "" {
+			tgtLang = taskTgt
+		}
+
+		// T5-style models describe each task with a text prefix and length
+		// defaults in config.json task_specific_params.
+		params := text2textTaskParams(config, task, srcLang, tgtLang)
+		prefix := stringOption(callOptions, options, "prefix")
+		if prefix == "" {
+			prefix, _ = params["prefix"].(string)
+		}
+
+		maxNewTokens := intOption(callOptions, "max_new_tokens", intOption(params, "max_length", 128))
+		var streamerFn func(PipelineStreamEvent) bool
+		if fn, ok := callOptions["streamer"].(func(PipelineStreamEvent) bool); ok {
+			streamerFn = fn
+		}
+
+		out := make([]map[string]any, 0, len(texts))
+		for _, text := range texts {
+			inputIDs, tgtID, tgtFound, err := encodeSeq2SeqSource(tokenizer, config, prefix+text, srcLang, tgtLang)
+			if err != nil {
+				return nil, err
+			}
+			if !tgtFound && tgtLang != "" && prefix == "" && multilingual {
+				return nil, fmt.Errorf("%s: unknown target language %q for %s", task, tgtLang, modelID)
+			}
+
+			genOpts := GenerationOptions{
+				MaxNewTokens: maxNewTokens,
+				Streamer:     streamerFn,
+			}
+			if tgtID >= 0 {
+				// mBART/NLLB/M2M100: the target language is forced as the
+				// first generated token (forced_bos_token_id).
+				genOpts.DecoderInputIDs = []int64{model.decoderStartID(), tgtID}
+			}
+
+			attn := make([]int64, len(inputIDs))
+			for i := range attn {
+				attn[i] = 1
+			}
+			generated, err := model.Generate(tokenizer, [][]int64{inputIDs}, [][]int64{attn}, genOpts)
+			if err != nil {
+				return nil, fmt.Errorf("Generate: %w", err)
+			}
+			txt, err := tokenizer.Decode(generated[0])
+			if err != nil {
+				return nil, fmt.Errorf("Decode: %w", err)
+			}
+			out = append(out, map[string]any{outputKey: strings.TrimSpace(txt)})
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// parseTranslationTask splits "translation_en_to_fr" into ("en", "fr").
+func parseTranslationTask(task string) (string, string) {
+	rest, ok := strings.CutPrefix(task, "translation_")
+	if !ok {
+		return "", ""
+	}
+	src, tgt, ok := strings.Cut(rest, "_to_")
+	if !ok {
+		return "", ""
+	}
+	return src, tgt
+}
+
+// text2textTaskParams returns config.json task_specific_params for the task,
+// e.g. {"prefix": "translate English to German: ", "max_length": 300}.
+func text2textTaskParams(cfg *Config, task, srcLang, tgtLang string) map[string]any {
+	tsp, _ := cfg.Raw()["task_specific_params"].(map[string]any)
+	if tsp == nil {
+		return map[string]any{}
+	}
+	key := task
+	if task == "translation" && srcLang != "" && tgtLang != "" {
+		key = "translation_" + srcLang + "_to_" + tgtLang
+	}
+	params, _ := tsp[key].(map[string]any)
+	if params == nil {
+		return map[string]any{}
+	}
+	return params
+}
+
+// langTokenFor finds the vocabulary token for a language code across the
+// conventions used by multilingual seq2seq models: NLLB "fra_Latn" and
+// mBART "fr_XX" (verbatim), M2M100 "__fr__" and Marian multi-target ">>fra<<".
+func langTokenFor(tok *Tokenizer, lang string) (string, int64, bool) {
+	for _, cand := range []string{lang, "__" + lang + "__", ">>" + lang + "<<"} {
+		if id, ok := tok.TokenToID(cand); ok {
+			return cand, id, true
+		}
+	}
+	return "", -1, false
+}
+
+// isMultilingualTranslator reports whether the model picks its target
+// language from a language token: mBART, M2M100 and NLLB always do, Marian
+// only when its vocabulary has ">>xxx<<" tokens. Single-pair models
+// (opus-mt-en-fr) translate to their one target whatever tgt_lang says.
+func isMultilingualTranslator(tok *Tokenizer, cfg *Config) bool {
+	switch cfg.ModelType() {
+	case "mbart", "m2m_100", "nllb-moe":
+		return true
+	}
+	for token := range tok.tok.GetVocab(true) {
+		if strings.HasPrefix(token, ">>") && strings.HasSuffix(token, "<<") {
+			return true
+		}
+	}
+	return false
+}
+
+// encodeSeq2SeqSource tokenizes the encoder input. A source language token
+// replaces the tokenizer's default prefix ([src_lang] text </s>), and a
+// Marian target token is prepended to the text. tgtID is the token to force
+// as the first decoder output, or -1; tgtFound reports whether tgtLang was
+// applied either way.
+func encodeSeq2SeqSource(
+	tok *Tokenizer,
+	cfg *Config,
+	text string,
+	srcLang string,
+	tgtLang string,
+) (ids []int64, tgtID int64, tgtFound bool, err error) {
+	tgtID = -1
+	if tgtLang != "" {
+		if token, id, ok := langTokenFor(tok, tgtLang); ok {
+			tgtFound = true
+			if strings.HasPrefix(token, ">>") {
+				// Marian multi-target models take the target as a text prefix.
+				text = token + " " + text
+			} else {
+				tgtID = id
+			}
+		}
+	}
+
+	srcID := int64(-1)
+	if srcLang != "" {
+		if token, id, ok := langTokenFor(tok, srcLang); ok && !strings.HasPrefix(token, ">>") {
+			srcID = id
+		}
+	}
+	if srcID < 0 {
+		ids, err = tok.Encode(text, true)
+		return ids, tgtID, tgtFound, err
+	}
+
+	body, err := tok.Encode(text, false)
+	if err != nil {
+		return nil, -1, false, err
+	}
+	eos, ok := tok.TokenToID("</s>")
+	if !ok {
+		eos = cfg.EOS_TOKEN_ID()
+	}
+	ids = append([]int64{srcID}, body...)
+	if eos >= 0 {
+		ids = append(ids, eos)
+	}
+	return ids, tgtID, tgtFound, nil
+}
@@ -0,0 +1,104 @@
+package transformers
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/sugarme/tokenizer"
+	"github.com/sugarme/tokenizer/model/wordlevel"
+	"github.com/sugarme/tokenizer/pretokenizer"
+)
+
+// stubTokenizer is a whitespace word-level tokenizer over vocab, with no
+// special tokens added.
+func stubTokenizer(t *testing.T, vocab ...string) *Tokenizer {
+	t.Helper()
+	ids := map[string]int{"<unk>": 0}
+	for _, w := range vocab {
+		ids[w] = len(ids)
+	}
+	m, err := wordlevel.New(ids, "<unk>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok := tokenizer.NewTokenizer(m)
+	tok.WithPreTokenizer(pretokenizer.NewWhitespaceSplit())
+	return &Tokenizer{tok: tok}
+}
+
+func TestLangTokenFor(t *testing.T) {
+	tok := stubTokenizer(t, "fra_Latn", "__de__", ">>spa<<", "hello")
+	tests := []struct {
+		lang  string
+		token string
+		ok    bool
+	}{
+		{"fra_Latn", "fra_Latn", true},
+		{"de", "__de__", true},
+		{"spa", ">>spa<<", true},
+		{"fr", "", false},
+	}
+	for _, tt := range tests {
+		token, id, ok := langTokenFor(tok, tt.lang)
+		if token != tt.token || ok != tt.ok {
+			t.Errorf("langTokenFor(%q) = %q, %v; want %q, %v", tt.lang, token, ok, tt.token, tt.ok)
+		}
+		if want, _ := tok.TokenToID(tt.token); ok && id != want {
+			t.Errorf("langTokenFor(%q) id = %d, want %d", tt.lang, id, want)
+		}
+	}
+}
+
+func TestEncodeSeq2SeqSource(t *testing.T) {
+	vocab := []string{"</s>", "hello", "world", "eng_Latn", "fra_Latn", ">>fra<<", ">>deu<<"}
+	tok := stubTokenizer(t, vocab...)
+	id := func(w string) int64 { v, _ := tok.TokenToID(w); return v }
+	cfg := &Config{modelType: "marian", eosTokenID: -1}
+
+	tests := []struct {
+		name        string
+		src, tgt    string
+		wantIDs     []int64
+		wantTgtID   int64
+		wantTgtSeen bool
+	}{
+		{"plain", "", "", []int64{id("hello"), id("world")}, -1, false},
+		{"marian prefix", "", "fra", []int64{id(">>fra<<"), id("hello"), id("world")}, -1, true},
+		{"forced bos", "", "fra_Latn", []int64{id("hello"), id("world")}, id("fra_Latn"), true},
+		{"source token", "eng_Latn", "fra_Latn", []int64{id("eng_Latn"), id("hello"), id("world"), id("</s>")}, id("fra_Latn"), true},
+		{"unknown target", "", "fr", []int64{id("hello"), id("world")}, -1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, tgtID, found, err := encodeSeq2SeqSource(tok, cfg, "hello world", tt.src, tt.tgt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(ids, tt.wantIDs) || tgtID != tt.wantTgtID || found != tt.wantTgtSeen {
+				t.Errorf("got %v, %d, %v; want %v, %d, %v", ids, tgtID, found, tt.wantIDs, tt.wantTgtID, tt.wantTgtSeen)
+			}
+		})
+	}
+}
+
+func TestIsMultilingualTranslator(t *testing.T) {
+	single := stubTokenizer(t, "hello", "world")
+	multi := stubTokenizer(t, "hello", ">>fra<<")
+	tests := []struct {
+		name      string
+		tok       *Tokenizer
+		modelType string
+		want      bool
+	}{
+		{"opus-mt single pair", single, "marian", false},
+		{"opus-mt multi target", multi, "marian", true},
+		{"nllb", single, "m2m_100", true},
+		{"mbart", single, "mbart", true},
+		{"t5", single, "t5", false},
+	}
+	for _, tt := range tests {
+		if got := isMultilingualTranslator(tt.tok, &Config{modelType: tt.modelType}); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
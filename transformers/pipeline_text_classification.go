@@ -0,0 +1,246 @@
+package transformers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// newTextClassificationPipeline builds "text-classification" (alias
+// "sentiment-analysis") for sequence-classification exports:
+//
+//	clf, _ := Pipeline("sentiment-analysis", "Xenova/distilbert-base-uncased-finetuned-sst-2-english", nil)
+//	out, _ := clf("I love this!", nil)
+//	// [{ "label": "POSITIVE", "score": 0.9998 }]
+//
+// Inputs are a string, []string, a text pair {"text": ..., "text_pair": ...}
+// or a list of those. With top_k > 1 (or top_k <= 0 for all labels) the
+// result holds top_k entries per input, in input order. Where HF returns
+// one list per input, the result is flat: for batched inputs each entry
+// carries "input_index" (see GroupClassificationResults). Inputs run
+// batch_size at a time (default 32).
+func newTextClassificationPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	labels := id2Label(config)
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		pairs, err := textPairInputs("text-classification", inputs)
+		if err != nil {
+			return nil, err
+		}
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		topK := intOption(callOptions, "top_k", intOption(options, "top_k", 1))
+		fn := stringOption(callOptions, options, "function_to_apply")
+		batchSize := intOption(callOptions, "batch_size", intOption(options, "batch_size", 32))
+		if batchSize <= 0 {
+			batchSize = len(pairs)
+		}
+
+		var (
+			logits    []float32
+			numLabels int
+		)
+		for lo := 0; lo < len(pairs); lo += batchSize {
+			chunk, n, err := classifyPairs(model, tokenizer, pairs[lo:min(lo+batchSize, len(pairs))], model.maxSequenceLength(), truncateLongestFirst)
+			if err != nil {
+				return nil, err
+			}
+			logits, numLabels = append(logits, chunk...), n
+		}
+		if fn == "" {
+			fn = defaultClassificationFunction(config, numLabels)
+		}
+
+		var out []map[string]any
+		for i := range pairs {
+			scores := logits[i*numLabels : (i+1)*numLabels]
+			if err := applyClassificationFunction(fn, scores); err != nil {
+				return nil, err
+			}
+			for _, idx := range topIndices(scores, topK) {
+				entry := map[string]any{
+					"label": labelFor(labels, idx),
+					"score": float64(scores[idx]),
+				}
+				if len(pairs) > 1 {
+					entry["input_index"] = i
+				}
+				out = append(out, entry)
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// classifyPairs runs pairs as one padded batch, each truncated to maxLen
+// tokens by strategy, and returns the logits, row-major [len(pairs),
+// numLabels].
+func classifyPairs(model *EncoderModel, tokenizer *Tokenizer, pairs [][2]string, maxLen int, strategy truncation) ([]float32, int, error) {
+	rows := make([][]int64, len(pairs))
+	types := make([][]int64, len(pairs))
+	for i, p := range pairs {
+		ids, tt, err := tokenizer.encodeWithTypes(p[0], p[1], maxLen, strategy)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Encode: %w", err)
+		}
+		rows[i], types[i] = ids, tt
+	}
+	outs, err := model.Run(newEncoderBatch(rows, types, model.padID()))
+	if err != nil {
+		return nil, 0, err
+	}
+	logits, err := model.output(outs, "logits")
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(logits.shape) != 2 || int(logits.shape[0]) != len(pairs) {
+		return nil, 0, fmt.Errorf("unexpected logits shape %v", logits.shape)
+	}
+	return logits.data, int(logits.shape[1]), nil
+}
+
+// textPairInputs accepts a string, []string, {"text", "text_pair"} maps or
+// lists of those, and returns (text, pair) tuples; pair is "" for single
+// texts.
+func textPairInputs(task string, inputs any) ([][2]string, error) {
+	one := func(x any) ([2]string, error) {
+		switch t := x.(type) {
+		case string:
+			return [2]string{t, ""}, nil
+		case [2]string:
+			return t, nil
+		case map[string]any:
+			text, ok := t["text"].(string)
+			if !ok {
+				return [2]string{}, fmt.Errorf("%s: pair input needs a \"text\" string", task)
+			}
+			pair, _ := t["text_pair"].(string)
+			return [2]string{text, pair}, nil
+		case map[string]string:
+			return [2]string{t["text"], t["text_pair"]}, nil
+		}
+		return [2]string{}, fmt.Errorf("%s: unsupported input %T", task, x)
+	}
+
+	var items []any
+	switch t := inputs.(type) {
+	case []string:
+		for _, s := range t {
+			items = append(items, s)
+		}
+	case [][2]string:
+		for _, p := range t {
+			items = append(items, p)
+		}
+	case []map[string]any:
+		for _, m := range t {
+			items = append(items, m)
+		}
+	case []any:
+		items = t
+	default:
+		items = []any{inputs}
+	}
+
+	out := make([][2]string, len(items))
+	for i, x := range items {
+		p, err := one(x)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = p
+	}
+	return out, nil
+}
+
+// id2Label returns config.json id2label as a slice indexed by class ID.
+func id2Label(cfg *Config) []string {
+	m, _ := cfg.Raw()["id2label"].(map[string]any)
+	var labels []string
+	for k, v := range m {
+		idx, err := strconv.Atoi(k)
+		name, ok := v.(string)
+		if err != nil || !ok || idx < 0 {
+			continue
+		}
+		for len(labels) <= idx {
+			labels = append(labels, "")
+		}
+		labels[idx] = name
+	}
+	return labels
+}
+
+// labelFor names class idx, falling back to HF's LABEL_<idx>.
+func labelFor(labels []string, idx int) string {
+	if idx < len(labels) && labels[idx] != "" {
+		return labels[idx]
+	}
+	return "LABEL_" + strconv.Itoa(idx)
+}
+
+// defaultClassificationFunction follows HF: none for regression, sigmoid
+// for multi-label or single-logit heads, softmax otherwise.
+func defaultClassificationFunction(cfg *Config, numLabels int) string {
+	switch problem, _ := cfg.Raw()["problem_type"].(string); {
+	case problem == "regression":
+		return "none"
+	case problem == "multi_label_classification" || numLabels == 1:
+		return "sigmoid"
+	}
+	return "softmax"
+}
+
+// applyClassificationFunction turns logits into scores in place.
+func applyClassificationFunction(fn string, xs []float32) error {
+	switch fn {
+	case "softmax":
+		softmaxF32(xs)
+	case "sigmoid":
+		for i, v := range xs {
+			xs[i] = float32(1 / (1 + math.Exp(-float64(v))))
+		}
+	case "none":
+	default:
+		return fmt.Errorf("function_to_apply must be softmax, sigmoid or none, got %q", fn)
+	}
+	return nil
+}
+
+// topIndices returns the indices of the k largest scores, best first; k <= 0
+// or k > len(scores) returns all of them.
+func topIndices(scores []float32, k int) []int {
+	idx := make([]int, len(scores))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return scores[idx[a]] > scores[idx[b]] })
+	if k > 0 && k < len(idx) {
+		idx = idx[:k]
+	}
+	return idx
+}
@@ -0,0 +1,143 @@
+package transformers
+
+import (
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestTopIndices(t *testing.T) {
+	scores := []float32{0.1, 0.7, 0.1, 0.05, 0.05}
+	tests := []struct {
+		k    int
+		want []int
+	}{
+		{1, []int{1}},
+		{2, []int{1, 0}},
+		{0, []int{1, 0, 2, 3, 4}}, // ties keep index order
+		{-1, []int{1, 0, 2, 3, 4}},
+		{9, []int{1, 0, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		if got := topIndices(scores, tt.k); !slices.Equal(got, tt.want) {
+			t.Errorf("topIndices(k=%d) = %v, want %v", tt.k, got, tt.want)
+		}
+	}
+	if got := topIndices(nil, 3); len(got) != 0 {
+		t.Errorf("topIndices(nil) = %v, want none", got)
+	}
+}
+
+func TestTruncateKeepingEndsLongestFirst(t *testing.T) {
+	// [CLS] a1..a4 [SEP] b1 b2 [SEP] with BERT type IDs.
+	ids := []int64{101, 1, 2, 3, 4, 102, 5, 6, 102}
+	types := []int64{0, 0, 0, 0, 0, 0, 1, 1, 1}
+	special := []int64{1, 0, 0, 0, 0, 1, 0, 0, 1}
+	tests := []struct {
+		maxLen    int
+		wantIDs   []int64
+		wantTypes []int64
+	}{
+		{9, ids, types},
+		{7, []int64{101, 1, 2, 102, 5, 6, 102}, []int64{0, 0, 0, 0, 1, 1, 1}},
+		// Once both sides are equal, the later one loses first.
+		{6, []int64{101, 1, 2, 102, 5, 102}, []int64{0, 0, 0, 0, 1, 1}},
+		{5, []int64{101, 1, 102, 5, 102}, []int64{0, 0, 0, 1, 1}},
+	}
+	for _, tt := range tests {
+		gotIDs, gotTypes, err := truncateKeepingEnds(ids, types, special, tt.maxLen, truncateLongestFirst)
+		if err != nil {
+			t.Fatalf("maxLen %d: %v", tt.maxLen, err)
+		}
+		if !slices.Equal(gotIDs, tt.wantIDs) || !slices.Equal(gotTypes, tt.wantTypes) {
+			t.Errorf("maxLen %d: got %v %v, want %v %v", tt.maxLen, gotIDs, gotTypes, tt.wantIDs, tt.wantTypes)
+		}
+	}
+}
+
+func TestDefaultClassificationFunction(t *testing.T) {
+	cfg := func(problem string) *Config {
+		raw := map[string]any{}
+		if problem != "" {
+			raw["problem_type"] = problem
+		}
+		return &Config{raw: raw}
+	}
+	tests := []struct {
+		name      string
+		problem   string
+		numLabels int
+		want      string
+	}{
+		{"single-label", "single_label_classification", 3, "softmax"},
+		{"unset, several labels", "", 2, "softmax"},
+		{"unset, one logit", "", 1, "sigmoid"},
+		{"multi-label", "multi_label_classification", 5, "sigmoid"},
+		{"regression", "regression", 1, "none"},
+		{"regression, several outputs", "regression", 3, "none"},
+	}
+	for _, tt := range tests {
+		if got := defaultClassificationFunction(cfg(tt.problem), tt.numLabels); got != tt.want {
+			t.Errorf("%s: defaultClassificationFunction = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyClassificationFunction(t *testing.T) {
+	tests := []struct {
+		fn   string
+		in   []float32
+		want []float32
+	}{
+		{"softmax", []float32{0, 0, float32(math.Log(2))}, []float32{0.25, 0.25, 0.5}},
+		{"sigmoid", []float32{0, float32(math.Log(3))}, []float32{0.5, 0.75}},
+		{"none", []float32{-1.5, 2}, []float32{-1.5, 2}},
+	}
+	for _, tt := range tests {
+		xs := slices.Clone(tt.in)
+		if err := applyClassificationFunction(tt.fn, xs); err != nil {
+			t.Fatalf("%s: %v", tt.fn, err)
+		}
+		for i := range xs {
+			if math.Abs(float64(xs[i]-tt.want[i])) > 1e-6 {
+				t.Errorf("%s(%v) = %v, want %v", tt.fn, tt.in, xs, tt.want)
+				break
+			}
+		}
+	}
+	if err := applyClassificationFunction("tanh", []float32{1}); err == nil {
+		t.Error("unknown function: expected an error")
+	}
+}
+
+func TestTextPairInputs(t *testing.T) {
+	tests := []struct {
+		in   any
+		want [][2]string
+	}{
+		{"hi", [][2]string{{"hi", ""}}},
+		{[]string{"a", "b"}, [][2]string{{"a", ""}, {"b", ""}}},
+		{map[string]any{"text": "q", "text_pair": "d"}, [][2]string{{"q", "d"}}},
+		{[]any{"a", map[string]string{"text": "q", "text_pair": "d"}}, [][2]string{{"a", ""}, {"q", "d"}}},
+	}
+	for _, tt := range tests {
+		got, err := textPairInputs("text-classification", tt.in)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("textPairInputs(%v) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := textPairInputs("text-classification", 42); err == nil {
+		t.Error("textPairInputs(42): expected an error")
+	}
+}
+
+func TestID2Label(t *testing.T) {
+	cfg := &Config{raw: map[string]any{"id2label": map[string]any{"0": "NEGATIVE", "2": "POSITIVE", "x": "junk"}}}
+	labels := id2Label(cfg)
+	for idx, want := range []string{"NEGATIVE", "LABEL_1", "POSITIVE", "LABEL_3"} {
+		if got := labelFor(labels, idx); got != want {
+			t.Errorf("labelFor(%d) = %q, want %q", idx, got, want)
+		}
+	}
+}
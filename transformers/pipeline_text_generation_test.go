@@ -0,0 +1,68 @@
+package transformers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTextGenerationMode(t *testing.T) {
+	yes := map[string]any{"completion": true}
+	no := map[string]any{"completion": false}
+	none := map[string]any{}
+	tests := []struct {
+		name                 string
+		isChat, hasTemplate  bool
+		callOptions, options map[string]any
+		want                 promptMode
+	}{
+		{"prompt", false, true, none, none, promptCompletion},
+		{"prompt without template", false, false, none, none, promptCompletion},
+		{"chat", true, true, none, none, promptChat},
+		{"chat without template", true, false, none, none, promptRawChat},
+		{"explicit completion", true, true, yes, none, promptCompletion},
+		{"explicit completion without template", true, false, none, yes, promptCompletion},
+		{"call overrides pipeline", true, true, no, yes, promptChat},
+		{"default template asked for", true, false, no, none, promptChat},
+		{"prompt ignores completion false", false, true, no, none, promptCompletion},
+	}
+	for _, tt := range tests {
+		if got := textGenerationMode(tt.isChat, tt.hasTemplate, tt.callOptions, tt.options); got != tt.want {
+			t.Errorf("%s: mode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChatTurns(t *testing.T) {
+	messages := []ChatMessage{
+		{Role: RoleSystem, Content: "Be brief."},
+		{Role: RoleUser, Content: "Hi"},
+	}
+	reply := map[string]any{"role": "assistant", "content": "Hello!"}
+	if got := chatTurns(messages, " Hello!\n", false); !reflect.DeepEqual(got, []map[string]any{reply}) {
+		t.Fatalf("reply only: %v", got)
+	}
+	want := []map[string]any{
+		{"role": "system", "content": "Be brief."},
+		{"role": "user", "content": "Hi"},
+		reply,
+	}
+	if got := chatTurns(messages, "Hello!", true); !reflect.DeepEqual(got, want) {
+		t.Fatalf("full text: %v", got)
+	}
+}
+
+func TestBoolOptionOr(t *testing.T) {
+	pipeline := map[string]any{"return_full_text": false}
+	if boolOptionOr(nil, pipeline, "return_full_text", true) {
+		t.Fatal("pipeline option ignored")
+	}
+	if !boolOptionOr(map[string]any{"return_full_text": true}, pipeline, "return_full_text", false) {
+		t.Fatal("call option must win")
+	}
+	if !boolOptionOr(nil, nil, "return_full_text", true) {
+		t.Fatal("default ignored")
+	}
+	if _, err := normalizeOptions(pipeline, scopePipeline); err != nil {
+		t.Fatalf("return_full_text as a pipeline option: %v", err)
+	}
+}
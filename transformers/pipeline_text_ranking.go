@@ -0,0 +1,187 @@
+package transformers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// newTextRankingPipeline builds "text-ranking" (alias "rerank") for
+// cross-encoder exports (bge-reranker, ms-marco-MiniLM, ...). Each document
+// is scored as a (query, document) pair:
+//
+//	rerank, _ := Pipeline("text-ranking", "Xenova/ms-marco-MiniLM-L-6-v2", nil)
+//	out, _ := rerank(map[string]any{
+//		"query":     "How many people live in Berlin?",
+//		"documents": []string{"Berlin has 3.5 million inhabitants.", "Paris is in France."},
+//	}, map[string]any{"top_n": 1})
+//	// [{ "index": 0, "score": 0.99, "text": "Berlin has 3.5 million inhabitants." }]
+//
+// The input may also be the query string with the documents in the
+// "documents" option. Results are sorted by score; "index" is the
+// document's position in the input.
+func newTextRankingPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		query, docs, err := queryDocumentsInput(inputs, callOptions, options)
+		if err != nil {
+			return nil, err
+		}
+		topN := intOption(callOptions, "top_n", intOption(options, "top_n", 0))
+		batchSize := intOption(callOptions, "batch_size", intOption(options, "batch_size", 32))
+		if batchSize <= 0 {
+			batchSize = len(docs)
+		}
+		maxLen := model.maxSequenceLength()
+		if n := intOption(callOptions, "max_length", intOption(options, "max_length", 0)); n > 0 {
+			maxLen = min(n, maxLen)
+		}
+		fn := stringOption(callOptions, options, "function_to_apply")
+
+		// Empty documents are not scored: the tokenizer would see the bare
+		// query, which can outrank every real document.
+		var scored []int
+		for i, doc := range docs {
+			if strings.TrimSpace(doc) != "" {
+				scored = append(scored, i)
+			}
+		}
+		scores := make([]float64, len(docs))
+		for lo := 0; lo < len(scored); lo += batchSize {
+			chunk := scored[lo:min(lo+batchSize, len(scored))]
+			pairs := make([][2]string, len(chunk))
+			for i, idx := range chunk {
+				pairs[i] = [2]string{query, docs[idx]}
+			}
+			logits, numLabels, err := classifyPairs(model, tokenizer, pairs, maxLen, truncateLongestFirst)
+			if err != nil {
+				return nil, err
+			}
+			if fn == "" {
+				fn = defaultClassificationFunction(config, numLabels)
+			}
+			for i, idx := range chunk {
+				row := logits[i*numLabels : (i+1)*numLabels]
+				if err := applyClassificationFunction(fn, row); err != nil {
+					return nil, err
+				}
+				// Two-class heads put "relevant" last.
+				scores[idx] = float64(row[numLabels-1])
+			}
+		}
+
+		order := rankOrder(scores, scored, topN)
+		out := make([]map[string]any, len(order))
+		for i, idx := range order {
+			out[i] = map[string]any{
+				"index": idx,
+				"score": scores[idx],
+				"text":  docs[idx],
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// rankOrder returns document indices best first, at most topN (0 = all).
+// Only the documents in scored have a score; the others (empty documents)
+// rank last and are given the lowest real score, or 0 if there is none.
+func rankOrder(scores []float64, scored []int, topN int) []int {
+	isScored := make([]bool, len(scores))
+	lowest := 0.0
+	for k, idx := range scored {
+		isScored[idx] = true
+		if k == 0 || scores[idx] < lowest {
+			lowest = scores[idx]
+		}
+	}
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+		if !isScored[i] {
+			scores[i] = lowest
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if isScored[order[a]] != isScored[order[b]] {
+			return isScored[order[a]]
+		}
+		return scores[order[a]] > scores[order[b]]
+	})
+	if topN > 0 && topN < len(order) {
+		order = order[:topN]
+	}
+	return order
+}
+
+// queryDocumentsInput reads {"query", "documents"} or a query string with
+// the "documents" option.
+func queryDocumentsInput(inputs any, callOptions, options map[string]any) (string, []string, error) {
+	var (
+		query   string
+		rawDocs any
+	)
+	switch t := inputs.(type) {
+	case string:
+		query = t
+	case map[string]any:
+		query, _ = t["query"].(string)
+		rawDocs = t["documents"]
+	default:
+		return "", nil, fmt.Errorf("text-ranking: expected a query string or {\"query\", \"documents\"}, got %T", inputs)
+	}
+	for _, o := range []map[string]any{callOptions, options} {
+		if rawDocs != nil {
+			break
+		}
+		rawDocs = o["documents"]
+	}
+	if query == "" {
+		return "", nil, fmt.Errorf("text-ranking: query is required")
+	}
+
+	// Keep empty documents so indices match the caller's list; they are
+	// ranked last.
+	var docs []string
+	switch t := rawDocs.(type) {
+	case []string:
+		docs = t
+	case []any:
+		for _, x := range t {
+			s, ok := x.(string)
+			if !ok {
+				return "", nil, fmt.Errorf("text-ranking: expected string documents, got %T", x)
+			}
+			docs = append(docs, s)
+		}
+	}
+	if len(docs) == 0 {
+		return "", nil, fmt.Errorf("text-ranking: documents are required")
+	}
+	return query, docs, nil
+}
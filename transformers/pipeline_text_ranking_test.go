@@ -0,0 +1,73 @@
+package transformers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRankOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		scores     []float64
+		scored     []int
+		topN       int
+		want       []int
+		wantScores []float64
+	}{
+		{"best first", []float64{0.1, 0.9, 0.5}, []int{0, 1, 2}, 0, []int{1, 2, 0}, []float64{0.1, 0.9, 0.5}},
+		{"top_n", []float64{0.1, 0.9, 0.5}, []int{0, 1, 2}, 2, []int{1, 2}, nil},
+		{"ties keep input order", []float64{0.5, 0.5}, []int{0, 1}, 0, []int{0, 1}, nil},
+		// An empty document must not outrank real ones, even when their
+		// scores (raw logits) are negative.
+		{"empty last", []float64{0, -2, -1}, []int{1, 2}, 0, []int{2, 1, 0}, []float64{-2, -2, -1}},
+		{"all empty", []float64{0, 0}, nil, 0, []int{0, 1}, []float64{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := append([]float64(nil), tt.scores...)
+			if got := rankOrder(scores, tt.scored, tt.topN); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+			if tt.wantScores != nil && !reflect.DeepEqual(scores, tt.wantScores) {
+				t.Fatalf("scores = %v, want %v", scores, tt.wantScores)
+			}
+		})
+	}
+}
+
+func TestQueryDocumentsInput(t *testing.T) {
+	docs := []string{"a", "", "b"}
+	tests := []struct {
+		name        string
+		inputs      any
+		callOptions map[string]any
+		options     map[string]any
+		wantQuery   string
+		wantDocs    []string
+		wantErr     string
+	}{
+		{"map", map[string]any{"query": "q", "documents": docs}, nil, nil, "q", docs, ""},
+		{"any documents", map[string]any{"query": "q", "documents": []any{"a", "b"}}, nil, nil, "q", []string{"a", "b"}, ""},
+		{"call option", "q", map[string]any{"documents": docs}, map[string]any{"documents": []string{"x"}}, "q", docs, ""},
+		{"pipeline option", "q", nil, map[string]any{"documents": []string{"x"}}, "q", []string{"x"}, ""},
+		{"no query", map[string]any{"documents": docs}, nil, nil, "", nil, "query is required"},
+		{"no documents", "q", nil, nil, "", nil, "documents are required"},
+		{"bad document", map[string]any{"query": "q", "documents": []any{"a", 1}}, nil, nil, "", nil, "string documents"},
+		{"bad input", 42, nil, nil, "", nil, "expected a query"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, got, err := queryDocumentsInput(tt.inputs, tt.callOptions, tt.options)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || query != tt.wantQuery || !reflect.DeepEqual(got, tt.wantDocs) {
+				t.Fatalf("got %q, %v, %v; want %q, %v", query, got, err, tt.wantQuery, tt.wantDocs)
+			}
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Aggregation strategies for "token-classification", as in HF.
+const (
+	AggregationNone    = "none"
+	AggregationSimple  = "simple"
+	AggregationFirst   = "first"
+	AggregationAverage = "average"
+	AggregationMax     = "max"
+)
+
+// newTokenClassificationPipeline builds "token-classification" (alias "ner"):
+//
+//	ner, _ := Pipeline("token-classification", "Xenova/bert-base-NER", nil)
+//	out, _ := ner("My name is Wolfgang and I live in Berlin", map[string]any{"aggregation_strategy": "simple"})
+//	// [{ "entity_group": "PER", "score": 0.99, "word": "Wolfgang", "start": 11, "end": 19 }, ...]
+//
+// start/end are character (rune) offsets into the input, as in HF, so
+// string([]rune(text)[start:end]) is the span.
+// With aggregation "none" each token is reported with "entity" and "index"
+// instead of "entity_group". For batched inputs the entities of every text
+// are concatenated, each tagged with "input_index".
+func newTokenClassificationPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	labels := id2Label(config)
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		texts, err := textInputs("token-classification", inputs)
+		if err != nil {
+			return nil, err
+		}
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		strategy := stringOption(callOptions, options, "aggregation_strategy")
+		if strategy == "" {
+			strategy = AggregationNone
+		}
+		switch strategy {
+		case AggregationNone, AggregationSimple, AggregationFirst, AggregationAverage, AggregationMax:
+		default:
+			return nil, fmt.Errorf("token-classification: unknown aggregation_strategy %q", strategy)
+		}
+		ignore, ok := stringListOption(callOptions, options, "ignore_labels")
+		if !ok {
+			ignore = []string{"O"}
+		}
+
+		encs := make([]*Encoding, len(texts))
+		rows := make([][]int64, len(texts))
+		types := make([][]int64, len(texts))
+		for i, text := range texts {
+			enc, err := tokenizer.EncodeWithOffsets(text)
+			if err != nil {
+				return nil, fmt.Errorf("Encode: %w", err)
+			}
+			if limit := model.maxSequenceLength(); len(enc.IDs) > limit {
+				enc = truncateEncoding(enc, limit)
+			}
+			encs[i], rows[i], types[i] = enc, enc.IDs, enc.TypeIDs
+		}
+		batch := newEncoderBatch(rows, types, model.padID())
+		outs, err := model.Run(batch)
+		if err != nil {
+			return nil, err
+		}
+		logits, err := model.output(outs, "logits")
+		if err != nil {
+			return nil, err
+		}
+		if len(logits.shape) != 3 {
+			return nil, fmt.Errorf("token-classification: unexpected logits shape %v", logits.shape)
+		}
+		numLabels := int(logits.shape[2])
+
+		var out []map[string]any
+		for i, text := range texts {
+			row := logits.data[i*batch.seqLen*numLabels : (i+1)*batch.seqLen*numLabels]
+			tokens := preEntities(text, encs[i], row, numLabels)
+			for _, e := range aggregateEntities(text, tokens, labels, strategy) {
+				if containsString(ignore, e.label) {
+					continue
+				}
+				m := e.toMap(text, strategy)
+				if len(texts) > 1 {
+					m["input_index"] = i
+				}
+				out = append(out, m)
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// preEntity is one non-special token with its label probabilities.
+type preEntity struct {
+	word      string
+	scores    []float32
+	start     int
+	end       int
+	index     int
+	wordID    int
+	isSubword bool
+}
+
+// preEntities softmaxes the logits of every non-special token of enc.
+func preEntities(text string, enc *Encoding, logits []float32, numLabels int) []preEntity {
+	var out []preEntity
+	for j := range enc.IDs {
+		if enc.SpecialTokensMask[j] != 0 {
+			continue
+		}
+		scores := append([]float32(nil), logits[j*numLabels:(j+1)*numLabels]...)
+		softmaxF32(scores)
+		start, end := enc.Offsets[j][0], enc.Offsets[j][1]
+		out = append(out, preEntity{
+			word:   enc.Tokens[j],
+			scores: scores,
+			start:  start,
+			end:    end,
+			index:  j,
+			wordID: enc.WordIDs[j],
+		})
+	}
+	for k := range out {
+		out[k].isSubword = isSubwordToken(text, out, k)
+	}
+	return out
+}
+
+// isSubwordToken reports whether token k continues the previous word. Word
+// IDs from the pre-tokenizer decide when available; otherwise, as in HF, a
+// token that is not preceded by a space continues the word.
+func isSubwordToken(text string, tokens []preEntity, k int) bool {
+	if k == 0 {
+		return false
+	}
+	if tokens[k].wordID >= 0 && tokens[k-1].wordID >= 0 {
+		return tokens[k].wordID == tokens[k-1].wordID
+	}
+	start := tokens[k].start
+	if start <= 0 || start > len(text) {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:start])
+	return !unicode.IsSpace(r) && tokens[k-1].end == start
+}
+
+// entity is a labelled span, either one token or an aggregated group.
+type entity struct {
+	label string
+	score float64
+	word  string
+	start int // byte offsets into the text
+	end   int
+	index int // token index; AggregationNone only
+}
+
+// toMap reports e with its span converted to character offsets into text.
+func (e entity) toMap(text, strategy string) map[string]any {
+	m := map[string]any{
+		"score": e.score,
+		"word":  e.word,
+		"start": runeOffset(text, e.start),
+		"end":   runeOffset(text, e.end),
+	}
+	if strategy == AggregationNone {
+		m["entity"] = e.label
+		m["index"] = e.index
+	} else {
+		m["entity_group"] = e.label
+	}
+	return m
+}
+
+// aggregateEntities applies HF's aggregation strategies to the tokens.
+func aggregateEntities(text string, tokens []preEntity, labels []string, strategy string) []entity {
+	tokenEntity := func(t preEntity) entity {
+		idx := argmaxF32(t.scores)
+		return entity{
+			label: labelFor(labels, idx),
+			score: float64(t.scores[idx]),
+			word:  t.word,
+			start: t.start,
+			end:   t.end,
+			index: t.index,
+		}
+	}
+
+	var ents []entity
+	switch strategy {
+	case AggregationNone, AggregationSimple:
+		for _, t := range tokens {
+			ents = append(ents, tokenEntity(t))
+		}
+		if strategy == AggregationNone {
+			return ents
+		}
+	default:
+		// Merge sub-word tokens into words, then label each word.
+		for k := 0; k < len(tokens); {
+			end := k + 1
+			for end < len(tokens) && tokens[end].isSubword {
+				end++
+			}
+			ents = append(ents, aggregateWord(text, tokens[k:end], labels, strategy))
+			k = end
+		}
+	}
+	return groupEntities(text, ents)
+}
+
+// aggregateWord labels one word from its tokens' probabilities.
+func aggregateWord(text string, word []preEntity, labels []string, strategy string) entity {
+	var scores []float32
+	switch strategy {
+	case AggregationFirst:
+		scores = word[0].scores
+	case AggregationMax:
+		best, bestScore := 0, float32(-1)
+		for k, t := range word {
+			if s := t.scores[argmaxF32(t.scores)]; s > bestScore {
+				best, bestScore = k, s
+			}
+		}
+		scores = word[best].scores
+	default: // AggregationAverage
+		scores = make([]float32, len(word[0].scores))
+		for _, t := range word {
+			for c, v := range t.scores {
+				scores[c] += v / float32(len(word))
+			}
+		}
+	}
+	idx := argmaxF32(scores)
+	start, end := word[0].start, word[len(word)-1].end
+	return entity{
+		label: labelFor(labels, idx),
+		score: float64(scores[idx]),
+		word:  spanText(text, start, end),
+		start: start,
+		end:   end,
+	}
+}
+
+// groupEntities merges adjacent entities with the same tag, where a B- prefix
+// starts a new group (BIO tagging), and averages their scores.
+func groupEntities(text string, ents []entity) []entity {
+	var (
+		groups  []entity
+		current []entity
+	)
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		_, tag := bioTag(current[0].label)
+		var sum float64
+		for _, e := range current {
+			sum += e.score
+		}
+		start, end := current[0].start, current[len(current)-1].end
+		groups = append(groups, entity{
+			label: tag,
+			score: sum / float64(len(current)),
+			word:  spanText(text, start, end),
+			start: start,
+			end:   end,
+		})
+		current = nil
+	}
+	for _, e := range ents {
+		if len(current) > 0 {
+			bi, tag := bioTag(e.label)
+			_, lastTag := bioTag(current[len(current)-1].label)
+			if tag != lastTag || bi == "B" {
+				flush()
+			}
+		}
+		current = append(current, e)
+	}
+	flush()
+	return groups
+}
+
+// bioTag splits "B-PER" into ("B", "PER"); labels without a prefix count as
+// inside tags.
+func bioTag(label string) (string, string) {
+	if tag, ok := strings.CutPrefix(label, "B-"); ok {
+		return "B", tag
+	}
+	if tag, ok := strings.CutPrefix(label, "I-"); ok {
+		return "I", tag
+	}
+	return "I", label
+}
+
+// spanText returns text[start:end], clamped to the string.
+func spanText(text string, start, end int) string {
+	start = min(max(start, 0), len(text))
+	end = min(max(end, start), len(text))
+	return text[start:end]
+}
+
+// truncateEncoding keeps the first maxLen-1 tokens and the final special
+// token of a single-sequence encoding.
+func truncateEncoding(enc *Encoding, maxLen int) *Encoding {
+	if maxLen < 2 || len(enc.IDs) <= maxLen {
+		return enc
+	}
+	last := len(enc.IDs) - 1
+	keep := func(xs []int64) []int64 { return append(append([]int64(nil), xs[:maxLen-1]...), xs[last]) }
+	return &Encoding{
+		IDs:               keep(enc.IDs),
+		TypeIDs:           keep(enc.TypeIDs),
+		Tokens:            append(append([]string(nil), enc.Tokens[:maxLen-1]...), enc.Tokens[last]),
+		Offsets:           append(append([][2]int(nil), enc.Offsets[:maxLen-1]...), enc.Offsets[last]),
+		SpecialTokensMask: keep(enc.SpecialTokensMask),
+		WordIDs:           append(append([]int(nil), enc.WordIDs[:maxLen-1]...), enc.WordIDs[last]),
+	}
+}
+
+func containsString(xs []string, s string) bool {
+	for _, x := range xs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package transformers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRuneOffset(t *testing.T) {
+	text := "Zoë lives in Köln"
+	for byteOff, want := range map[int]int{-1: 0, 0: 0, 2: 2, 4: 3, 5: 4, len(text): 17, 100: 17} {
+		if got := runeOffset(text, byteOff); got != want {
+			t.Errorf("runeOffset(%d) = %d, want %d", byteOff, got, want)
+		}
+	}
+}
+
+func TestTokenClassificationCharOffsets(t *testing.T) {
+	tok := stubTokenizer(t, "Zoë", "lives", "in", "Köln")
+	text := "Zoë lives in Köln"
+	enc, err := tok.EncodeWithOffsets(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Labels O, PER, LOC; Zoë is PER and Köln is LOC.
+	logits := []float32{
+		0, 9, 0,
+		9, 0, 0,
+		9, 0, 0,
+		0, 0, 9,
+	}
+	labels := []string{"O", "PER", "LOC"}
+	var got []map[string]any
+	for _, e := range aggregateEntities(text, preEntities(text, enc, logits, 3), labels, AggregationSimple) {
+		if e.label != "O" {
+			got = append(got, e.toMap(text, AggregationSimple))
+		}
+	}
+	want := []struct {
+		group      string
+		start, end int
+	}{{"PER", 0, 3}, {"LOC", 13, 17}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	runes := []rune(text)
+	for i, w := range want {
+		if got[i]["entity_group"] != w.group || got[i]["start"] != w.start || got[i]["end"] != w.end {
+			t.Errorf("entity %d = %v, want %s [%d, %d)", i, got[i], w.group, w.start, w.end)
+		}
+		if span := string(runes[w.start:w.end]); span != got[i]["word"] {
+			t.Errorf("span %q != word %q", span, got[i]["word"])
+		}
+	}
+}
+
+func TestStringListOption(t *testing.T) {
+	tests := []struct {
+		name          string
+		call, options map[string]any
+		want          []string
+		ok            bool
+	}{
+		{"unset", nil, map[string]any{}, nil, false},
+		{"string", map[string]any{"k": "O"}, nil, []string{"O"}, true},
+		{"[]string", nil, map[string]any{"k": []string{"O", "", "MISC"}}, []string{"O", "MISC"}, true},
+		{"[]any from JSON", map[string]any{"k": []any{"O", 1, "MISC"}}, nil, []string{"O", "MISC"}, true},
+		{"explicit empty list", map[string]any{"k": []any{}}, map[string]any{"k": "O"}, nil, true},
+		{"call wins", map[string]any{"k": "B"}, map[string]any{"k": "A"}, []string{"B"}, true},
+	}
+	for _, tt := range tests {
+		got, ok := stringListOption(tt.call, tt.options, "k")
+		if ok != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, %v; want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
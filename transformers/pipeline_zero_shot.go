@@ -0,0 +1,200 @@
+package transformers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// newZeroShotClassificationPipeline builds "zero-shot-classification" on top
+// of an NLI (MNLI/XNLI) sequence classifier. Each candidate label becomes a
+// hypothesis scored for entailment against the input:
+//
+//	clf, _ := Pipeline("zero-shot-classification", "Xenova/distilbert-base-uncased-mnli", nil)
+//	out, _ := clf("My card was charged twice", map[string]any{
+//		"candidate_labels": []string{"billing", "shipping", "login"},
+//	})
+//	// [{ "sequence": "...", "labels": ["billing", ...], "scores": [0.93, ...] }]
+func newZeroShotClassificationPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	model, err := AutoModel.FromPretrainedWithOptions(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	entailment, contradiction, err := nliLabelIndices(id2Label(config))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", modelID, err)
+	}
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		texts, err := textInputs("zero-shot-classification", inputs)
+		if err != nil {
+			return nil, err
+		}
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		candidates := candidateLabelsOption(callOptions, options)
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("zero-shot-classification: candidate_labels is required")
+		}
+		template := stringOption(callOptions, options, "hypothesis_template")
+		if template == "" {
+			template = "This example is {}."
+		}
+		if !strings.Contains(template, "{}") {
+			return nil, fmt.Errorf("zero-shot-classification: hypothesis_template %q has no {} placeholder", template)
+		}
+		multiLabel := boolOption(callOptions, options, "multi_label") || len(candidates) == 1
+		batchSize := intOption(callOptions, "batch_size", intOption(options, "batch_size", 32))
+		if batchSize <= 0 {
+			batchSize = len(texts) * len(candidates)
+		}
+
+		// One (premise, hypothesis) pair per text and label, run in batches.
+		pairs := make([][2]string, 0, len(texts)*len(candidates))
+		for _, text := range texts {
+			for _, label := range candidates {
+				pairs = append(pairs, [2]string{text, strings.ReplaceAll(template, "{}", label)})
+			}
+		}
+		var (
+			logits    []float32
+			numLabels int
+		)
+		for lo := 0; lo < len(pairs); lo += batchSize {
+			// Long inputs lose premise tokens only, as in HF (only_first):
+			// the hypothesis carries the label.
+			chunk, n, err := classifyPairs(model, tokenizer, pairs[lo:min(lo+batchSize, len(pairs))], model.maxSequenceLength(), truncateOnlyFirst)
+			if err != nil {
+				return nil, fmt.Errorf("zero-shot-classification: %w", err)
+			}
+			logits, numLabels = append(logits, chunk...), n
+		}
+		if entailment >= numLabels || contradiction >= numLabels {
+			return nil, fmt.Errorf("zero-shot-classification: model has %d logits, id2label does not match", numLabels)
+		}
+
+		out := make([]map[string]any, len(texts))
+		for i, text := range texts {
+			rows := logits[i*len(candidates)*numLabels : (i+1)*len(candidates)*numLabels]
+			out[i] = zeroShotResult(text, candidates, rows, numLabels, entailment, contradiction, multiLabel)
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
+
+// zeroShotResult scores the candidates of one text from its NLI logits,
+// row-major [len(candidates), numLabels], and sorts them best first. Single-
+// label scores are a softmax of the entailment logits across candidates;
+// multi-label ones weigh entailment against contradiction per candidate.
+func zeroShotResult(text string, candidates []string, logits []float32, numLabels, entailment, contradiction int, multiLabel bool) map[string]any {
+	scores := make([]float64, len(candidates))
+	for j := range candidates {
+		row := logits[j*numLabels:]
+		if multiLabel {
+			// Entailment vs. contradiction for this label alone.
+			e, c := float64(row[entailment]), float64(row[contradiction])
+			scores[j] = 1 / (1 + math.Exp(c-e))
+		} else {
+			scores[j] = float64(row[entailment])
+		}
+	}
+	if !multiLabel {
+		softmaxF64(scores)
+	}
+
+	order := make([]int, len(candidates))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	labels := make([]string, len(order))
+	sorted := make([]float64, len(order))
+	for k, idx := range order {
+		labels[k], sorted[k] = candidates[idx], scores[idx]
+	}
+	return map[string]any{
+		"sequence": text,
+		"labels":   labels,
+		"scores":   sorted,
+	}
+}
+
+// nliLabelIndices finds the entailment and contradiction classes in id2label.
+func nliLabelIndices(labels []string) (entailment, contradiction int, err error) {
+	entailment, contradiction = -1, -1
+	for i, l := range labels {
+		switch l = strings.ToLower(l); {
+		case strings.HasPrefix(l, "entail"):
+			entailment = i
+		case strings.HasPrefix(l, "contra"):
+			contradiction = i
+		}
+	}
+	if entailment < 0 {
+		return -1, -1, fmt.Errorf("no entailment label in id2label %v; zero-shot-classification needs an NLI model", labels)
+	}
+	if contradiction < 0 {
+		// Two-class NLI heads: whichever class is not entailment.
+		contradiction = 0
+		if entailment == 0 {
+			contradiction = len(labels) - 1
+		}
+	}
+	return entailment, contradiction, nil
+}
+
+// candidateLabelsOption reads candidate_labels as []string, []any or, as
+// in HF, a comma-separated string.
+func candidateLabelsOption(callOptions, options map[string]any) []string {
+	labels, _ := stringListOption(callOptions, options, "candidate_labels")
+	v, ok := callOptions["candidate_labels"]
+	if !ok {
+		v = options["candidate_labels"]
+	}
+	if _, isString := v.(string); !isString || len(labels) == 0 {
+		return labels
+	}
+	var out []string
+	for _, s := range strings.Split(labels[0], ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// softmaxF64 converts xs to probabilities in place.
+func softmaxF64(xs []float64) {
+	maxV := math.Inf(-1)
+	for _, v := range xs {
+		maxV = math.Max(maxV, v)
+	}
+	var sum float64
+	for i, v := range xs {
+		xs[i] = math.Exp(v - maxV)
+		sum += xs[i]
+	}
+	for i := range xs {
+		xs[i] /= sum
+	}
+}
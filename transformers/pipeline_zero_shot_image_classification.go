@@ -0,0 +1,119 @@
+package transformers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// newZeroShotImageClassificationPipeline builds
+// "zero-shot-image-classification" on CLIP/SigLIP exports. Each candidate
+// label is put through hypothesis_template and scored against the image:
+//
+//	clf, _ := Pipeline("zero-shot-image-classification", "Xenova/clip-vit-base-patch32", nil)
+//	out, _ := clf("cat.jpg", map[string]any{
+//		"candidate_labels": []string{"cat", "dog", "car"},
+//	})
+//	// [{ "label": "cat", "score": 0.98 }, { "label": "dog", ... }, ...]
+//
+// Scores are a softmax across labels for CLIP and independent sigmoids for
+// SigLIP. For batched inputs each entry carries "input_index".
+func newZeroShotImageClassificationPipeline(modelID string, options map[string]any) (Generator, error) {
+	config, err := AutoConfig.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	tokenizer, err := AutoTokenizer.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load tokenizer: %w", err)
+	}
+	processor, err := AutoImageProcessor.FromPretrained(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("load image processor: %w", err)
+	}
+	model, err := loadCLIPModel(
+		modelID,
+		config,
+		encoderDtypeOption(options),
+		modelLoadOptionsFrom(options),
+		true,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load model: %w", err)
+	}
+	model.LogitScale = float32(floatOption(nil, options, "logit_scale", float64(model.LogitScale)))
+	model.LogitBias = float32(floatOption(nil, options, "logit_bias", float64(model.LogitBias)))
+	if err := model.checkLogitScale(); err != nil {
+		model.Close()
+		return nil, fmt.Errorf("zero-shot-image-classification: %w", err)
+	}
+	fn := "softmax"
+	if config.ModelType() == "siglip" {
+		fn = "sigmoid"
+	}
+
+	generator := func(
+		inputs any,
+		callOptions map[string]any,
+	) ([]map[string]any, error) {
+		if callOptions == nil {
+			callOptions = map[string]any{}
+		}
+		candidates := candidateLabelsOption(callOptions, options)
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("zero-shot-image-classification: candidate_labels is required")
+		}
+		template := stringOption(callOptions, options, "hypothesis_template")
+		if template == "" {
+			template = "This is a photo of {}."
+		}
+		if !strings.Contains(template, "{}") {
+			return nil, fmt.Errorf("zero-shot-image-classification: hypothesis_template %q has no {} placeholder", template)
+		}
+		prompts := make([]string, len(candidates))
+		for i, label := range candidates {
+			prompts[i] = strings.ReplaceAll(template, "{}", label)
+		}
+
+		images := imageInputs(inputs)
+		pixels := make([]*PixelValues, len(images))
+		for i, in := range images {
+			img, err := imageFromInput(in)
+			if err != nil {
+				return nil, err
+			}
+			if pixels[i], err = processor.Preprocess(img); err != nil {
+				return nil, err
+			}
+		}
+		logits, err := model.LogitsPerImage(tokenizer, pixels, prompts)
+		if err != nil {
+			return nil, err
+		}
+
+		var out []map[string]any
+		for i, scores := range logits {
+			if err := applyClassificationFunction(fn, scores); err != nil {
+				return nil, err
+			}
+			order := make([]int, len(scores))
+			for k := range order {
+				order[k] = k
+			}
+			sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+			for _, idx := range order {
+				entry := map[string]any{
+					"label": candidates[idx],
+					"score": float64(scores[idx]),
+				}
+				if len(images) > 1 {
+					entry["input_index"] = i
+				}
+				out = append(out, entry)
+			}
+		}
+		return out, nil
+	}
+
+	return generator, nil
+}
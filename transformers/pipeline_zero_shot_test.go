@@ -0,0 +1,183 @@
+package transformers
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sugarme/tokenizer/processor"
+)
+
+func TestTruncateKeepingEnds(t *testing.T) {
+	tests := []struct {
+		name       string
+		ids, types []int64
+		special    []int64
+		maxLen     int
+		strategy   truncation
+		wantIDs    []int64
+		wantTypes  []int64
+		wantErr    string
+	}{
+		{
+			// <s> premise </s></s> hypothesis </s>, all type IDs 0.
+			name:      "roberta only_first",
+			ids:       []int64{0, 10, 11, 12, 13, 14, 2, 2, 20, 21, 2},
+			types:     []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			special:   []int64{1, 0, 0, 0, 0, 0, 1, 1, 0, 0, 1},
+			maxLen:    8,
+			strategy:  truncateOnlyFirst,
+			wantIDs:   []int64{0, 10, 11, 2, 2, 20, 21, 2},
+			wantTypes: []int64{0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:      "bert only_first",
+			ids:       []int64{101, 10, 11, 12, 102, 20, 21, 102},
+			types:     []int64{0, 0, 0, 0, 0, 1, 1, 1},
+			special:   []int64{1, 0, 0, 0, 1, 0, 0, 1},
+			maxLen:    6,
+			strategy:  truncateOnlyFirst,
+			wantIDs:   []int64{101, 10, 102, 20, 21, 102},
+			wantTypes: []int64{0, 0, 0, 1, 1, 1},
+		},
+		{
+			name:      "longest_first ties cut the pair",
+			ids:       []int64{0, 1, 2, 3, 2, 2, 4, 5, 6, 2},
+			types:     []int64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			special:   []int64{1, 0, 0, 0, 1, 1, 0, 0, 0, 1},
+			maxLen:    8,
+			strategy:  truncateLongestFirst,
+			wantIDs:   []int64{0, 1, 2, 2, 2, 4, 5, 2},
+			wantTypes: []int64{0, 0, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:      "single text",
+			ids:       []int64{101, 1, 2, 3, 4, 102},
+			types:     []int64{0, 0, 0, 0, 0, 0},
+			special:   []int64{1, 0, 0, 0, 0, 1},
+			maxLen:    4,
+			wantIDs:   []int64{101, 1, 2, 102},
+			wantTypes: []int64{0, 0, 0, 0},
+		},
+		{
+			name:     "only_first cannot fit the hypothesis",
+			ids:      []int64{0, 1, 2, 2, 3, 4, 5, 6, 2},
+			types:    []int64{0, 0, 0, 0, 0, 0, 0, 0, 0},
+			special:  []int64{1, 0, 1, 1, 0, 0, 0, 0, 1},
+			maxLen:   5,
+			strategy: truncateOnlyFirst,
+			wantErr:  "only_first",
+		},
+		{
+			name:      "special tokens alone too long",
+			ids:       []int64{101, 102},
+			types:     []int64{0, 0},
+			special:   []int64{1, 1},
+			maxLen:    1,
+			wantIDs:   []int64{101},
+			wantTypes: []int64{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, types, err := truncateKeepingEnds(tt.ids, tt.types, tt.special, tt.maxLen, tt.strategy)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) || !reflect.DeepEqual(types, tt.wantTypes) {
+				t.Fatalf("got %v %v, want %v %v", ids, types, tt.wantIDs, tt.wantTypes)
+			}
+		})
+	}
+}
+
+func TestEncodeWithTypesRobertaPair(t *testing.T) {
+	tok := stubTokenizer(t, "<s>", "</s>", "a", "b", "c", "d", "e", "label")
+	id := func(w string) int64 { v, _ := tok.TokenToID(w); return v }
+	tok.tok.WithPostProcessor(processor.NewRobertaProcessing(
+		processor.PostToken{Value: "</s>", Id: int(id("</s>"))},
+		processor.PostToken{Value: "<s>", Id: int(id("<s>"))},
+		true, false))
+
+	// The cut must follow the separators, whatever the type IDs are.
+	ids, _, err := tok.encodeWithTypes("a b c d e", "label", 7, truncateOnlyFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{id("<s>"), id("a"), id("b"), id("</s>"), id("</s>"), id("label"), id("</s>")}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("ids = %v, want %v: only the premise is cut", ids, want)
+	}
+}
+
+func TestNLILabelIndices(t *testing.T) {
+	tests := []struct {
+		labels         []string
+		entail, contra int
+		wantErr        bool
+	}{
+		{[]string{"contradiction", "neutral", "entailment"}, 2, 0, false},
+		{[]string{"ENTAILMENT", "NEUTRAL", "CONTRADICTION"}, 0, 2, false},
+		{[]string{"entailment", "not_entailment"}, 0, 1, false},
+		{[]string{"not_entailment", "entailment"}, 1, 0, false},
+		{[]string{"POSITIVE", "NEGATIVE"}, 0, 0, true},
+	}
+	for _, tt := range tests {
+		e, c, err := nliLabelIndices(tt.labels)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && (e != tt.entail || c != tt.contra)) {
+			t.Errorf("nliLabelIndices(%v) = %d, %d, %v; want %d, %d", tt.labels, e, c, err, tt.entail, tt.contra)
+		}
+	}
+}
+
+func TestZeroShotResult(t *testing.T) {
+	// contradiction, neutral, entailment per candidate.
+	logits := []float32{
+		0, 0, 0, // "sports"
+		0, 0, 2, // "politics"
+	}
+	candidates := []string{"sports", "politics"}
+	sig := 1 / (1 + math.Exp(-2))
+
+	single := zeroShotResult("text", candidates, logits, 3, 2, 0, false)
+	if got := single["labels"].([]string); !reflect.DeepEqual(got, []string{"politics", "sports"}) {
+		t.Fatalf("labels = %v", got)
+	}
+	if got := single["scores"].([]float64); math.Abs(got[0]-sig) > 1e-9 || math.Abs(got[0]+got[1]-1) > 1e-9 {
+		t.Fatalf("single-label scores = %v, want a softmax led by %v", got, sig)
+	}
+
+	multi := zeroShotResult("text", candidates, logits, 3, 2, 0, true)
+	if got := multi["scores"].([]float64); math.Abs(got[0]-sig) > 1e-9 || math.Abs(got[1]-0.5) > 1e-9 {
+		t.Fatalf("multi-label scores = %v, want [%v 0.5]", got, sig)
+	}
+	if multi["sequence"] != "text" {
+		t.Fatalf("sequence = %v", multi["sequence"])
+	}
+}
+
+func TestCandidateLabelsOption(t *testing.T) {
+	tests := []struct {
+		name          string
+		call, options map[string]any
+		want          []string
+	}{
+		{"comma-separated", map[string]any{"candidate_labels": " billing, travel ,,"}, nil, []string{"billing", "travel"}},
+		{"list keeps commas", nil, map[string]any{"candidate_labels": []string{"food, drink", "sport"}}, []string{"food, drink", "sport"}},
+		{"[]any from JSON", map[string]any{"candidate_labels": []any{"a", "b"}}, nil, []string{"a", "b"}},
+		{"call wins", map[string]any{"candidate_labels": "a"}, map[string]any{"candidate_labels": []string{"b"}}, []string{"a"}},
+		{"unset", map[string]any{}, map[string]any{}, nil},
+	}
+	for _, tt := range tests {
+		if got := candidateLabelsOption(tt.call, tt.options); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
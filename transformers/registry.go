@@ -0,0 +1,220 @@
+package transformers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TaskFactory builds the Generator of a task. task is the registered name
+// the request resolved to, or the full name for prefix tasks
+// ("translation_en_to_fr"), so one factory can serve a family of names.
+// options has been validated, with integers normalized to int.
+type TaskFactory func(task, modelID string, options map[string]any) (Generator, error)
+
+// TaskOptions describes a task beyond its factory.
+type TaskOptions struct {
+	// DefaultModel is used when Pipeline is called with an empty model ID.
+	DefaultModel string
+
+	// Aliases are alternative names, e.g. "sentiment-analysis" for
+	// "text-classification".
+	Aliases []string
+
+	// MatchPrefix also routes "<name>_<suffix>" to this task.
+	MatchPrefix bool
+
+	// OptionKeys are extra pipeline/call option keys the task reads. They
+	// pass validation with any value; the factory checks them itself.
+	OptionKeys []string
+}
+
+type registeredTask struct {
+	name    string
+	factory TaskFactory
+	opts    TaskOptions
+}
+
+var taskRegistry = struct {
+	sync.RWMutex
+	tasks   map[string]*registeredTask // by name and alias
+	options map[string]string          // custom option key -> task name
+}{
+	tasks:   map[string]*registeredTask{},
+	options: map[string]string{},
+}
+
+// RegisterTask makes factory available to Pipeline under name:
+//
+//	transformers.RegisterTask("invoice-extraction", func(task, modelID string, opts map[string]any) (transformers.Generator, error) {
+//		...
+//	})
+//	extract, err := transformers.Pipeline("invoice-extraction", "acme/invoice-head", nil)
+//
+// Registering a name or alias that is already taken is an error; built-in
+// tasks are registered the same way and cannot be replaced.
+func RegisterTask(name string, factory TaskFactory) error {
+	return RegisterTaskWithOptions(name, factory, TaskOptions{})
+}
+
+// RegisterTaskWithOptions is RegisterTask with a default model, aliases and
+// task-specific option keys.
+func RegisterTaskWithOptions(name string, factory TaskFactory, opts TaskOptions) error {
+	if name == "" {
+		return errors.New("RegisterTask: empty task name")
+	}
+	if factory == nil {
+		return fmt.Errorf("RegisterTask %q: factory is nil", name)
+	}
+	taskRegistry.Lock()
+	defer taskRegistry.Unlock()
+
+	names := append([]string{name}, opts.Aliases...)
+	for _, n := range names {
+		if prev, ok := taskRegistry.tasks[n]; ok {
+			return fmt.Errorf("RegisterTask %q: name %q is already registered by %q", name, n, prev.name)
+		}
+	}
+	for _, key := range opts.OptionKeys {
+		if _, ok := knownOptions[key]; ok {
+			continue // already understood by the validator
+		}
+		if prev, ok := taskRegistry.options[key]; ok && prev != name {
+			// Shared custom keys are fine; they are untyped either way.
+			continue
+		}
+		taskRegistry.options[key] = name
+	}
+	t := &registeredTask{name: name, factory: factory, opts: opts}
+	for _, n := range names {
+		taskRegistry.tasks[n] = t
+	}
+	return nil
+}
+
+// Tasks lists the registered task names (without aliases), sorted.
+func Tasks() []string {
+	taskRegistry.RLock()
+	defer taskRegistry.RUnlock()
+	var names []string
+	for n, t := range taskRegistry.tasks {
+		if n == t.name {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupTask resolves a task name or alias to the registered name. For
+// prefix tasks the requested name is kept, so the factory sees the suffix.
+func lookupTask(task string) (*registeredTask, string, bool) {
+	taskRegistry.RLock()
+	defer taskRegistry.RUnlock()
+	if t, ok := taskRegistry.tasks[task]; ok {
+		return t, t.name, true
+	}
+	if i := strings.Index(task, "_"); i > 0 {
+		if t, ok := taskRegistry.tasks[task[:i]]; ok && t.opts.MatchPrefix {
+			return t, task, true
+		}
+	}
+	return nil, "", false
+}
+
+// customOption reports whether key was declared by a registered task.
+func customOption(key string) bool {
+	taskRegistry.RLock()
+	defer taskRegistry.RUnlock()
+	_, ok := taskRegistry.options[key]
+	return ok
+}
+
+func customOptionKeys() []string {
+	taskRegistry.RLock()
+	defer taskRegistry.RUnlock()
+	return sortedKeys(taskRegistry.options)
+}
+
+// builtin adapts the constructors of tasks that ignore the task name.
+func builtin(fn func(modelID string, options map[string]any) (Generator, error)) TaskFactory {
+	return func(_, modelID string, options map[string]any) (Generator, error) {
+		return fn(modelID, options)
+	}
+}
+
+func mustRegisterTask(name string, factory TaskFactory, opts TaskOptions) {
+	if err := RegisterTaskWithOptions(name, factory, opts); err != nil {
+		panic(err)
+	}
+}
+
+// Built-in tasks. Default models are the ones the docs use.
+func init() {
+	mustRegisterTask("text-generation", builtin(newTextGenerationPipeline), TaskOptions{
+		DefaultModel: "onnx-community/SmolLM2-135M-ONNX",
+	})
+	mustRegisterTask("text2text-generation", newText2TextPipeline, TaskOptions{
+		DefaultModel: "Xenova/flan-t5-small",
+	})
+	mustRegisterTask("summarization", newText2TextPipeline, TaskOptions{
+		DefaultModel: "Xenova/distilbart-cnn-6-6",
+	})
+	mustRegisterTask("translation", newText2TextPipeline, TaskOptions{
+		DefaultModel: "Xenova/nllb-200-distilled-600M",
+		MatchPrefix:  true,
+	})
+	mustRegisterTask("automatic-speech-recognition", builtin(newASRPipeline), TaskOptions{
+		DefaultModel: "onnx-community/whisper-base",
+	})
+	mustRegisterTask("feature-extraction", builtin(newFeatureExtractionPipeline), TaskOptions{
+		DefaultModel: "Xenova/all-MiniLM-L6-v2",
+	})
+	mustRegisterTask("text-classification", builtin(newTextClassificationPipeline), TaskOptions{
+		DefaultModel: "Xenova/distilbert-base-uncased-finetuned-sst-2-english",
+		Aliases:      []string{"sentiment-analysis"},
+	})
+	mustRegisterTask("token-classification", builtin(newTokenClassificationPipeline), TaskOptions{
+		DefaultModel: "Xenova/bert-base-NER",
+		Aliases:      []string{"ner"},
+	})
+	mustRegisterTask("question-answering", builtin(newQuestionAnsweringPipeline), TaskOptions{
+		DefaultModel: "Xenova/distilbert-base-cased-distilled-squad",
+	})
+	mustRegisterTask("zero-shot-classification", builtin(newZeroShotClassificationPipeline), TaskOptions{
+		DefaultModel: "Xenova/distilbert-base-uncased-mnli",
+	})
+	mustRegisterTask("fill-mask", builtin(newFillMaskPipeline), TaskOptions{
+		DefaultModel: "Xenova/bert-base-uncased",
+	})
+	mustRegisterTask("image-classification", builtin(newImageClassificationPipeline), TaskOptions{
+		DefaultModel: "Xenova/vit-base-patch16-224",
+	})
+	mustRegisterTask("zero-shot-image-classification", builtin(newZeroShotImageClassificationPipeline), TaskOptions{
+		DefaultModel: "Xenova/clip-vit-base-patch32",
+	})
+	mustRegisterTask("image-feature-extraction", builtin(newImageFeatureExtractionPipeline), TaskOptions{
+		DefaultModel: "Xenova/clip-vit-base-patch32",
+	})
+	mustRegisterTask("object-detection", builtin(newObjectDetectionPipeline), TaskOptions{
+		DefaultModel: "Xenova/detr-resnet-50",
+	})
+	mustRegisterTask("image-segmentation", builtin(newImageSegmentationPipeline), TaskOptions{
+		DefaultModel: "Xenova/segformer-b0-finetuned-ade-512-512",
+	})
+	mustRegisterTask("text-ranking", builtin(newTextRankingPipeline), TaskOptions{
+		DefaultModel: "Xenova/bge-reranker-base",
+		Aliases:      []string{"rerank"},
+	})
+	mustRegisterTask("image-text-to-text", builtin(newImageTextToTextPipeline), TaskOptions{
+		DefaultModel: "HuggingFaceTB/SmolVLM-256M-Instruct",
+	})
+	mustRegisterTask("image-to-text", builtin(newImageToTextPipeline), TaskOptions{
+		DefaultModel: "Xenova/vit-gpt2-image-captioning",
+	})
+	mustRegisterTask("audio-classification", builtin(newAudioClassificationPipeline), TaskOptions{
+		DefaultModel: "Xenova/ast-finetuned-audioset-10-10-0.4593",
+	})
+}
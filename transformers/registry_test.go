@@ -0,0 +1,140 @@
+package transformers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// registerTestTask registers a task and removes it again when t ends, so
+// tests stay repeatable against the global registry.
+func registerTestTask(t *testing.T, name string, factory TaskFactory, opts TaskOptions) error {
+	t.Helper()
+	if err := RegisterTaskWithOptions(name, factory, opts); err != nil {
+		return err
+	}
+	t.Cleanup(func() {
+		taskRegistry.Lock()
+		defer taskRegistry.Unlock()
+		for _, n := range append([]string{name}, opts.Aliases...) {
+			delete(taskRegistry.tasks, n)
+		}
+		for key, owner := range taskRegistry.options {
+			if owner == name {
+				delete(taskRegistry.options, key)
+			}
+		}
+	})
+	return nil
+}
+
+func stubFactory(task, modelID string, options map[string]any) (Generator, error) {
+	return func(inputs any, callOptions map[string]any) ([]map[string]any, error) {
+		return []map[string]any{{"task": task, "model": modelID, "options": options, "call": callOptions}}, nil
+	}, nil
+}
+
+func TestLookupTask(t *testing.T) {
+	tests := []struct {
+		task   string
+		want   string
+		wantOK bool
+	}{
+		{"text-classification", "text-classification", true},
+		{"sentiment-analysis", "text-classification", true},
+		{"ner", "token-classification", true},
+		{"translation", "translation", true},
+		{"translation_en_to_fr", "translation_en_to_fr", true},
+		{"summarization_short", "", false}, // summarization does not match prefixes
+		{"_translation", "", false},
+		{"no-such-task", "", false},
+	}
+	for _, tt := range tests {
+		_, got, ok := lookupTask(tt.task)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("lookupTask(%q) = %q, %v; want %q, %v", tt.task, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestRegisterTaskRejects(t *testing.T) {
+	if err := registerTestTask(t, "registry-test-base", stubFactory, TaskOptions{Aliases: []string{"registry-test-alias"}}); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		task    string
+		factory TaskFactory
+		opts    TaskOptions
+		wantErr string
+	}{
+		{"empty name", "", stubFactory, TaskOptions{}, "empty task name"},
+		{"nil factory", "registry-test-nil", nil, TaskOptions{}, "factory is nil"},
+		{"builtin name", "text-generation", stubFactory, TaskOptions{}, `already registered by "text-generation"`},
+		{"builtin alias", "registry-test-x", stubFactory, TaskOptions{Aliases: []string{"sentiment-analysis"}}, `already registered by "text-classification"`},
+		{"name taken by alias", "registry-test-alias", stubFactory, TaskOptions{}, `already registered by "registry-test-base"`},
+		{"duplicate", "registry-test-base", stubFactory, TaskOptions{}, "already registered"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registerTestTask(t, tt.task, tt.factory, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	// A rejected registration must not leave any of its names behind.
+	if _, _, ok := lookupTask("registry-test-x"); ok {
+		t.Fatal("rejected task was partially registered")
+	}
+}
+
+func TestRegisteredTaskPipeline(t *testing.T) {
+	err := registerTestTask(t, "registry-test-extract", stubFactory, TaskOptions{
+		DefaultModel: "acme/extractor",
+		Aliases:      []string{"registry-test-extraction"},
+		MatchPrefix:  true,
+		OptionKeys:   []string{"registry_test_schema"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := Tasks()
+	if !slices.Contains(names, "registry-test-extract") || slices.Contains(names, "registry-test-extraction") {
+		t.Fatalf("Tasks() = %v: want the name without its alias", names)
+	}
+	if !slices.Contains(names, "text-classification") || slices.Contains(names, "sentiment-analysis") {
+		t.Fatalf("Tasks() = %v: want the built-ins without aliases", names)
+	}
+	if !slices.IsSorted(names) {
+		t.Fatalf("Tasks() = %v: not sorted", names)
+	}
+
+	gen, err := Pipeline("registry-test-extraction", "", map[string]any{"registry_test_schema": []any{"total"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := gen("x", map[string]any{"registry_test_schema": "v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out[0]["task"] != "registry-test-extract" || out[0]["model"] != "acme/extractor" {
+		t.Fatalf("factory saw task %v, model %v", out[0]["task"], out[0]["model"])
+	}
+
+	gen, err = Pipeline("registry-test-extract_v2", "acme/other", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := gen("x", nil); out[0]["task"] != "registry-test-extract_v2" {
+		t.Fatalf("prefix task: factory saw %v", out[0]["task"])
+	}
+
+	// Custom keys are only known while a task declares them.
+	if _, err := Pipeline("registry-test-extract", "", map[string]any{"registry_test_schem": 1}); err == nil {
+		t.Fatal("misspelled custom option accepted")
+	} else if !strings.Contains(err.Error(), `did you mean "registry_test_schema"`) {
+		t.Fatalf("err = %v, want a suggestion", err)
+	}
+}
@@ -0,0 +1,420 @@
+package transformers
+
+import (
+	"fmt"
+	"image"
+)
+
+// TypedGenerator is a Generator whose results are decoded into T.
+type TypedGenerator[T any] func(inputs any, options map[string]any) ([]T, error)
+
+// result is implemented by the pointer of every typed result, decoding one
+// HF-shaped entry of a Generator's output.
+type result[T any] interface {
+	*T
+	fromMap(m map[string]any) error
+}
+
+// TypedPipeline is Pipeline with results decoded into T, the result type of
+// the task (TextGenerationResult, ClassificationResult, ...):
+//
+//	chat, _ := TypedPipeline[TextGenerationResult]("text-generation", modelID, nil)
+//	res, _ := chat(messages, map[string]any{"max_new_tokens": 32})
+//	fmt.Println(res[0].Reply(), res[0].FinishReason, res[0].Usage.TotalTokens)
+//
+// The HF-shaped maps stay available from Pipeline for JSON output. Decoding
+// fails with an error, rather than a panic, if an entry does not have the
+// shape of T.
+func TypedPipeline[T any, PT result[T]](task, modelID string, options map[string]any) (TypedGenerator[T], error) {
+	gen, err := Pipeline(task, modelID, options)
+	if err != nil {
+		return nil, err
+	}
+	return Typed[T, PT](gen), nil
+}
+
+// Typed wraps gen so its results are decoded into T.
+func Typed[T any, PT result[T]](gen Generator) TypedGenerator[T] {
+	return func(inputs any, options map[string]any) ([]T, error) {
+		out, err := gen(inputs, options)
+		if err != nil {
+			return nil, err
+		}
+		return DecodeResults[T, PT](out)
+	}
+}
+
+// DecodeResults converts a Generator's output into typed results.
+func DecodeResults[T any, PT result[T]](out []map[string]any) ([]T, error) {
+	res := make([]T, len(out))
+	for i, m := range out {
+		if err := PT(&res[i]).fromMap(m); err != nil {
+			return nil, fmt.Errorf("result %d: %w", i, err)
+		}
+	}
+	return res, nil
+}
+
+// TextGenerationResult is one result of text-generation, image-text-to-text,
+// image-to-text, text2text-generation, summarization or translation.
+type TextGenerationResult struct {
+	// GeneratedText is the generated text: the completion (after the prompt
+	// unless return_full_text is false), summary or translation. For chats
+	// it is the assistant reply.
+	GeneratedText string `json:"generated_text"`
+
+	// Messages is the chat returned by chat pipelines: the input messages
+	// when return_full_text is set, then the assistant reply.
+	Messages []ChatMessage `json:"messages,omitempty"`
+
+	// FinishReason is "stop" (EOS, a stop string or the streamer) or
+	// "length" (max_new_tokens reached); empty when the task does not
+	// report it.
+	FinishReason string  `json:"finish_reason,omitempty"`
+	TokenIDs     []int64 `json:"token_ids,omitempty"`
+	Usage        *Usage  `json:"usage,omitempty"`
+}
+
+// Usage counts the tokens of one generation.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// Reply returns the assistant reply of a chat result, or GeneratedText.
+func (r TextGenerationResult) Reply() string {
+	if n := len(r.Messages); n > 0 && r.Messages[n-1].Role == RoleAssistant {
+		return r.Messages[n-1].Content
+	}
+	return r.GeneratedText
+}
+
+func (r *TextGenerationResult) fromMap(m map[string]any) error {
+	var v any
+	for _, key := range []string{"generated_text", "summary_text", "translation_text"} {
+		if x, ok := m[key]; ok {
+			v = x
+			break
+		}
+	}
+	switch t := v.(type) {
+	case string:
+		r.GeneratedText = t
+	case []map[string]any:
+		r.Messages = make([]ChatMessage, len(t))
+		for i, msg := range t {
+			role, _ := msg["role"].(string)
+			content, _ := msg["content"].(string)
+			r.Messages[i] = ChatMessage{Role: MessageRole(role), Content: content}
+		}
+		r.GeneratedText = r.Reply()
+	default:
+		return fmt.Errorf("generated_text: unexpected %T", v)
+	}
+	r.FinishReason, _ = m["finish_reason"].(string)
+	r.TokenIDs, _ = m["token_ids"].([]int64)
+	if u, ok := m["usage"].(map[string]any); ok {
+		r.Usage = &Usage{
+			PromptTokens:     intOption(u, "prompt_tokens", 0),
+			CompletionTokens: intOption(u, "completion_tokens", 0),
+			TotalTokens:      intOption(u, "total_tokens", 0),
+		}
+	}
+	return nil
+}
+
+// ClassificationResult is one label of text-, image-, audio- or
+// zero-shot-image-classification. Pipelines return the labels of all inputs
+// in one flat list, top_k per input in input order; with more than one
+// input, InputIndex says which input a label belongs to.
+// GroupClassificationResults restores HF's one-list-per-input shape.
+type ClassificationResult struct {
+	Label      string  `json:"label"`
+	Score      float64 `json:"score"`
+	InputIndex int     `json:"input_index,omitempty"` // batched inputs only
+}
+
+func (r *ClassificationResult) fromMap(m map[string]any) error {
+	var err error
+	if r.Label, err = stringField(m, "label"); err != nil {
+		return err
+	}
+	if r.Score, err = floatField(m, "score"); err != nil {
+		return err
+	}
+	r.InputIndex = intOption(m, "input_index", 0)
+	return nil
+}
+
+// GroupClassificationResults splits a flat classification result into one
+// list per input, indexed by InputIndex.
+func GroupClassificationResults(results []ClassificationResult) [][]ClassificationResult {
+	var groups [][]ClassificationResult
+	for _, r := range results {
+		for len(groups) <= r.InputIndex {
+			groups = append(groups, nil)
+		}
+		groups[r.InputIndex] = append(groups[r.InputIndex], r)
+	}
+	return groups
+}
+
+// ZeroShotClassificationResult ranks the candidate labels of one text.
+type ZeroShotClassificationResult struct {
+	Sequence string    `json:"sequence"`
+	Labels   []string  `json:"labels"`
+	Scores   []float64 `json:"scores"`
+}
+
+func (r *ZeroShotClassificationResult) fromMap(m map[string]any) error {
+	var ok bool
+	r.Sequence, _ = m["sequence"].(string)
+	if r.Labels, ok = m["labels"].([]string); !ok {
+		return fmt.Errorf("labels: unexpected %T", m["labels"])
+	}
+	if r.Scores, ok = m["scores"].([]float64); !ok {
+		return fmt.Errorf("scores: unexpected %T", m["scores"])
+	}
+	return nil
+}
+
+// TokenClassificationResult is one entity (or token, without aggregation).
+type TokenClassificationResult struct {
+	// Entity is "entity_group" with aggregation, else the token's "entity".
+	Entity     string  `json:"entity"`
+	Score      float64 `json:"score"`
+	Word       string  `json:"word"`
+	Start      int     `json:"start"` // character offsets into the input
+	End        int     `json:"end"`
+	Index      int     `json:"index,omitempty"` // aggregation "none" only
+	InputIndex int     `json:"input_index,omitempty"`
+}
+
+func (r *TokenClassificationResult) fromMap(m map[string]any) error {
+	var err error
+	if r.Entity, err = stringField(m, "entity_group"); err != nil {
+		if r.Entity, err = stringField(m, "entity"); err != nil {
+			return err
+		}
+	}
+	if r.Score, err = floatField(m, "score"); err != nil {
+		return err
+	}
+	r.Word, _ = m["word"].(string)
+	r.Start = intOption(m, "start", 0)
+	r.End = intOption(m, "end", 0)
+	r.Index = intOption(m, "index", 0)
+	r.InputIndex = intOption(m, "input_index", 0)
+	return nil
+}
+
+// QuestionAnsweringResult is one answer span; Start/End are character (rune)
+// offsets into the context.
+type QuestionAnsweringResult struct {
+	Answer     string  `json:"answer"`
+	Score      float64 `json:"score"`
+	Start      int     `json:"start"`
+	End        int     `json:"end"`
+	InputIndex int     `json:"input_index,omitempty"`
+}
+
+func (r *QuestionAnsweringResult) fromMap(m map[string]any) error {
+	var err error
+	if r.Answer, err = stringField(m, "answer"); err != nil {
+		return err
+	}
+	if r.Score, err = floatField(m, "score"); err != nil {
+		return err
+	}
+	r.Start = intOption(m, "start", 0)
+	r.End = intOption(m, "end", 0)
+	r.InputIndex = intOption(m, "input_index", 0)
+	return nil
+}
+
+// FillMaskResult is one candidate for a mask token. With several texts or
+// several masks in one text, InputIndex and MaskIndex say which mask the
+// candidate fills.
+type FillMaskResult struct {
+	Score      float64 `json:"score"`
+	Token      int64   `json:"token"`
+	TokenStr   string  `json:"token_str"`
+	Sequence   string  `json:"sequence"`
+	InputIndex int     `json:"input_index,omitempty"`
+	MaskIndex  int     `json:"mask_index,omitempty"`
+}
+
+func (r *FillMaskResult) fromMap(m map[string]any) error {
+	var err error
+	if r.Score, err = floatField(m, "score"); err != nil {
+		return err
+	}
+	switch t := m["token"].(type) {
+	case int64:
+		r.Token = t
+	case int:
+		r.Token = int64(t)
+	default:
+		return fmt.Errorf("token: unexpected %T", m["token"])
+	}
+	r.TokenStr, _ = m["token_str"].(string)
+	r.Sequence, _ = m["sequence"].(string)
+	r.InputIndex = intOption(m, "input_index", 0)
+	r.MaskIndex = intOption(m, "mask_index", 0)
+	return nil
+}
+
+// FeatureExtractionResult is the embedding of one text or image. Without
+// pooling, TokenEmbeddings holds one row per token and Embedding is nil.
+type FeatureExtractionResult struct {
+	Embedding       []float32   `json:"embedding,omitempty"`
+	TokenEmbeddings [][]float32 `json:"token_embeddings,omitempty"`
+}
+
+func (r *FeatureExtractionResult) fromMap(m map[string]any) error {
+	switch t := m["embedding"].(type) {
+	case []float32:
+		r.Embedding = t
+	case [][]float32:
+		r.TokenEmbeddings = t
+	default:
+		return fmt.Errorf("embedding: unexpected %T", m["embedding"])
+	}
+	return nil
+}
+
+// SpeechRecognitionResult is the transcript of one audio input.
+type SpeechRecognitionResult struct {
+	Text   string        `json:"text"`
+	Chunks []SpeechChunk `json:"chunks,omitempty"` // with return_timestamps
+}
+
+// SpeechChunk is one timestamped segment, in seconds.
+type SpeechChunk struct {
+	Timestamp [2]float64 `json:"timestamp"`
+	Text      string     `json:"text"`
+}
+
+func (r *SpeechRecognitionResult) fromMap(m map[string]any) error {
+	var err error
+	if r.Text, err = stringField(m, "text"); err != nil {
+		return err
+	}
+	chunks, _ := m["chunks"].([]map[string]any)
+	for _, c := range chunks {
+		ts, _ := c["timestamp"].([]float64)
+		if len(ts) != 2 {
+			return fmt.Errorf("chunk timestamp: unexpected %v", c["timestamp"])
+		}
+		text, _ := c["text"].(string)
+		r.Chunks = append(r.Chunks, SpeechChunk{Timestamp: [2]float64{ts[0], ts[1]}, Text: text})
+	}
+	return nil
+}
+
+// ObjectDetectionResult is one detected object.
+type ObjectDetectionResult struct {
+	Label      string      `json:"label"`
+	Score      float64     `json:"score"`
+	Box        BoundingBox `json:"box"`
+	InputIndex int         `json:"input_index,omitempty"`
+}
+
+// BoundingBox is in pixels of the original image.
+type BoundingBox struct {
+	XMin int `json:"xmin"`
+	YMin int `json:"ymin"`
+	XMax int `json:"xmax"`
+	YMax int `json:"ymax"`
+}
+
+func (r *ObjectDetectionResult) fromMap(m map[string]any) error {
+	var err error
+	if r.Label, err = stringField(m, "label"); err != nil {
+		return err
+	}
+	if r.Score, err = floatField(m, "score"); err != nil {
+		return err
+	}
+	box, ok := m["box"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("box: unexpected %T", m["box"])
+	}
+	r.Box = BoundingBox{
+		XMin: intOption(box, "xmin", 0),
+		YMin: intOption(box, "ymin", 0),
+		XMax: intOption(box, "xmax", 0),
+		YMax: intOption(box, "ymax", 0),
+	}
+	r.InputIndex = intOption(m, "input_index", 0)
+	return nil
+}
+
+// ImageSegmentationResult is one label's mask. Score is nil for alpha
+// mattes, which have no class score.
+type ImageSegmentationResult struct {
+	Label      string      `json:"label"`
+	Score      *float64    `json:"score"`
+	Mask       *image.Gray `json:"-"`
+	InputIndex int         `json:"input_index,omitempty"`
+}
+
+func (r *ImageSegmentationResult) fromMap(m map[string]any) error {
+	var err error
+	if r.Label, err = stringField(m, "label"); err != nil {
+		return err
+	}
+	if s, err := floatField(m, "score"); err == nil {
+		r.Score = &s
+	}
+	var ok bool
+	if r.Mask, ok = m["mask"].(*image.Gray); !ok {
+		return fmt.Errorf("mask: unexpected %T", m["mask"])
+	}
+	r.InputIndex = intOption(m, "input_index", 0)
+	return nil
+}
+
+// RankingResult is one reranked document; Index points into the input
+// documents.
+type RankingResult struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+	Text  string  `json:"text"`
+}
+
+func (r *RankingResult) fromMap(m map[string]any) error {
+	var err error
+	if r.Score, err = floatField(m, "score"); err != nil {
+		return err
+	}
+	r.Index = intOption(m, "index", 0)
+	r.Text, _ = m["text"].(string)
+	return nil
+}
+
+func stringField(m map[string]any, key string) (string, error) {
+	s, ok := m[key].(string)
+	if !ok {
+		return "", fieldError(m, key)
+	}
+	return s, nil
+}
+
+func floatField(m map[string]any, key string) (float64, error) {
+	switch t := m[key].(type) {
+	case float64:
+		return t, nil
+	case float32:
+		return float64(t), nil
+	}
+	return 0, fieldError(m, key)
+}
+
+func fieldError(m map[string]any, key string) error {
+	if _, ok := m[key]; !ok {
+		return fmt.Errorf("%s: missing", key)
+	}
+	return fmt.Errorf("%s: unexpected %T", key, m[key])
+}
@@ -0,0 +1,246 @@
+package transformers
+
+import (
+	"image"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The entries below are built by the same helpers the pipelines use, or
+// copy their literal shapes, so a change to either side shows up here.
+
+func TestDecodeTextGenerationResults(t *testing.T) {
+	opts := GenerationOptions{MaxNewTokens: 3}
+
+	chat := map[string]any{"generated_text": chatTurns([]ChatMessage{{Role: RoleUser, Content: "hi"}}, " hello ", true)}
+	addGenerationStats(chat, 5, []int64{7, 8}, "hello", opts)
+	prompt := map[string]any{"generated_text": "Once upon a time"}
+	addGenerationStats(prompt, 2, []int64{1, 2, 3}, "upon a time", opts)
+	summary := map[string]any{"summary_text": "short"}
+	translation := map[string]any{"translation_text": "Hallo"}
+
+	res, err := DecodeResults[TextGenerationResult]([]map[string]any{chat, prompt, summary, translation})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChat := TextGenerationResult{
+		GeneratedText: "hello",
+		Messages:      []ChatMessage{{Role: RoleUser, Content: "hi"}, {Role: RoleAssistant, Content: "hello"}},
+		FinishReason:  "stop",
+		TokenIDs:      []int64{7, 8},
+		Usage:         &Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7},
+	}
+	if !reflect.DeepEqual(res[0], wantChat) {
+		t.Errorf("chat = %+v, want %+v", res[0], wantChat)
+	}
+	if res[0].Reply() != "hello" {
+		t.Errorf("chat Reply = %q", res[0].Reply())
+	}
+	if res[1].GeneratedText != "Once upon a time" || res[1].FinishReason != "length" || res[1].Usage.TotalTokens != 5 {
+		t.Errorf("prompt = %+v", res[1])
+	}
+	if res[2].GeneratedText != "short" || res[3].GeneratedText != "Hallo" || res[2].Usage != nil {
+		t.Errorf("summary/translation = %+v, %+v", res[2], res[3])
+	}
+
+	if _, err := DecodeResults[TextGenerationResult]([]map[string]any{{"generated_text": 42}}); err == nil {
+		t.Error("numeric generated_text: expected an error")
+	}
+}
+
+func TestDecodeClassificationResults(t *testing.T) {
+	var out []map[string]any
+	for i, logits := range [][]float32{{0, 2}, {3, 0}} {
+		entries, err := labeledScores(logits, []string{"NEGATIVE", "POSITIVE"}, "softmax", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[0]["input_index"] = i
+		out = append(out, entries...)
+	}
+	res, err := DecodeResults[ClassificationResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].Label != "POSITIVE" || res[1].Label != "NEGATIVE" || res[1].InputIndex != 1 {
+		t.Errorf("results = %+v", res)
+	}
+	if math.Abs(res[0].Score-1/(1+math.Exp(-2))) > 1e-6 {
+		t.Errorf("score = %v", res[0].Score)
+	}
+	groups := GroupClassificationResults(res)
+	if len(groups) != 2 || groups[1][0].Label != "NEGATIVE" {
+		t.Errorf("groups = %+v", groups)
+	}
+
+	if _, err := DecodeResults[ClassificationResult]([]map[string]any{{"label": "x"}}); err == nil || !strings.Contains(err.Error(), "score: missing") {
+		t.Errorf("missing score: err = %v", err)
+	}
+}
+
+func TestDecodeZeroShotClassificationResults(t *testing.T) {
+	// Two labels of a [contradiction, neutral, entailment] head.
+	logits := []float32{0, 0, 1, 0, 0, 3}
+	out := []map[string]any{zeroShotResult("I lost my card", []string{"travel", "billing"}, logits, 3, 2, 0, false)}
+	res, err := DecodeResults[ZeroShotClassificationResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := res[0]
+	if r.Sequence != "I lost my card" || !reflect.DeepEqual(r.Labels, []string{"billing", "travel"}) || len(r.Scores) != 2 {
+		t.Fatalf("result = %+v", r)
+	}
+	if math.Abs(r.Scores[0]+r.Scores[1]-1) > 1e-6 || r.Scores[0] <= r.Scores[1] {
+		t.Errorf("scores = %v", r.Scores)
+	}
+}
+
+func TestDecodeTokenClassificationResults(t *testing.T) {
+	text := "Wolfgang lives in Berlin"
+	out := []map[string]any{
+		entity{label: "PER", score: 0.99, word: "Wolfgang", start: 0, end: 8}.toMap(text, AggregationSimple),
+		entity{label: "B-LOC", score: 0.9, word: "Berlin", start: 18, end: 24, index: 4}.toMap(text, AggregationNone),
+	}
+	out[1]["input_index"] = 1
+	res, err := DecodeResults[TokenClassificationResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TokenClassificationResult{
+		{Entity: "PER", Score: 0.99, Word: "Wolfgang", Start: 0, End: 8},
+		{Entity: "B-LOC", Score: 0.9, Word: "Berlin", Start: 18, End: 24, Index: 4, InputIndex: 1},
+	}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("results = %+v, want %+v", res, want)
+	}
+}
+
+func TestDecodeQuestionAnsweringResults(t *testing.T) {
+	out := []map[string]any{{"answer": "Wolfgang", "score": 0.98, "start": 0, "end": 8, "input_index": 2}}
+	res, err := DecodeResults[QuestionAnsweringResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := QuestionAnsweringResult{Answer: "Wolfgang", Score: 0.98, Start: 0, End: 8, InputIndex: 2}
+	if res[0] != want {
+		t.Errorf("result = %+v, want %+v", res[0], want)
+	}
+}
+
+func TestDecodeFillMaskResults(t *testing.T) {
+	out := []map[string]any{
+		{"score": 0.42, "token": int64(3000), "token_str": "paris", "sequence": "the capital of france is paris."},
+		{"score": float64(float32(0.1)), "token": int64(7), "token_str": "a", "sequence": "a b", "input_index": 1, "mask_index": 1},
+	}
+	res, err := DecodeResults[FillMaskResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].Token != 3000 || res[0].TokenStr != "paris" || res[0].Score != 0.42 {
+		t.Errorf("result 0 = %+v", res[0])
+	}
+	if res[1].InputIndex != 1 || res[1].MaskIndex != 1 {
+		t.Errorf("result 1 = %+v, want input and mask index 1", res[1])
+	}
+	if _, err := DecodeResults[FillMaskResult]([]map[string]any{{"score": 0.1, "token": "x"}}); err == nil {
+		t.Error("string token: expected an error")
+	}
+}
+
+func TestDecodeFeatureExtractionResults(t *testing.T) {
+	out := []map[string]any{
+		{"embedding": []float32{0.6, 0.8}},
+		{"embedding": [][]float32{{1, 0}, {0, 1}}},
+	}
+	res, err := DecodeResults[FeatureExtractionResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res[0].Embedding) != 2 || res[0].TokenEmbeddings != nil {
+		t.Errorf("pooled = %+v", res[0])
+	}
+	if len(res[1].TokenEmbeddings) != 2 || res[1].Embedding != nil {
+		t.Errorf("per token = %+v", res[1])
+	}
+}
+
+func TestDecodeSpeechRecognitionResults(t *testing.T) {
+	out := []map[string]any{
+		{"text": "hello world"},
+		{
+			"text": "hello world",
+			"chunks": []map[string]any{
+				{"timestamp": []float64{roundCentis(0), roundCentis(1.234)}, "text": "hello"},
+				{"timestamp": []float64{1.23, 2.5}, "text": "world"},
+			},
+		},
+	}
+	res, err := DecodeResults[SpeechRecognitionResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0].Chunks != nil {
+		t.Errorf("without timestamps: chunks = %v", res[0].Chunks)
+	}
+	want := []SpeechChunk{{Timestamp: [2]float64{0, 1.23}, Text: "hello"}, {Timestamp: [2]float64{1.23, 2.5}, Text: "world"}}
+	if !reflect.DeepEqual(res[1].Chunks, want) {
+		t.Errorf("chunks = %+v, want %+v", res[1].Chunks, want)
+	}
+}
+
+func TestDecodeObjectDetectionResults(t *testing.T) {
+	out := []map[string]any{{
+		"label": "car",
+		"score": 0.99,
+		"box":   map[string]any{"xmin": 12, "ymin": 40, "xmax": 210, "ymax": 160},
+	}}
+	res, err := DecodeResults[ObjectDetectionResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ObjectDetectionResult{Label: "car", Score: 0.99, Box: BoundingBox{XMin: 12, YMin: 40, XMax: 210, YMax: 160}}
+	if res[0] != want {
+		t.Errorf("result = %+v, want %+v", res[0], want)
+	}
+}
+
+func TestDecodeImageSegmentationResults(t *testing.T) {
+	pv := &PixelValues{Shape: []int64{1, 3, 2, 2}, OriginalSize: [2]int{2, 2}, ResizedSize: [2]int{2, 2}}
+	semantic, err := segmentationEntries(floatOutput{shape: []int64{1, 2, 1, 1}, data: []float32{1, 0}}, pv, []string{"wall", "floor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matte, err := segmentationEntries(floatOutput{shape: []int64{1, 1, 1, 1}, data: []float32{0.5}}, pv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := DecodeResults[ImageSegmentationResult](append(semantic, matte...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("got %d results", len(res))
+	}
+	if res[0].Label != "wall" || res[0].Score == nil || res[0].Mask.Rect != image.Rect(0, 0, 2, 2) {
+		t.Errorf("semantic = %+v", res[0])
+	}
+	if res[1].Label != "foreground" || res[1].Score != nil || res[1].Mask == nil {
+		t.Errorf("matte = %+v", res[1])
+	}
+}
+
+func TestDecodeRankingResults(t *testing.T) {
+	out := []map[string]any{
+		{"index": 2, "score": 0.99, "text": "Berlin has 3.5 million inhabitants."},
+		{"index": 0, "score": 0.01, "text": ""},
+	}
+	res, err := DecodeResults[RankingResult](out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0] != (RankingResult{Index: 2, Score: 0.99, Text: "Berlin has 3.5 million inhabitants."}) || res[1].Index != 0 {
+		t.Errorf("results = %+v", res)
+	}
+}
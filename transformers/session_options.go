@@ -0,0 +1,65 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+
+	onnx "github.com/yalue/onnxruntime_go"
+)
+
+// ModelLoadOptions tunes how ONNX sessions are created. The zero value keeps
+// ORT defaults: full graph optimization on every load.
+type ModelLoadOptions struct {
+	// GraphOptimizationLevel is "disable", "basic", "extended" or "all".
+	// Empty means "all".
+	GraphOptimizationLevel string
+
+	IntraOpNumThreads int
+	InterOpNumThreads int
+
+	// tracker collects the sessions loaded for a GeneratorHandle.
+	tracker *resourceTracker
+}
+
+func (o ModelLoadOptions) optimizationLevel() (onnx.GraphOptimizationLevel, error) {
+	switch strings.ToLower(o.GraphOptimizationLevel) {
+	case "", "all":
+		return onnx.GraphOptimizationLevelEnableAll, nil
+	case "extended":
+		return onnx.GraphOptimizationLevelEnableExtended, nil
+	case "basic":
+		return onnx.GraphOptimizationLevelEnableBasic, nil
+	case "disable", "none":
+		return onnx.GraphOptimizationLevelDisableAll, nil
+	}
+	return 0, fmt.Errorf("unknown graph optimization level %q", o.GraphOptimizationLevel)
+}
+
+// newSessionOptions builds ORT session options from o.
+func (o ModelLoadOptions) newSessionOptions() (*onnx.SessionOptions, error) {
+	level, err := o.optimizationLevel()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := onnx.NewSessionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("NewSessionOptions: %w", err)
+	}
+	if err := opts.SetGraphOptimizationLevel(level); err != nil {
+		opts.Destroy()
+		return nil, fmt.Errorf("SetGraphOptimizationLevel: %w", err)
+	}
+	if o.IntraOpNumThreads > 0 {
+		if err := opts.SetIntraOpNumThreads(o.IntraOpNumThreads); err != nil {
+			opts.Destroy()
+			return nil, fmt.Errorf("SetIntraOpNumThreads: %w", err)
+		}
+	}
+	if o.InterOpNumThreads > 0 {
+		if err := opts.SetInterOpNumThreads(o.InterOpNumThreads); err != nil {
+			opts.Destroy()
+			return nil, fmt.Errorf("SetInterOpNumThreads: %w", err)
+		}
+	}
+	return opts, nil
+}
@@ -1,6 +1,8 @@
 package transformers
 
 import (
+	"errors"
+	"fmt"
 	"math"
 
 	onnx "github.com/yalue/onnxruntime_go"
@@ -18,6 +20,20 @@ func tensorFromFloat32s(data []float32, shape []int64) (*onnx.Tensor[float32], e
 	return onnx.NewTensor(sh, data)
 }
 
+// tensorFromFloat32sAs wraps data as a tensor of element type dt: float16
+// inputs get converted half-precision bytes, anything else float32.
+func tensorFromFloat32sAs(dt onnx.TensorElementDataType, data []float32, shape []int64) (onnx.Value, error) {
+	if dt != onnx.TensorElementDataTypeFloat16 {
+		return tensorFromFloat32s(data, shape)
+	}
+	raw := make([]byte, max(2*len(data), 2))
+	for i, f := range data {
+		h := float32ToFloat16(f)
+		raw[2*i], raw[2*i+1] = byte(h), byte(h>>8)
+	}
+	return onnx.NewCustomDataTensor(onnx.NewShape(shape...), raw, dt)
+}
+
 // argmaxF32 returns the index of the largest value in xs.
 // If xs is empty, returns 0.
 func argmaxF32(xs []float32) int {
@@ -79,3 +95,106 @@ func sampleFromProbsF32(xs []float32, rnd func() float32) int {
 	}
 	return len(xs) - 1
 }
+
+// zeroTensor allocates a zero-filled tensor of the given ONNX element type.
+// float16 has no Go type, so it is backed by raw bytes.
+func zeroTensor(dt onnx.TensorElementDataType, shape []int64) (onnx.Value, error) {
+	count := int64(1)
+	for _, d := range shape {
+		count *= d
+	}
+	switch dt {
+	case onnx.TensorElementDataTypeInt64:
+		return tensorFromInt64s(make([]int64, count), shape)
+	case onnx.TensorElementDataTypeInt32:
+		return onnx.NewTensor(onnx.NewShape(shape...), make([]int32, count))
+	case onnx.TensorElementDataTypeBool:
+		return onnx.NewTensor(onnx.NewShape(shape...), make([]bool, count))
+	case onnx.TensorElementDataTypeFloat16:
+		// CustomDataTensor rejects empty buffers even for zero-sized shapes.
+		return onnx.NewCustomDataTensor(onnx.NewShape(shape...), make([]byte, max(2*count, 2)), dt)
+	default:
+		return tensorFromFloat32s(make([]float32, count), shape)
+	}
+}
+
+// float32sFromValue returns the data and shape of a float32 or float16
+// output tensor as float32.
+func float32sFromValue(v onnx.Value) ([]float32, onnx.Shape, error) {
+	switch t := v.(type) {
+	case *onnx.Tensor[float32]:
+		return t.GetData(), t.GetShape(), nil
+	case *onnx.CustomDataTensor:
+		if dt := onnx.TensorElementDataType(t.DataType()); dt != onnx.TensorElementDataTypeFloat16 {
+			return nil, nil, fmt.Errorf("unsupported tensor element type %s", dt)
+		}
+		raw := t.GetData()
+		out := make([]float32, len(raw)/2)
+		for i := range out {
+			out[i] = float16ToFloat32(uint16(raw[2*i]) | uint16(raw[2*i+1])<<8)
+		}
+		return out, t.GetShape(), nil
+	case nil:
+		return nil, nil, errors.New("tensor is nil")
+	}
+	return nil, nil, fmt.Errorf("unsupported tensor type %T", v)
+}
+
+// float16ToFloat32 converts an IEEE 754 half-precision value.
+func float16ToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := int32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch {
+	case exp == 0 && frac == 0:
+		return math.Float32frombits(sign)
+	case exp == 0:
+		// Subnormal: shift until the implicit leading bit appears.
+		exp = 1
+		for frac&0x400 == 0 {
+			frac <<= 1
+			exp--
+		}
+		frac &= 0x3ff
+	case exp == 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	}
+	return math.Float32frombits(sign | uint32(exp+112)<<23 | frac<<13)
+}
+
+// float32ToFloat16 converts to IEEE 754 half precision, rounding to nearest
+// even. Values past the half range become infinities.
+func float32ToFloat16(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int32(b>>23)&0xff - 127 + 15
+	frac := b & 0x7fffff
+	switch {
+	case b&0x7f800000 == 0x7f800000:
+		if frac != 0 {
+			return sign | 0x7e00 // NaN
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f:
+		return sign | 0x7c00
+	case exp <= 0:
+		if exp < -10 {
+			return sign
+		}
+		// Subnormal: make the implicit bit explicit and shift it down.
+		frac |= 0x800000
+		shift := uint32(14 - exp)
+		half := frac >> shift
+		rem, mid := frac&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > mid || rem == mid && half&1 == 1 {
+			half++
+		}
+		return sign | uint16(half)
+	}
+	half := uint32(exp)<<10 | frac>>13
+	// A carry out of the mantissa rolls into the exponent, as it should.
+	if rem := frac & 0x1fff; rem > 0x1000 || rem == 0x1000 && half&1 == 1 {
+		half++
+	}
+	return sign | uint16(half)
+}
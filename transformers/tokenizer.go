@@ -1,10 +1,12 @@
 package transformers
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/sugarme/tokenizer"
 	"github.com/sugarme/tokenizer/pretrained"
@@ -12,8 +14,10 @@ import (
 
 // Tokenizer wraps sugarme/tokenizer with a HF-like interface.
 type Tokenizer struct {
-	tok          *tokenizer.Tokenizer
-	chatTemplate func([]ChatMessage) (string, error)
+	tok             *tokenizer.Tokenizer
+	chatTemplate    func([]ChatMessage) (string, error)
+	hasChatTemplate bool              // the repo defines one; chatTemplate may be the default
+	specialTokens   map[string]string // e.g. "mask_token" -> "[MASK]"
 }
 
 // AutoTokenizer is the HF-style static dispatcher:
@@ -33,7 +37,7 @@ func (autoTokenizer) FromPretrained(
 	}
 
 	// Best-effort fetch of auxiliary tokenizer assets; missing files are skipped.
-	_, _ = HFHubEnsureOptionalFiles(modelID, modelFilesList([]string{
+	assets, _ := HFHubEnsureOptionalFiles(modelID, modelFilesList([]string{
 		"tokenizer_config.json",
 		"special_tokens_map.json",
 		"vocab.json",
@@ -45,14 +49,101 @@ func (autoTokenizer) FromPretrained(
 		return nil, err
 	}
 
-	chatTplFn, _ := loadChatTemplate(modelID)
+	chatTplFn, customTpl := loadChatTemplate(modelID)
 
 	tok, err := pretrained.FromFile(sanitizedPath)
 	if err != nil {
 		return nil, fmt.Errorf("AutoTokenizer: %w", err)
 	}
 
-	return &Tokenizer{tok: tok, chatTemplate: chatTplFn}, nil
+	return &Tokenizer{
+		tok:             tok,
+		chatTemplate:    chatTplFn,
+		hasChatTemplate: customTpl || configHasChatTemplate(assets),
+		specialTokens:   loadSpecialTokens(assets),
+	}, nil
+}
+
+// configHasChatTemplate reports whether tokenizer_config.json carries a
+// "chat_template" (a string, or a list of named templates).
+func configHasChatTemplate(assets map[string]string) bool {
+	path, ok := assets["tokenizer_config.json"]
+	if !ok {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var raw struct {
+		ChatTemplate any `json:"chat_template"`
+	}
+	if json.Unmarshal(data, &raw) != nil {
+		return false
+	}
+	switch t := raw.ChatTemplate.(type) {
+	case string:
+		return t != ""
+	case []any:
+		return len(t) > 0
+	}
+	return false
+}
+
+// loadSpecialTokens reads the named special tokens (bos_token, mask_token,
+// ...) from tokenizer_config.json, overridden by special_tokens_map.json.
+// Values are either strings or {"content": ...} objects.
+func loadSpecialTokens(assets map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, name := range []string{"tokenizer_config.json", "special_tokens_map.json"} {
+		path, ok := assets[name]
+		if !ok {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var raw map[string]any
+		if json.Unmarshal(data, &raw) != nil {
+			continue
+		}
+		for key, v := range raw {
+			if !strings.HasSuffix(key, "_token") {
+				continue
+			}
+			switch t := v.(type) {
+			case string:
+				out[key] = t
+			case map[string]any:
+				if c, ok := t["content"].(string); ok {
+					out[key] = c
+				}
+			}
+		}
+	}
+	return out
+}
+
+// SpecialToken returns a named special token such as "mask_token" or
+// "cls_token", or "" if the tokenizer files do not define it.
+func (t *Tokenizer) SpecialToken(name string) string {
+	return t.specialTokens[name]
+}
+
+// Close releases the tokenizer. Tokenizers are pure Go and hold no native
+// resources, so this is a no-op; it exists so code that closes everything a
+// pipeline loaded (models, tokenizers, generators) need not special-case
+// them. t stays usable.
+func (t *Tokenizer) Close() error {
+	return nil
+}
+
+// HasChatTemplate reports whether the model repo defines a chat template.
+// Base (completion-only) checkpoints do not; callers can use it to send them
+// plain-text prompts (or completion: true) instead of a chat.
+func (t *Tokenizer) HasChatTemplate() bool {
+	return t.hasChatTemplate
 }
 
 // Encode plain text into IDs.
@@ -69,6 +160,181 @@ func (t *Tokenizer) Encode(text string, addSpecialTokens bool) ([]int64, error)
 	return out, nil
 }
 
+// Encoding is one tokenized sequence (or pair) with its alignment to the
+// input text.
+type Encoding struct {
+	IDs               []int64
+	TypeIDs           []int64
+	Tokens            []string
+	Offsets           [][2]int // byte offsets [start, end) into the input; [0, 0] for special tokens
+	SpecialTokensMask []int64  // 1 for tokens added by the post-processor ([CLS], </s>, ...)
+	WordIDs           []int    // index of the pre-tokenized word, -1 for special tokens
+}
+
+// runeOffset converts a byte offset into s, as in Encoding.Offsets, to the
+// character (rune) offset HF pipelines report spans in.
+func runeOffset(s string, byteOff int) int {
+	byteOff = min(max(byteOff, 0), len(s))
+	return utf8.RuneCountInString(s[:byteOff])
+}
+
+// EncodeWithOffsets encodes text with special tokens and keeps the token
+// strings, offsets and word indices that Encode drops.
+func (t *Tokenizer) EncodeWithOffsets(text string) (*Encoding, error) {
+	enc, err := t.tok.EncodeSingle(text, true)
+	if err != nil {
+		return nil, err
+	}
+	return newEncoding(enc), nil
+}
+
+// EncodePairWithOffsets is EncodeWithOffsets for a (text, pair) input;
+// offsets of pair tokens index into pair, and their TypeIDs are 1 where the
+// model uses segment IDs.
+func (t *Tokenizer) EncodePairWithOffsets(text, pair string) (*Encoding, error) {
+	enc, err := t.tok.EncodePair(text, pair, true)
+	if err != nil {
+		return nil, err
+	}
+	return newEncoding(enc), nil
+}
+
+// newEncoding converts a sugarme encoding, dropping any fixed padding that
+// tokenizer.json asked for; batches pad themselves.
+func newEncoding(enc *tokenizer.Encoding) *Encoding {
+	out := &Encoding{}
+	at := func(xs []int, i, def int) int {
+		if i < len(xs) {
+			return xs[i]
+		}
+		return def
+	}
+	for i, id := range enc.Ids {
+		if at(enc.AttentionMask, i, 1) == 0 {
+			continue
+		}
+		var off [2]int
+		if i < len(enc.Offsets) && len(enc.Offsets[i]) == 2 {
+			off = [2]int{enc.Offsets[i][0], enc.Offsets[i][1]}
+		}
+		tok := ""
+		if i < len(enc.Tokens) {
+			tok = enc.Tokens[i]
+		}
+		out.IDs = append(out.IDs, int64(id))
+		out.TypeIDs = append(out.TypeIDs, int64(at(enc.TypeIds, i, 0)))
+		out.Tokens = append(out.Tokens, tok)
+		out.Offsets = append(out.Offsets, off)
+		out.SpecialTokensMask = append(out.SpecialTokensMask, int64(at(enc.SpecialTokenMask, i, 0)))
+		out.WordIDs = append(out.WordIDs, at(enc.Words, i, -1))
+	}
+	return out
+}
+
+// untruncated returns t without the truncation tokenizer.json may set, for
+// callers that split long inputs themselves.
+func (t *Tokenizer) untruncated() *Tokenizer {
+	if t.tok.GetTruncation() == nil {
+		return t
+	}
+	tok := *t.tok
+	tok.WithTruncation(nil)
+	c := *t
+	c.tok = &tok
+	return &c
+}
+
+// truncation is how encodeWithTypes shortens a (text, pair) input.
+type truncation int
+
+const (
+	truncateLongestFirst truncation = iota // cut the longer sequence first
+	truncateOnlyFirst                      // cut only text (NLI premises)
+)
+
+// encodeWithTypes encodes text (and pair, when non-empty) with special tokens
+// and returns the IDs with their token type IDs, truncated to maxLen
+// (0 = no limit) by strategy.
+func (t *Tokenizer) encodeWithTypes(text, pair string, maxLen int, strategy truncation) ([]int64, []int64, error) {
+	var (
+		enc *Encoding
+		err error
+	)
+	if pair == "" {
+		enc, err = t.EncodeWithOffsets(text)
+	} else {
+		enc, err = t.EncodePairWithOffsets(text, pair)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	ids, types := enc.IDs, enc.TypeIDs
+	if maxLen > 0 && len(ids) > maxLen {
+		return truncateKeepingEnds(ids, types, enc.SpecialTokensMask, maxLen, strategy)
+	}
+	return ids, types, nil
+}
+
+// truncateKeepingEnds shortens an encoded sequence to maxLen, keeping every
+// special token. The sequences are the runs of non-special tokens between
+// them, found by the separators rather than by type IDs, which are all 0 for
+// RoBERTa-style pairs; tokens are cut from the end of a sequence. Only when
+// the special tokens alone exceed maxLen is the sequence simply cut.
+func truncateKeepingEnds(ids, types, special []int64, maxLen int, strategy truncation) ([]int64, []int64, error) {
+	type run struct{ start, n, keep int }
+	var runs []run
+	for i := 0; i < len(ids); {
+		if i < len(special) && special[i] != 0 {
+			i++
+			continue
+		}
+		start := i
+		for i < len(ids) && (i >= len(special) || special[i] == 0) {
+			i++
+		}
+		runs = append(runs, run{start: start, n: i - start, keep: i - start})
+	}
+	excess := len(ids) - maxLen
+	switch {
+	case len(runs) == 0:
+	case strategy == truncateOnlyFirst:
+		if runs[0].keep <= excess {
+			return nil, nil, fmt.Errorf("input needs %d tokens cut but its first sequence has only %d (only_first truncation)", excess, runs[0].keep)
+		}
+		runs[0].keep -= excess
+		excess = 0
+	default:
+		for ; excess > 0; excess-- {
+			best := -1
+			for i, r := range runs {
+				// Ties cut the later sequence, as HF's longest_first does.
+				if r.keep > 0 && (best < 0 || r.keep >= runs[best].keep) {
+					best = i
+				}
+			}
+			if best < 0 {
+				break
+			}
+			runs[best].keep--
+		}
+	}
+	if excess > 0 {
+		return ids[:maxLen], types[:maxLen], nil
+	}
+	outIDs := make([]int64, 0, maxLen)
+	outTypes := make([]int64, 0, maxLen)
+	prev := 0
+	for _, r := range runs {
+		// Special tokens before the run, then the run's first keep tokens.
+		outIDs = append(append(outIDs, ids[prev:r.start]...), ids[r.start:r.start+r.keep]...)
+		outTypes = append(append(outTypes, types[prev:r.start]...), types[r.start:r.start+r.keep]...)
+		prev = r.start + r.n
+	}
+	outIDs = append(outIDs, ids[prev:]...)
+	outTypes = append(outTypes, types[prev:]...)
+	return outIDs, outTypes, nil
+}
+
 // Decode IDs into plain text.
 func (t *Tokenizer) Decode(ids []int64) (string, error) {
 	uids := make([]int, len(ids))
@@ -78,6 +344,17 @@ func (t *Tokenizer) Decode(ids []int64) (string, error) {
 	return t.tok.Decode(uids, true), nil
 }
 
+// TokenToID looks up a single token (including added/special tokens).
+func (t *Tokenizer) TokenToID(token string) (int64, bool) {
+	id, ok := t.tok.TokenToId(token)
+	return int64(id), ok
+}
+
+// IDToToken is the inverse of TokenToID.
+func (t *Tokenizer) IDToToken(id int64) (string, bool) {
+	return t.tok.IdToToken(int(id))
+}
+
 // BatchDecode helper.
 func (t *Tokenizer) BatchDecode(batch [][]int64) ([]string, error) {
 	res := make([]string, len(batch))
@@ -107,7 +384,7 @@ func (t *Tokenizer) renderChatTemplate(messages []ChatMessage) (string, error) {
 	for _, m := range messages {
 		if m.Role == RoleSystem {
 			b.WriteString("System: ")
-			b.WriteString(m.Content)
+			b.WriteString(m.TextContent())
 			b.WriteString("\n")
 		}
 	}
@@ -122,7 +399,7 @@ func (t *Tokenizer) renderChatTemplate(messages []ChatMessage) (string, error) {
 		}
 		b.WriteString(role)
 		b.WriteString(": ")
-		b.WriteString(m.Content)
+		b.WriteString(m.TextContent())
 		b.WriteString("\n")
 	}
 	return b.String(), nil
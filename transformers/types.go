@@ -10,10 +10,24 @@ const (
 )
 
 type ChatMessage struct {
-	Role       MessageRole `json:"role"`
-	Content    string      `json:"content"`
-	Name       string      `json:"name,omitempty"`
-	ToolCallID string      `json:"tool_call_id,omitempty"`
+	Role    MessageRole `json:"role"`
+	Content string      `json:"content"`
+	// Parts is multi-part content (text and images) for vision-language
+	// models; when set it is used instead of Content. In JSON both map to
+	// "content", as a string or a list of parts. Being a slice, it makes
+	// ChatMessage incomparable with ==.
+	Parts      []ContentPart `json:"-"`
+	Name       string        `json:"name,omitempty"`
+	ToolCallID string        `json:"tool_call_id,omitempty"`
+}
+
+// ContentPart is one part of a multi-part message, in the HF chat format:
+// {"type": "text", "text": ...} or {"type": "image", "image": ...}. Image is
+// a file path, encoded bytes or an image.Image.
+type ContentPart struct {
+	Type  string `json:"type"`
+	Text  string `json:"text,omitempty"`
+	Image any    `json:"image,omitempty"`
 }
 
 // Tool schema types – kept for future use; v1 doesn't yet embed tools into prompt.
@@ -41,8 +55,11 @@ type PipelineStreamEvent struct {
 }
 
 // Generator is what Pipeline(...) returns.
-// It mirrors the JS/Python pattern: generator(messages, options) -> output.
+// It mirrors the JS/Python pattern: generator(inputs, options) -> output.
+// The input type depends on the task: []ChatMessage (or a plain prompt
+// string) for text-generation, a string or []string for
+// text2text-generation, summarization and translation.
 type Generator func(
-	messages []ChatMessage,
+	inputs any,
 	options map[string]any,
 ) ([]map[string]any, error)